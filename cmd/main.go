@@ -14,6 +14,10 @@ func main() {
 		if errors.Is(err, cli.ErrShowConfigDisplayed) {
 			return
 		}
+		if errors.Is(err, cli.ErrSchemaDriftDetected) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
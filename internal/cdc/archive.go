@@ -0,0 +1,396 @@
+package cdc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrNoArchive            = errors.New("no oplog archive index found")
+	ErrArchiveFormat        = errors.New("unsupported archive format (use bson or ndjson)")
+	ErrArchiveFormatChanged = errors.New("archive already uses a different format")
+	ErrCorruptArchive       = errors.New("corrupt oplog archive")
+)
+
+const (
+	ArchiveFormatBSON   = "bson"
+	ArchiveFormatNDJSON = "ndjson"
+
+	// ArchiveIndexFile lists every partition with its timestamp range so
+	// readers only open the files a query needs.
+	ArchiveIndexFile = "index.json"
+
+	DefaultArchivePartition = time.Hour
+)
+
+// ArchiveIndex is the on-disk catalogue of an archive directory.
+type ArchiveIndex struct {
+	Format     string             `json:"format"`
+	Partitions []ArchivePartition `json:"partitions"`
+}
+
+// ArchivePartition is one gzip file holding the entries whose wall time falls
+// in [Start, Start+partition). First and Last bound the oplog timestamps in it.
+// Flushed is the file length after the last completed gzip member.
+type ArchivePartition struct {
+	File    string         `json:"file"`
+	Start   time.Time      `json:"start"`
+	First   bson.Timestamp `json:"first"`
+	Last    bson.Timestamp `json:"last"`
+	Count   int64          `json:"count"`
+	Bytes   int64          `json:"bytes"`
+	Flushed int64          `json:"flushed"`
+}
+
+// Last returns the newest archived timestamp, where a continuous archive
+// resumes.
+func (idx ArchiveIndex) Last() bson.Timestamp {
+	var last bson.Timestamp
+	for _, p := range idx.Partitions {
+		if p.Last.After(last) {
+			last = p.Last
+		}
+	}
+	return last
+}
+
+// ReadArchiveIndex loads dir/index.json.
+func ReadArchiveIndex(dir string) (ArchiveIndex, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ArchiveIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return ArchiveIndex{}, fmt.Errorf("%w: %s", ErrNoArchive, dir)
+	}
+	if err != nil {
+		return ArchiveIndex{}, err
+	}
+	var idx ArchiveIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return ArchiveIndex{}, fmt.Errorf("%w: %w", ErrCorruptArchive, err)
+	}
+	return idx, nil
+}
+
+// ArchiveWriter appends raw oplog entries to time-partitioned gzip files.
+// Every Flush ends the current gzip member and rewrites the index, so a crash
+// loses at most the entries written since the last Flush.
+type ArchiveWriter struct {
+	dir       string
+	format    string
+	partition time.Duration
+	index     ArchiveIndex
+
+	file *os.File
+	gz   *gzip.Writer
+	part int // index into index.Partitions of the open file
+}
+
+// OpenArchiveWriter opens or creates an archive in dir. An existing archive
+// keeps its format; entries at or before its last timestamp are skipped.
+func OpenArchiveWriter(dir, format string, partition time.Duration) (*ArchiveWriter, error) {
+	if format != ArchiveFormatBSON && format != ArchiveFormatNDJSON {
+		return nil, fmt.Errorf("%w: %s", ErrArchiveFormat, format)
+	}
+	if partition <= 0 {
+		partition = DefaultArchivePartition
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	idx, err := ReadArchiveIndex(dir)
+	switch {
+	case errors.Is(err, ErrNoArchive):
+		idx = ArchiveIndex{Format: format}
+	case err != nil:
+		return nil, err
+	case idx.Format != format:
+		return nil, fmt.Errorf("%w: %s is %s", ErrArchiveFormatChanged, dir, idx.Format)
+	}
+	return &ArchiveWriter{dir: dir, format: format, partition: partition, index: idx, part: -1}, nil
+}
+
+// Last is the newest timestamp in the archive.
+func (w *ArchiveWriter) Last() bson.Timestamp { return w.index.Last() }
+
+// Index returns a copy of the current index.
+func (w *ArchiveWriter) Index() ArchiveIndex {
+	idx := w.index
+	idx.Partitions = append([]ArchivePartition(nil), w.index.Partitions...)
+	return idx
+}
+
+// Write appends one raw oplog entry.
+func (w *ArchiveWriter) Write(raw bson.Raw) error {
+	ts, err := entryTimestamp(raw)
+	if err != nil {
+		return err
+	}
+	if !ts.After(w.index.Last()) {
+		return nil
+	}
+	start := entryTime(raw, ts).Truncate(w.partition)
+	if w.part < 0 || !w.index.Partitions[w.part].Start.Equal(start) {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := w.openPartition(start); err != nil {
+			return err
+		}
+	}
+
+	data := []byte(raw)
+	if w.format == ArchiveFormatNDJSON {
+		if data, err = bson.MarshalExtJSON(raw, true, false); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	if _, err := w.gz.Write(data); err != nil {
+		return err
+	}
+	p := &w.index.Partitions[w.part]
+	if p.First.IsZero() {
+		p.First = ts
+	}
+	p.Last = ts
+	p.Count++
+	p.Bytes += int64(len(raw))
+	return nil
+}
+
+func (w *ArchiveWriter) openPartition(start time.Time) error {
+	w.part = -1
+	for i, p := range w.index.Partitions {
+		if p.Start.Equal(start) {
+			w.part = i
+		}
+	}
+	if w.part < 0 {
+		name := fmt.Sprintf("oplog-%s.%s.gz", start.UTC().Format("20060102T1504Z"), w.format)
+		w.index.Partitions = append(w.index.Partitions, ArchivePartition{File: name, Start: start.UTC()})
+		sort.Slice(w.index.Partitions, func(i, j int) bool {
+			return w.index.Partitions[i].Start.Before(w.index.Partitions[j].Start)
+		})
+		for i, p := range w.index.Partitions {
+			if p.File == name {
+				w.part = i
+			}
+		}
+	}
+	// gzip readers treat concatenated members as one stream, so reopening a
+	// partition appends a new member. A crash can leave a torn member after
+	// the last flush; cutting the file back to the indexed length drops it,
+	// and its entries are archived again since the index never listed them.
+	p := w.index.Partitions[w.part]
+	f, err := os.OpenFile(filepath.Join(w.dir, p.File), os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	end := p.Flushed
+	if end == 0 && p.Count > 0 {
+		// Indexes written before Flushed was recorded: keep the whole file.
+		if end, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.gz = f, gzip.NewWriter(f)
+	return nil
+}
+
+// Flush closes the open partition and saves the index.
+func (w *ArchiveWriter) Flush() error {
+	if w.file == nil {
+		return nil
+	}
+	f := w.file
+	w.file = nil
+	if err := w.gz.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	end, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	w.index.Partitions[w.part].Flushed = end
+	w.part = -1
+
+	data, err := json.MarshalIndent(w.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(w.dir, ArchiveIndexFile), append(data, '\n'))
+}
+
+// Close flushes the archive.
+func (w *ArchiveWriter) Close() error { return w.Flush() }
+
+// ScanArchive calls fn for every archived entry in [from, to] in timestamp
+// order, opening only the partitions the index says overlap the range. A zero
+// bound is open.
+func ScanArchive(dir string, from, to bson.Timestamp, fn func(bson.Raw) error) error {
+	idx, err := ReadArchiveIndex(dir)
+	if err != nil {
+		return err
+	}
+	var last bson.Timestamp
+	for _, p := range idx.Partitions {
+		if (!to.IsZero() && p.First.After(to)) || (!from.IsZero() && p.Last.Before(from)) {
+			continue
+		}
+		err := readPartition(filepath.Join(dir, p.File), idx.Format, func(raw bson.Raw) error {
+			ts, err := entryTimestamp(raw)
+			if err != nil {
+				return err
+			}
+			// An interrupted writer can leave entries that were written again
+			// after resuming; keep the first copy.
+			if !ts.After(last) {
+				return nil
+			}
+			last = ts
+			if (!from.IsZero() && ts.Before(from)) || (!to.IsZero() && ts.After(to)) {
+				return nil
+			}
+			return fn(raw)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.File, err)
+		}
+	}
+	return nil
+}
+
+func readPartition(path, format string, fn func(bson.Raw) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorruptArchive, err)
+	}
+	defer gz.Close()
+	r := bufio.NewReader(gz)
+
+	if format == ArchiveFormatNDJSON {
+		for {
+			line, err := r.ReadBytes('\n')
+			if len(line) > 1 {
+				var doc bson.D
+				if uerr := bson.UnmarshalExtJSON(line, true, &doc); uerr != nil {
+					return fmt.Errorf("%w: %w", ErrCorruptArchive, uerr)
+				}
+				raw, merr := bson.Marshal(doc)
+				if merr != nil {
+					return merr
+				}
+				if ferr := fn(raw); ferr != nil {
+					return ferr
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(r, size[:]); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: %w", ErrCorruptArchive, err)
+		}
+		n := binary.LittleEndian.Uint32(size[:])
+		if n < 5 {
+			return fmt.Errorf("%w: document length %d", ErrCorruptArchive, n)
+		}
+		doc := make([]byte, n)
+		copy(doc, size[:])
+		if _, err := io.ReadFull(r, doc[4:]); err != nil {
+			return fmt.Errorf("%w: %w", ErrCorruptArchive, err)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+func entryTimestamp(raw bson.Raw) (bson.Timestamp, error) {
+	v, err := raw.LookupErr("ts")
+	if err != nil {
+		return bson.Timestamp{}, fmt.Errorf("%w: entry without ts", ErrCorruptArchive)
+	}
+	t, i, ok := v.TimestampOK()
+	if !ok {
+		return bson.Timestamp{}, fmt.Errorf("%w: ts is %s", ErrCorruptArchive, v.Type)
+	}
+	return bson.Timestamp{T: t, I: i}, nil
+}
+
+// entryTime partitions by the wall clock when the entry has one, which keeps
+// files aligned with what people search by.
+func entryTime(raw bson.Raw, ts bson.Timestamp) time.Time {
+	if wall, ok := raw.Lookup("wall").TimeOK(); ok {
+		return wall.UTC()
+	}
+	return time.Unix(int64(ts.T), 0).UTC()
+}
+
+// writeFileAtomic replaces path through a synced temporary file so readers
+// never see a partial write.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
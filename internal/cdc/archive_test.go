@@ -0,0 +1,180 @@
+package cdc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func archiveEntry(t *testing.T, sec uint32, wall time.Time) bson.Raw {
+	t.Helper()
+	raw, err := bson.Marshal(bson.D{
+		{Key: "ts", Value: bson.Timestamp{T: sec, I: 1}},
+		{Key: "op", Value: "i"},
+		{Key: "ns", Value: "app.orders"},
+		{Key: "wall", Value: wall},
+		{Key: "o", Value: bson.D{{Key: "_id", Value: int64(sec)}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestArchiveRoundTripAcrossPartitions(t *testing.T) {
+	for _, format := range []string{ArchiveFormatBSON, ArchiveFormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			base := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+
+			w, err := OpenArchiveWriter(dir, format, time.Hour)
+			if err != nil {
+				t.Fatalf("OpenArchiveWriter: %v", err)
+			}
+			for i, offset := range []time.Duration{0, 30 * time.Minute, 90 * time.Minute} {
+				if err := w.Write(archiveEntry(t, uint32(100+i), base.Add(offset))); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			// Reopening appends a new gzip member and skips what is archived.
+			w, err = OpenArchiveWriter(dir, format, time.Hour)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			if err := w.Write(archiveEntry(t, 101, base)); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(archiveEntry(t, 103, base.Add(100*time.Minute))); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			idx, err := ReadArchiveIndex(dir)
+			if err != nil {
+				t.Fatalf("ReadArchiveIndex: %v", err)
+			}
+			if len(idx.Partitions) != 2 || idx.Partitions[0].Count != 2 || idx.Partitions[1].Count != 2 {
+				t.Fatalf("unexpected partitions: %+v", idx.Partitions)
+			}
+			if idx.Last() != (bson.Timestamp{T: 103, I: 1}) {
+				t.Fatalf("Last = %v", idx.Last())
+			}
+
+			var got []uint32
+			err = ScanArchive(dir, bson.Timestamp{T: 101}, bson.Timestamp{T: 103, I: 1}, func(raw bson.Raw) error {
+				ts, err := entryTimestamp(raw)
+				got = append(got, ts.T)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("ScanArchive: %v", err)
+			}
+			if len(got) != 3 || got[0] != 101 || got[2] != 103 {
+				t.Fatalf("scanned %v, want [101 102 103]", got)
+			}
+		})
+	}
+}
+
+func TestArchiveKeepsFormat(t *testing.T) {
+	dir := t.TempDir()
+	w, err := OpenArchiveWriter(dir, ArchiveFormatBSON, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(archiveEntry(t, 1, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenArchiveWriter(dir, ArchiveFormatNDJSON, 0); !errors.Is(err, ErrArchiveFormatChanged) {
+		t.Fatalf("expected ErrArchiveFormatChanged, got %v", err)
+	}
+	if _, err := OpenArchiveWriter(dir, "parquet", 0); !errors.Is(err, ErrArchiveFormat) {
+		t.Fatalf("expected ErrArchiveFormat, got %v", err)
+	}
+}
+
+func TestScanArchiveReportsCorruption(t *testing.T) {
+	dir := t.TempDir()
+	if err := ScanArchive(dir, bson.Timestamp{}, bson.Timestamp{}, nil); !errors.Is(err, ErrNoArchive) {
+		t.Fatalf("expected ErrNoArchive, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ArchiveIndexFile),
+		[]byte(`{"format":"bson","partitions":[{"file":"bad.bson.gz"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.bson.gz"), []byte("not gzip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := ScanArchive(dir, bson.Timestamp{}, bson.Timestamp{}, func(bson.Raw) error { return nil })
+	if !errors.Is(err, ErrCorruptArchive) {
+		t.Fatalf("expected ErrCorruptArchive, got %v", err)
+	}
+}
+
+func TestArchiveDropsTornMemberOnReopen(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	w, err := OpenArchiveWriter(dir, ArchiveFormatBSON, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(archiveEntry(t, 100, base)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A writer that dies mid-flush leaves part of a gzip member behind.
+	idx, err := ReadArchiveIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, idx.Partitions[0].File), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{0x1f, 0x8b, 0x08, 0x00, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = OpenArchiveWriter(dir, ArchiveFormatBSON, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(archiveEntry(t, 101, base.Add(time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []uint32
+	err = ScanArchive(dir, bson.Timestamp{}, bson.Timestamp{}, func(raw bson.Raw) error {
+		ts, err := entryTimestamp(raw)
+		got = append(got, ts.T)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("ScanArchive after a torn member: %v", err)
+	}
+	if len(got) != 2 || got[0] != 100 || got[1] != 101 {
+		t.Fatalf("scanned %v, want [100 101]", got)
+	}
+}
@@ -0,0 +1,277 @@
+package cdc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrNoCheckpoint      = errors.New("no checkpoint stored")
+	ErrUnsupportedStore  = errors.New("unsupported token store (use file:<path>, mongo:<collection>[/<name>] or memory)")
+	ErrCorruptCheckpoint = errors.New("corrupt checkpoint")
+)
+
+// DefaultCheckpointColl is used by "mongo:" specs that name no collection.
+const DefaultCheckpointColl = "oplog_checkpoints"
+
+// Checkpoint is the position of the last delivered change event. ClusterTime
+// lets a stream start with startAtOperationTime when there is no Token, such
+// as a --from start time.
+type Checkpoint struct {
+	Token       bson.Raw
+	ClusterTime bson.Timestamp
+	UpdatedAt   time.Time
+}
+
+// TokenStore persists checkpoints. Load returns ErrNoCheckpoint when nothing
+// has been saved yet.
+type TokenStore interface {
+	Load(ctx context.Context) (Checkpoint, error)
+	Save(ctx context.Context, cp Checkpoint) error
+}
+
+// OpenTokenStore builds a store from a spec. mongo stores live in db.
+//
+//	file:/var/lib/mongork/oplog.token
+//	mongo:oplog_checkpoints/orders-sink
+//	memory
+func OpenTokenStore(spec string, db *mongo.Database) (TokenStore, error) {
+	switch {
+	case spec == "memory":
+		return &MemoryTokenStore{}, nil
+	case strings.HasPrefix(spec, "file:"):
+		return NewFileTokenStore(strings.TrimPrefix(strings.TrimPrefix(spec, "file:"), "//")), nil
+	case strings.HasPrefix(spec, "mongo:"):
+		if db == nil {
+			return nil, fmt.Errorf("%w: %s needs a database", ErrUnsupportedStore, spec)
+		}
+		coll, name, _ := strings.Cut(strings.TrimPrefix(spec, "mongo:"), "/")
+		if coll == "" {
+			coll = DefaultCheckpointColl
+		}
+		if name == "" {
+			name = "default"
+		}
+		return NewMongoTokenStore(db.Collection(coll), name), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedStore, spec)
+	}
+}
+
+// fileCheckpoint is the on-disk form; the token is hex so the file stays
+// readable and diffable.
+type fileCheckpoint struct {
+	Token       string    `json:"token"`
+	ClusterTime uint64    `json:"cluster_time,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FileTokenStore keeps the checkpoint in a JSON file. Saves write a temporary
+// file in the same directory, fsync it and rename it over the old one, so a
+// crash never leaves a truncated token behind.
+type FileTokenStore struct {
+	path string
+}
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load also accepts the raw BSON token files written by earlier versions.
+func (s *FileTokenStore) Load(context.Context) (Checkpoint, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return Checkpoint{}, ErrNoCheckpoint
+	}
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	var fc fileCheckpoint
+	if err := json.Unmarshal(data, &fc); err != nil {
+		if bson.Raw(data).Validate() != nil {
+			return Checkpoint{}, fmt.Errorf("%w: %s", ErrCorruptCheckpoint, s.path)
+		}
+		info, _ := os.Stat(s.path)
+		cp := Checkpoint{Token: bson.Raw(data)}
+		if info != nil {
+			cp.UpdatedAt = info.ModTime()
+		}
+		return cp, nil
+	}
+	token, err := hex.DecodeString(fc.Token)
+	if err != nil || bson.Raw(token).Validate() != nil {
+		return Checkpoint{}, fmt.Errorf("%w: %s", ErrCorruptCheckpoint, s.path)
+	}
+	return Checkpoint{
+		Token:       token,
+		ClusterTime: bson.Timestamp{T: uint32(fc.ClusterTime >> 32), I: uint32(fc.ClusterTime)},
+		UpdatedAt:   fc.UpdatedAt,
+	}, nil
+}
+
+func (s *FileTokenStore) Save(_ context.Context, cp Checkpoint) error {
+	data, err := json.MarshalIndent(fileCheckpoint{
+		Token:       hex.EncodeToString(cp.Token),
+		ClusterTime: uint64(cp.ClusterTime.T)<<32 | uint64(cp.ClusterTime.I),
+		UpdatedAt:   cp.UpdatedAt,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, append(data, '\n'))
+}
+
+// MongoTokenStore keeps one checkpoint document per name, so several tails can
+// share a collection.
+type MongoTokenStore struct {
+	coll *mongo.Collection
+	name string
+}
+
+func NewMongoTokenStore(coll *mongo.Collection, name string) *MongoTokenStore {
+	return &MongoTokenStore{coll: coll, name: name}
+}
+
+type mongoCheckpoint struct {
+	ID          string         `bson:"_id"`
+	Token       bson.Raw       `bson:"token"`
+	ClusterTime bson.Timestamp `bson:"cluster_time"`
+	UpdatedAt   time.Time      `bson:"updated_at"`
+}
+
+// Namespace is the db.collection holding the checkpoints, which a cluster-wide
+// stream must not watch or it would see its own checkpoint writes.
+func (s *MongoTokenStore) Namespace() string {
+	return s.coll.Database().Name() + "." + s.coll.Name()
+}
+
+func (s *MongoTokenStore) Load(ctx context.Context) (Checkpoint, error) {
+	var doc mongoCheckpoint
+	err := s.coll.FindOne(ctx, bson.M{"_id": s.name}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Checkpoint{}, ErrNoCheckpoint
+	}
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	return Checkpoint{Token: doc.Token, ClusterTime: doc.ClusterTime, UpdatedAt: doc.UpdatedAt}, nil
+}
+
+func (s *MongoTokenStore) Save(ctx context.Context, cp Checkpoint) error {
+	doc := mongoCheckpoint{ID: s.name, Token: cp.Token, ClusterTime: cp.ClusterTime, UpdatedAt: cp.UpdatedAt}
+	_, err := s.coll.ReplaceOne(ctx, bson.M{"_id": s.name}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+// MemoryTokenStore keeps the checkpoint for the life of the process; useful for
+// tests and one-off tails that should not persist their position.
+type MemoryTokenStore struct {
+	mu sync.Mutex
+	cp *Checkpoint
+}
+
+func (s *MemoryTokenStore) Load(context.Context) (Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cp == nil {
+		return Checkpoint{}, ErrNoCheckpoint
+	}
+	return *s.cp, nil
+}
+
+func (s *MemoryTokenStore) Save(_ context.Context, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cp = &cp
+	return nil
+}
+
+// Checkpointer records acknowledged positions and writes them to a store at
+// most once per interval. Flush(ctx, true) writes the latest position
+// regardless, e.g. on shutdown.
+type Checkpointer struct {
+	store    TokenStore
+	interval time.Duration
+
+	pending   *Checkpoint
+	latest    *Checkpoint
+	lastSaved time.Time
+	nowFunc   func() time.Time
+}
+
+func NewCheckpointer(store TokenStore, interval time.Duration) *Checkpointer {
+	return &Checkpointer{store: store, interval: interval, nowFunc: time.Now}
+}
+
+// Mark records a position whose events have been delivered and flushes it if
+// the interval has passed.
+func (c *Checkpointer) Mark(ctx context.Context, cp Checkpoint) error {
+	if len(cp.Token) == 0 {
+		return nil
+	}
+	cp.UpdatedAt = c.nowFunc().UTC()
+	c.pending, c.latest = &cp, &cp
+	return c.Flush(ctx, false)
+}
+
+// Latest returns the most recent marked position, saved or not, which is
+// where an in-process reconnect should resume.
+func (c *Checkpointer) Latest() (Checkpoint, bool) {
+	if c.latest == nil {
+		return Checkpoint{}, false
+	}
+	return *c.latest, true
+}
+
+func (c *Checkpointer) Flush(ctx context.Context, force bool) error {
+	if c.pending == nil {
+		return nil
+	}
+	if !force && c.interval > 0 && c.nowFunc().Sub(c.lastSaved) < c.interval {
+		return nil
+	}
+	if err := c.store.Save(ctx, *c.pending); err != nil {
+		return err
+	}
+	c.pending = nil
+	c.lastSaved = c.nowFunc()
+	return nil
+}
+
+// Server error codes for change streams that can no longer resume from a token.
+const (
+	codeInvalidResumeToken      = 260
+	codeChangeStreamFatalError  = 280
+	codeChangeStreamHistoryLost = 286
+)
+
+// IsHistoryLost reports whether err means the resume point has rolled off the
+// oplog or the token can no longer be used to resume.
+func IsHistoryLost(err error) bool {
+	var se mongo.ServerError
+	if !errors.As(err, &se) {
+		return false
+	}
+	return se.HasErrorCode(codeChangeStreamHistoryLost) ||
+		se.HasErrorCode(codeChangeStreamFatalError) ||
+		se.HasErrorCode(codeInvalidResumeToken)
+}
+
+// IsTokenRolledOff reports whether err means the token's position is no longer
+// in the oplog. No other way of resuming from that position can succeed.
+func IsTokenRolledOff(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorCode(codeChangeStreamHistoryLost)
+}
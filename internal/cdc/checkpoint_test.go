@@ -0,0 +1,108 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func testToken(t *testing.T, data string) bson.Raw {
+	t.Helper()
+	raw, err := bson.Marshal(bson.D{{Key: "_data", Value: data}})
+	if err != nil {
+		t.Fatalf("marshal token: %v", err)
+	}
+	return raw
+}
+
+func TestFileTokenStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewFileTokenStore(filepath.Join(dir, "oplog.token"))
+	if _, err := store.Load(ctx); !errors.Is(err, ErrNoCheckpoint) {
+		t.Fatalf("expected ErrNoCheckpoint, got %v", err)
+	}
+
+	want := Checkpoint{Token: testToken(t, "8266"), ClusterTime: bson.Timestamp{T: 1700000000, I: 7}}
+	if err := store.Save(ctx, want); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	got, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !bytes.Equal(got.Token, want.Token) || got.ClusterTime != want.ClusterTime {
+		t.Fatalf("round trip mismatch: got %+v want %+v", got, want)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected temp file to be renamed away, found %d entries", len(entries))
+	}
+}
+
+func TestFileTokenStoreLegacyAndCorrupt(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "oplog.token")
+	legacy := testToken(t, "legacy")
+	if err := os.WriteFile(path, legacy, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := NewFileTokenStore(path).Load(ctx)
+	if err != nil || !bytes.Equal(cp.Token, legacy) {
+		t.Fatalf("expected legacy raw token to load, got %+v (%v)", cp, err)
+	}
+
+	if err := os.WriteFile(path, legacy[:len(legacy)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileTokenStore(path).Load(ctx); !errors.Is(err, ErrCorruptCheckpoint) {
+		t.Fatalf("expected ErrCorruptCheckpoint for truncated token, got %v", err)
+	}
+}
+
+func TestCheckpointerFlushInterval(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryTokenStore{}
+	c := NewCheckpointer(store, time.Minute)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.nowFunc = func() time.Time { return now }
+
+	if err := c.Mark(ctx, Checkpoint{Token: testToken(t, "1")}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Second)
+	if err := c.Mark(ctx, Checkpoint{Token: testToken(t, "2")}); err != nil {
+		t.Fatal(err)
+	}
+	cp, _ := store.Load(ctx)
+	if data, _ := cp.Token.Lookup("_data").StringValueOK(); data != "1" {
+		t.Fatalf("expected second mark to wait for the interval, stored %q", data)
+	}
+
+	if err := c.Flush(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	cp, _ = store.Load(ctx)
+	if data, _ := cp.Token.Lookup("_data").StringValueOK(); data != "2" {
+		t.Fatalf("expected forced flush to store latest token, stored %q", data)
+	}
+}
+
+func TestOpenTokenStoreSpecs(t *testing.T) {
+	if _, err := OpenTokenStore("memory", nil); err != nil {
+		t.Fatalf("memory store: %v", err)
+	}
+	if _, err := OpenTokenStore("mongo:checkpoints/orders", nil); !errors.Is(err, ErrUnsupportedStore) {
+		t.Fatalf("expected mongo store without database to fail, got %v", err)
+	}
+	if _, err := OpenTokenStore("redis://localhost", nil); !errors.Is(err, ErrUnsupportedStore) {
+		t.Fatalf("expected ErrUnsupportedStore, got %v", err)
+	}
+}
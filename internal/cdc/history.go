@@ -0,0 +1,371 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrHistoryNoBaseline = errors.New("no insert found in the oplog for this document " +
+		"(it may have rolled off; pass a starting document)")
+	ErrHistoryNamespace = errors.New("namespace must be db.collection")
+)
+
+// HistoryOptions bound the oplog walk. Start, when set, is the document as it
+// was at From; otherwise the walk begins at the document's insert.
+type HistoryOptions struct {
+	From  bson.Timestamp
+	To    bson.Timestamp
+	Start bson.M
+	Limit int
+}
+
+// DocumentVersion is the document after one oplog entry was applied.
+type DocumentVersion struct {
+	Timestamp time.Time     `json:"timestamp"`
+	OpTime    string        `json:"optime"`
+	Operation string        `json:"operation"`
+	Document  bson.M        `json:"document,omitempty"`
+	Changes   []FieldChange `json:"changes"`
+	TxnNumber *int64        `json:"txn_number,omitempty"`
+}
+
+// FieldChange is one field-level difference between consecutive versions.
+type FieldChange struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+type historyEntry struct {
+	TS        bson.Timestamp `bson:"ts"`
+	Op        string         `bson:"op"`
+	NS        string         `bson:"ns"`
+	Wall      *time.Time     `bson:"wall,omitempty"`
+	O         bson.M         `bson:"o"`
+	O2        bson.M         `bson:"o2,omitempty"`
+	TxnNumber *int64         `bson:"txnNumber,omitempty"`
+}
+
+// DocumentHistory walks the oplog forward for one document, including writes
+// made inside transactions, and returns every version with field diffs.
+func DocumentHistory(
+	ctx context.Context,
+	client *mongo.Client,
+	ns string,
+	id any,
+	opts HistoryOptions,
+) ([]DocumentVersion, error) {
+	if db, coll, ok := strings.Cut(ns, "."); !ok || db == "" || coll == "" {
+		return nil, fmt.Errorf("%w: %s", ErrHistoryNamespace, ns)
+	}
+	oplog, err := OplogCollection(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := bson.A{bson.M{"o._id": id}, bson.M{"o2._id": id}}
+	filter := bson.D{{Key: "$or", Value: bson.A{
+		bson.M{"ns": ns, "$or": byID},
+		bson.M{"op": "c", "o.applyOps": bson.M{"$elemMatch": bson.M{"ns": ns, "$or": byID}}},
+	}}}
+	ts := bson.M{}
+	if !opts.From.IsZero() {
+		ts["$gte"] = opts.From
+	}
+	if !opts.To.IsZero() {
+		ts["$lte"] = opts.To
+	}
+	if len(ts) > 0 {
+		filter = append(filter, bson.E{Key: "ts", Value: ts})
+	}
+
+	cur, err := oplog.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "$natural", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var raw []historyEntry
+	if err := cur.All(ctx, &raw); err != nil {
+		return nil, err
+	}
+	return buildHistory(expandHistoryEntries(raw, ns, id), opts)
+}
+
+// expandHistoryEntries replaces applyOps entries with the inner operations on
+// this document, tagged with the transaction number.
+func expandHistoryEntries(entries []historyEntry, ns string, id any) []historyEntry {
+	out := make([]historyEntry, 0, len(entries))
+	for _, e := range entries {
+		ops, ok := e.O["applyOps"].(bson.A)
+		if e.Op != "c" || !ok {
+			out = append(out, e)
+			continue
+		}
+		for _, rawOp := range ops {
+			op, ok := ToBsonM(rawOp)
+			if !ok || op["ns"] != ns {
+				continue
+			}
+			inner := historyEntry{TS: e.TS, Wall: e.Wall, NS: ns, TxnNumber: e.TxnNumber}
+			inner.Op, _ = op["op"].(string)
+			inner.O, _ = ToBsonM(op["o"])
+			inner.O2, _ = ToBsonM(op["o2"])
+			if sameID(inner.O["_id"], id) || sameID(inner.O2["_id"], id) {
+				out = append(out, inner)
+			}
+		}
+	}
+	return out
+}
+
+func sameID(a, b any) bool {
+	if a == nil {
+		return false
+	}
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	af, aNum := Number(a)
+	bf, bNum := Number(b)
+	return aNum && bNum && af == bf
+}
+
+func buildHistory(entries []historyEntry, opts HistoryOptions) ([]DocumentVersion, error) {
+	var (
+		versions []DocumentVersion
+		current  bson.M
+		started  bool
+	)
+	if opts.Start != nil {
+		current = normalizeDoc(opts.Start)
+		started = true
+		versions = append(versions, DocumentVersion{
+			Operation: "baseline",
+			Document:  cloneDoc(current),
+			Changes:   diffDocs(nil, current),
+		})
+	}
+
+	for _, e := range entries {
+		var next bson.M
+		operation := ""
+		switch e.Op {
+		case "i":
+			next, operation = normalizeDoc(e.O), "insert"
+		case "u":
+			if !started {
+				continue
+			}
+			if IsOperatorUpdate(e.O) {
+				next, operation = applyUpdate(cloneDoc(current), e.O), "update"
+			} else {
+				next, operation = normalizeDoc(e.O), "replace"
+			}
+		case "d":
+			if !started {
+				continue
+			}
+			operation = "delete"
+		default:
+			continue
+		}
+		started = true
+		at := time.Unix(int64(e.TS.T), 0).UTC()
+		if e.Wall != nil {
+			at = e.Wall.UTC()
+		}
+		versions = append(versions, DocumentVersion{
+			Timestamp: at,
+			OpTime:    fmt.Sprintf("%d:%d", e.TS.T, e.TS.I),
+			Operation: operation,
+			Document:  cloneDoc(next),
+			Changes:   diffDocs(current, next),
+			TxnNumber: e.TxnNumber,
+		})
+		current = next
+		if opts.Limit > 0 && len(versions) >= opts.Limit {
+			break
+		}
+	}
+	if !started {
+		return nil, ErrHistoryNoBaseline
+	}
+	return versions, nil
+}
+
+// applyUpdate applies an oplog update: a $v:2 diff or $set/$unset. Arrays are
+// resized first so element updates land on the new length.
+func applyUpdate(doc bson.M, o bson.M) bson.M {
+	delta := ParseOplogUpdate(o)
+	for _, t := range delta.Truncated {
+		arr, _ := lookupPath(doc, t.Field).(bson.A)
+		resized := make(bson.A, t.NewSize)
+		copy(resized, arr)
+		setPath(doc, t.Field, resized)
+	}
+	for path, v := range delta.Set {
+		setPath(doc, path, normalizeValue(v))
+	}
+	for _, path := range delta.Unset {
+		unsetPath(doc, path)
+	}
+	return doc
+}
+
+// arrayIndex reads a path component as an element index when the value at
+// that level is an array.
+func arrayIndex(container any, part string) (bson.A, int, bool) {
+	arr, ok := container.(bson.A)
+	if !ok {
+		return nil, 0, false
+	}
+	idx, err := strconv.Atoi(part)
+	if err != nil || idx < 0 {
+		return nil, 0, false
+	}
+	return arr, idx, true
+}
+
+func lookupPath(doc bson.M, path string) any {
+	var cur any = doc
+	for _, p := range strings.Split(path, ".") {
+		if arr, idx, ok := arrayIndex(cur, p); ok {
+			if idx >= len(arr) {
+				return nil
+			}
+			cur = arr[idx]
+			continue
+		}
+		m, ok := cur.(bson.M)
+		if !ok {
+			return nil
+		}
+		cur = m[p]
+	}
+	return cur
+}
+
+// setPath sets a dotted path, indexing into arrays (padding them with nulls)
+// and creating missing sub-documents on the way.
+func setPath(doc bson.M, path string, v any) {
+	setIn(doc, strings.Split(path, "."), v)
+}
+
+func setIn(container any, parts []string, v any) any {
+	if len(parts) == 0 {
+		return v
+	}
+	if arr, idx, ok := arrayIndex(container, parts[0]); ok {
+		for len(arr) <= idx {
+			arr = append(arr, nil)
+		}
+		arr[idx] = setIn(arr[idx], parts[1:], v)
+		return arr
+	}
+	m, ok := container.(bson.M)
+	if !ok {
+		m = bson.M{}
+	}
+	m[parts[0]] = setIn(m[parts[0]], parts[1:], v)
+	return m
+}
+
+// unsetPath removes a dotted path; like $unset, an array element becomes null.
+func unsetPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	parent := lookupPath(doc, strings.Join(parts[:len(parts)-1], "."))
+	if len(parts) == 1 {
+		parent = doc
+	}
+	last := parts[len(parts)-1]
+	if arr, idx, ok := arrayIndex(parent, last); ok {
+		if idx < len(arr) {
+			arr[idx] = nil
+		}
+		return
+	}
+	if m, ok := parent.(bson.M); ok {
+		delete(m, last)
+	}
+}
+
+// diffDocs lists added, changed and removed leaf fields as dotted paths.
+func diffDocs(before, after bson.M) []FieldChange {
+	old, cur := map[string]any{}, map[string]any{}
+	flatten("", before, old)
+	flatten("", after, cur)
+
+	changes := []FieldChange{}
+	for path, v := range cur {
+		prev, ok := old[path]
+		switch {
+		case !ok:
+			changes = append(changes, FieldChange{Path: path, Kind: "added", New: v})
+		case !reflect.DeepEqual(prev, v):
+			changes = append(changes, FieldChange{Path: path, Kind: "changed", Old: prev, New: v})
+		}
+	}
+	for path, v := range old {
+		if _, ok := cur[path]; !ok {
+			changes = append(changes, FieldChange{Path: path, Kind: "removed", Old: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// flatten treats sub-documents as paths and arrays as leaves, so an array
+// change shows the whole old and new array.
+func flatten(prefix string, doc bson.M, out map[string]any) {
+	for k, v := range doc {
+		path := prefix + k
+		if child, ok := v.(bson.M); ok && len(child) > 0 {
+			flatten(path+".", child, out)
+			continue
+		}
+		out[path] = v
+	}
+}
+
+// normalizeValue converts nested bson.D values to bson.M so documents can be
+// patched and compared by field.
+func normalizeValue(v any) any {
+	if m, ok := ToBsonM(v); ok {
+		return normalizeDoc(m)
+	}
+	if arr, ok := v.(bson.A); ok {
+		out := make(bson.A, len(arr))
+		for i := range arr {
+			out[i] = normalizeValue(arr[i])
+		}
+		return out
+	}
+	return v
+}
+
+func normalizeDoc(doc bson.M) bson.M {
+	out := make(bson.M, len(doc))
+	for k, v := range doc {
+		out[k] = normalizeValue(v)
+	}
+	return out
+}
+
+func cloneDoc(doc bson.M) bson.M {
+	if doc == nil {
+		return nil
+	}
+	return normalizeDoc(doc)
+}
@@ -0,0 +1,111 @@
+package cdc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestBuildHistoryAppliesDiffs(t *testing.T) {
+	entries := []historyEntry{
+		{TS: bson.Timestamp{T: 100, I: 1}, Op: "i", O: bson.M{"_id": 1, "status": "new", "items": bson.A{"a"}}},
+		{TS: bson.Timestamp{T: 101, I: 1}, Op: "u", O: bson.M{"$v": 2, "diff": bson.D{
+			{Key: "u", Value: bson.D{{Key: "status", Value: "paid"}}},
+			{Key: "i", Value: bson.D{{Key: "total", Value: 10}}},
+			{Key: "sitems", Value: bson.D{{Key: "a", Value: true}, {Key: "u1", Value: "b"}}},
+		}}},
+		{TS: bson.Timestamp{T: 102, I: 1}, Op: "u", O: bson.M{"$unset": bson.M{"total": true},
+			"$set": bson.M{"shipping.city": "Berlin"}}},
+		{TS: bson.Timestamp{T: 103, I: 1}, Op: "d", O: bson.M{"_id": 1}},
+	}
+
+	versions, err := buildHistory(entries, HistoryOptions{})
+	if err != nil {
+		t.Fatalf("buildHistory: %v", err)
+	}
+	if len(versions) != 4 {
+		t.Fatalf("got %d versions, want 4", len(versions))
+	}
+
+	update := versions[1]
+	if update.Operation != "update" || update.OpTime != "101:1" {
+		t.Fatalf("unexpected version header: %+v", update)
+	}
+	wantChanges := []FieldChange{
+		{Path: "items", Kind: "changed", Old: bson.A{"a"}, New: bson.A{"a", "b"}},
+		{Path: "status", Kind: "changed", Old: "new", New: "paid"},
+		{Path: "total", Kind: "added", New: 10},
+	}
+	if !reflect.DeepEqual(update.Changes, wantChanges) {
+		t.Fatalf("changes = %+v, want %+v", update.Changes, wantChanges)
+	}
+
+	legacy := versions[2]
+	wantLegacy := []FieldChange{
+		{Path: "shipping.city", Kind: "added", New: "Berlin"},
+		{Path: "total", Kind: "removed", Old: 10},
+	}
+	if !reflect.DeepEqual(legacy.Changes, wantLegacy) {
+		t.Fatalf("legacy changes = %+v, want %+v", legacy.Changes, wantLegacy)
+	}
+
+	if deleted := versions[3]; deleted.Document != nil || len(deleted.Changes) != 4 {
+		t.Fatalf("delete should clear the document and remove every field: %+v", deleted)
+	}
+}
+
+func TestBuildHistoryNeedsBaseline(t *testing.T) {
+	entries := []historyEntry{
+		{TS: bson.Timestamp{T: 5}, Op: "u", O: bson.M{"$v": 2, "diff": bson.M{"u": bson.M{"n": 2}}}},
+	}
+	if _, err := buildHistory(entries, HistoryOptions{}); !errors.Is(err, ErrHistoryNoBaseline) {
+		t.Fatalf("expected ErrHistoryNoBaseline, got %v", err)
+	}
+
+	versions, err := buildHistory(entries, HistoryOptions{Start: bson.M{"_id": 1, "n": 1}})
+	if err != nil {
+		t.Fatalf("buildHistory with start: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Operation != "baseline" {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+	if got := versions[1].Document["n"]; got != 2 {
+		t.Fatalf("n = %v, want 2", got)
+	}
+}
+
+func TestExpandHistoryEntriesKeepsTransactionOps(t *testing.T) {
+	txn := int64(7)
+	insert := func(ns string, id int32) bson.D {
+		return bson.D{{Key: "op", Value: "i"}, {Key: "ns", Value: ns}, {Key: "o", Value: bson.D{{Key: "_id", Value: id}}}}
+	}
+	entries := []historyEntry{{Op: "c", TxnNumber: &txn, O: bson.M{"applyOps": bson.A{
+		insert("app.orders", 1), insert("app.orders", 2), insert("app.other", 1),
+	}}}}
+
+	got := expandHistoryEntries(entries, "app.orders", int64(1))
+	if len(got) != 1 || got[0].Op != "i" || got[0].TxnNumber == nil || *got[0].TxnNumber != 7 {
+		t.Fatalf("unexpected expansion: %+v", got)
+	}
+}
+
+func TestApplyUpdateIndexesIntoArrays(t *testing.T) {
+	doc := bson.M{"items": bson.A{bson.M{"qty": 1}, bson.M{"qty": 2}, bson.M{"qty": 3}}}
+	got := applyUpdate(doc, bson.M{"$v": 2, "diff": bson.M{"sitems": bson.M{
+		"a":  true,
+		"l":  int32(2),
+		"s1": bson.M{"u": bson.M{"qty": 5}},
+	}}})
+	want := bson.M{"items": bson.A{bson.M{"qty": 1}, bson.M{"qty": 5}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("applyUpdate = %v, want %v", got, want)
+	}
+
+	got = applyUpdate(got, bson.M{"$set": bson.M{"items.3": "x"}, "$unset": bson.M{"items.0": true}})
+	want = bson.M{"items": bson.A{nil, bson.M{"qty": 5}, nil, "x"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("applyUpdate($set/$unset) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package cdc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var ErrNATSProtocol = errors.New("nats protocol error")
+
+// NATSSink publishes records over the NATS core text protocol using only the
+// standard library. Each record goes to <subject>.<db>.<collection>; a batch is
+// acknowledged once the server answers the trailing PING with PONG, which
+// means it has processed every PUB before it.
+type NATSSink struct {
+	addr    string
+	subject string
+	user    string
+	pass    string
+	opts    Options
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func NewNATSSink(u *url.URL, opts Options) *NATSSink {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	s := &NATSSink{
+		addr:    host,
+		subject: strings.Trim(strings.ReplaceAll(u.Path, "/", "."), "."),
+		opts:    opts,
+	}
+	if u.User != nil {
+		s.user = u.User.Username()
+		s.pass, _ = u.User.Password()
+	}
+	return s
+}
+
+func (s *NATSSink) Write(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return retry(ctx, s.opts, func(ctx context.Context) error {
+		if err := s.publish(ctx, records); err != nil {
+			s.reset()
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *NATSSink) publish(ctx context.Context, records []Record) error {
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetDeadline(deadline)
+	}
+
+	w := bufio.NewWriter(s.conn)
+	for _, r := range records {
+		fmt.Fprintf(w, "PUB %s %d\r\n", s.subjectFor(r), len(r.Value))
+		w.Write(r.Value)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("PING\r\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return s.awaitPong()
+}
+
+func (s *NATSSink) connect(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("%w: expected INFO, got %q", ErrNATSProtocol, strings.TrimSpace(line))
+	}
+
+	connect := map[string]any{"verbose": false, "pedantic": false, "name": "mongork-cdc", "lang": "go"}
+	if s.user != "" {
+		connect["user"] = s.user
+		connect["pass"] = s.pass
+	}
+	payload, _ := json.Marshal(connect)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", payload); err != nil {
+		conn.Close()
+		return err
+	}
+	s.conn, s.r = conn, r
+	return nil
+}
+
+// awaitPong reads until PONG, answering server PINGs and failing on -ERR.
+func (s *NATSSink) awaitPong() error {
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("%w: %s", ErrNATSProtocol, line)
+		}
+	}
+}
+
+func (s *NATSSink) subjectFor(r Record) string {
+	ns := strings.ReplaceAll(r.Namespace, " ", "_")
+	switch {
+	case s.subject == "":
+		return ns
+	case ns == "":
+		return s.subject
+	default:
+		return s.subject + "." + ns
+	}
+}
+
+func (s *NATSSink) reset() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn, s.r = nil, nil
+}
+
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+	return nil
+}
@@ -0,0 +1,179 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var ErrNoOplog = errors.New("oplog collection not found (requires replica set)")
+
+// OplogCollection returns local.oplog.rs, or local.oplog.$main on a legacy
+// master/slave deployment.
+func OplogCollection(ctx context.Context, client *mongo.Client) (*mongo.Collection, error) {
+	local := client.Database("local")
+	names, err := local.ListCollectionNames(ctx, bson.M{"name": bson.M{"$in": bson.A{"oplog.rs", "oplog.$main"}}})
+	if err != nil {
+		return nil, fmt.Errorf("list local collections: %w", err)
+	}
+	for _, name := range []string{"oplog.rs", "oplog.$main"} {
+		if slices.Contains(names, name) {
+			return local.Collection(name), nil
+		}
+	}
+	return nil, ErrNoOplog
+}
+
+// ParseDocumentID reads an _id given on the command line: a 24-character hex
+// string is an ObjectID, an integer is a number, a quoted value is a string.
+func ParseDocumentID(raw string) any {
+	if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') && raw[len(raw)-1] == raw[0] {
+		return raw[1 : len(raw)-1]
+	}
+	if oid, err := bson.ObjectIDFromHex(raw); err == nil {
+		return oid
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return n
+	}
+	return raw
+}
+
+// ToBsonM views a decoded sub-document as a bson.M; bson.D is copied.
+func ToBsonM(v any) (bson.M, bool) {
+	switch m := v.(type) {
+	case bson.M:
+		return m, m != nil
+	case bson.D:
+		out := make(bson.M, len(m))
+		for _, e := range m {
+			out[e.Key] = e.Value
+		}
+		return out, true
+	case map[string]any:
+		return bson.M(m), m != nil
+	default:
+		return nil, false
+	}
+}
+
+// IsOperatorUpdate reports whether the "o" of an update entry is written with
+// operators ($set/$unset, or $v with a diff) rather than being a replacement.
+func IsOperatorUpdate(o bson.M) bool {
+	for k := range o {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// Number reads any BSON number as a float64.
+func Number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// UpdateDelta is the field-level effect of an oplog update. Paths are dotted
+// and array elements are addressed by index, e.g. items.2.qty.
+type UpdateDelta struct {
+	Set       bson.M
+	Unset     []string
+	Truncated []ArrayTruncation
+}
+
+// ArrayTruncation resizes the array at Field to NewSize elements.
+type ArrayTruncation struct {
+	Field   string
+	NewSize int32
+}
+
+// Empty reports whether the update changes nothing.
+func (d UpdateDelta) Empty() bool {
+	return len(d.Set) == 0 && len(d.Unset) == 0 && len(d.Truncated) == 0
+}
+
+// ParseOplogUpdate decodes the "o" of an oplog update: either a $v:2 diff
+// ({u: {...}, i: {...}, d: {...}, s<field>: subdiff}) or the older $set/$unset
+// form. A document without operators is a replacement and has no delta.
+func ParseOplogUpdate(o bson.M) UpdateDelta {
+	var delta UpdateDelta
+	if d, ok := ToBsonM(o["diff"]); ok {
+		walkOplogDiff(&delta, "", d)
+	} else {
+		if set, ok := ToBsonM(o["$set"]); ok && len(set) > 0 {
+			delta.Set = set
+		}
+		if unset, ok := ToBsonM(o["$unset"]); ok {
+			for f := range unset {
+				delta.Unset = append(delta.Unset, f)
+			}
+		}
+	}
+	sort.Strings(delta.Unset)
+	sort.Slice(delta.Truncated, func(i, j int) bool { return delta.Truncated[i].Field < delta.Truncated[j].Field })
+	return delta
+}
+
+func walkOplogDiff(delta *UpdateDelta, prefix string, d bson.M) {
+	set := func(field string, v any) {
+		if delta.Set == nil {
+			delta.Set = bson.M{}
+		}
+		delta.Set[field] = v
+	}
+	if isArray, _ := d["a"].(bool); isArray {
+		if size, ok := Number(d["l"]); ok {
+			field := strings.TrimSuffix(prefix, ".")
+			delta.Truncated = append(delta.Truncated, ArrayTruncation{Field: field, NewSize: int32(size)})
+		}
+		for k, v := range d {
+			switch {
+			case strings.HasPrefix(k, "u"):
+				set(prefix+k[1:], v)
+			case strings.HasPrefix(k, "s"):
+				if sub, ok := ToBsonM(v); ok {
+					walkOplogDiff(delta, prefix+k[1:]+".", sub)
+				}
+			}
+		}
+		return
+	}
+	for k, v := range d {
+		switch {
+		case k == "u" || k == "i":
+			if fields, ok := ToBsonM(v); ok {
+				for f, val := range fields {
+					set(prefix+f, val)
+				}
+			}
+		case k == "d":
+			if fields, ok := ToBsonM(v); ok {
+				for f := range fields {
+					delta.Unset = append(delta.Unset, prefix+f)
+				}
+			}
+		case strings.HasPrefix(k, "s"):
+			if sub, ok := ToBsonM(v); ok {
+				walkOplogDiff(delta, prefix+k[1:]+".", sub)
+			}
+		}
+	}
+}
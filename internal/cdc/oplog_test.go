@@ -0,0 +1,54 @@
+package cdc
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestParseDocumentID(t *testing.T) {
+	oid := bson.NewObjectID()
+	cases := map[string]any{
+		oid.Hex(): oid,
+		"42":      int64(42),
+		`"42"`:    "42",
+		"sku-1":   "sku-1",
+	}
+	for raw, want := range cases {
+		if got := ParseDocumentID(raw); got != want {
+			t.Errorf("ParseDocumentID(%q) = %#v, want %#v", raw, got, want)
+		}
+	}
+}
+
+func TestParseOplogUpdate(t *testing.T) {
+	diff := bson.M{"$v": 2, "diff": bson.D{
+		{Key: "u", Value: bson.D{{Key: "status", Value: "paid"}}},
+		{Key: "d", Value: bson.D{{Key: "draft", Value: false}}},
+		{Key: "sshipping", Value: bson.D{{Key: "i", Value: bson.D{{Key: "city", Value: "Rio"}}}}},
+		{Key: "sitems", Value: bson.D{
+			{Key: "a", Value: true},
+			{Key: "l", Value: int32(2)},
+			{Key: "s1", Value: bson.D{{Key: "u", Value: bson.D{{Key: "qty", Value: 3}}}}},
+		}},
+	}}
+	want := UpdateDelta{
+		Set:       bson.M{"status": "paid", "shipping.city": "Rio", "items.1.qty": 3},
+		Unset:     []string{"draft"},
+		Truncated: []ArrayTruncation{{Field: "items", NewSize: 2}},
+	}
+	if got := ParseOplogUpdate(diff); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseOplogUpdate($v:2) = %+v, want %+v", got, want)
+	}
+
+	legacy := bson.M{"$set": bson.M{"a.b": 1}, "$unset": bson.M{"z": true, "c": true}}
+	want = UpdateDelta{Set: bson.M{"a.b": 1}, Unset: []string{"c", "z"}}
+	if got := ParseOplogUpdate(legacy); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseOplogUpdate($set) = %+v, want %+v", got, want)
+	}
+
+	if got := ParseOplogUpdate(bson.M{"_id": 1, "n": 2}); !got.Empty() {
+		t.Fatalf("a replacement should have no delta, got %+v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package cdc
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/topology"
+)
+
+// Backoff computes exponential reconnect delays with ±20% jitter so several
+// followers do not reconnect in lockstep.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+var DefaultBackoff = Backoff{Initial: 500 * time.Millisecond, Max: 30 * time.Second}
+
+// Delay returns the wait before reconnect attempt n (starting at 1).
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+	if b.Max <= 0 {
+		b.Max = DefaultBackoff.Max
+	}
+	d := b.Initial
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	d = min(d, b.Max)
+	jitter := time.Duration(rand.Int64N(int64(d)/5+1)) * 2
+	return d - d/5 + jitter
+}
+
+// IsResumable reports whether a change stream error is transient, so the
+// follower should reconnect from its last checkpoint: network errors,
+// timeouts, server selection failures and errors the server labels
+// ResumableChangeStreamError. Lost history is never resumable.
+func IsResumable(err error) bool {
+	if err == nil || IsHistoryLost(err) {
+		return false
+	}
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+	var selection topology.ServerSelectionError
+	if errors.As(err, &selection) {
+		return true
+	}
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) && labeled.HasErrorLabel("ResumableChangeStreamError")
+}
+
+// StreamStats describes a follower's health for status lines and the TUI.
+type StreamStats struct {
+	Reconnects  int
+	LastError   string
+	LastEventAt time.Time
+}
+
+// Lag is how far the last delivered event trails the wall clock.
+func (s StreamStats) Lag(now time.Time) time.Duration {
+	if s.LastEventAt.IsZero() {
+		return 0
+	}
+	return max(now.Sub(s.LastEventAt), 0)
+}
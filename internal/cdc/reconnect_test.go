@@ -0,0 +1,43 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestBackoffDelayGrowsAndCaps(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second}
+	want := map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second}
+	for attempt, base := range want {
+		d := b.Delay(attempt)
+		if d < base-base/5 || d > base+base/5 {
+			t.Fatalf("attempt %d: delay %s outside ±20%% of %s", attempt, d, base)
+		}
+	}
+}
+
+func TestIsResumable(t *testing.T) {
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"nil":          {nil, false},
+		"plain":        {errors.New("boom"), false},
+		"deadline":     {context.DeadlineExceeded, true},
+		"labeled":      {mongo.CommandError{Code: 43, Labels: []string{"ResumableChangeStreamError"}}, true},
+		"network":      {mongo.CommandError{Labels: []string{"NetworkError"}}, true},
+		"history lost": {mongo.CommandError{Code: 286, Labels: []string{"ResumableChangeStreamError"}}, false},
+	}
+	for name, tc := range cases {
+		if got := IsResumable(tc.err); got != tc.want {
+			t.Errorf("%s: IsResumable = %v, want %v", name, got, tc.want)
+		}
+	}
+	if !IsHistoryLost(mongo.CommandError{Code: 286, Name: "ChangeStreamHistoryLost"}) {
+		t.Fatal("expected code 286 to be history lost")
+	}
+}
@@ -0,0 +1,129 @@
+// Package cdc forwards change events from `mongo oplog --follow` to external
+// sinks. A sink acknowledges a batch by returning nil from Write; callers only
+// advance their resume token after that, so delivery is at-least-once.
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedSink = errors.New("unsupported sink (use file:<path>, http(s)://<url> or nats://<host>/<subject>)")
+	ErrSinkClosed      = errors.New("sink closed")
+)
+
+// Record is one encoded change event. Namespace is "db.collection" and Key the
+// document _id, both used for routing; Value is the JSON payload.
+type Record struct {
+	Namespace string
+	Key       string
+	Value     []byte
+}
+
+// Sink delivers batches of records. Write must only return nil once the batch
+// is durably handed off; on error the caller redelivers the same batch.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// Options tune batching, retries and file rotation. Zero values use defaults;
+// a negative Retries disables retrying.
+type Options struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	Retries       int
+	RetryBackoff  time.Duration
+	Timeout       time.Duration
+	RotateBytes   int64
+	RotateEvery   time.Duration
+}
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultRetries       = 5
+	defaultRetryBackoff  = 500 * time.Millisecond
+	defaultTimeout       = 10 * time.Second
+)
+
+// WithDefaults fills unset options.
+func (o Options) WithDefaults() Options {
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultBatchSize
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = defaultFlushInterval
+	}
+	if o.Retries == 0 {
+		o.Retries = defaultRetries
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = defaultRetryBackoff
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaultTimeout
+	}
+	return o
+}
+
+// Open builds a sink from a spec:
+//
+//	file:/var/log/cdc/events.ndjson   NDJSON file, rotated by RotateBytes/RotateEvery
+//	https://hooks.example.com/cdc     HTTP POST of a JSON array per batch
+//	nats://localhost:4222/cdc         NATS PUB to cdc.<db>.<collection>
+func Open(spec string, opts Options) (Sink, error) {
+	opts = opts.WithDefaults()
+	if path, ok := strings.CutPrefix(spec, "file:"); ok {
+		return NewFileSink(strings.TrimPrefix(path, "//"), opts)
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedSink, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return NewWebhookSink(spec, opts), nil
+	case "nats":
+		return NewNATSSink(u, opts), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSink, spec)
+	}
+}
+
+// permanentError marks a failure that retrying cannot fix, e.g. HTTP 400.
+type permanentError struct{ err error }
+
+func (p permanentError) Error() string { return p.err.Error() }
+func (p permanentError) Unwrap() error { return p.err }
+
+// retry runs fn until it succeeds, returns a permanent error, runs out of
+// attempts or ctx ends. The delay doubles after every failed attempt.
+func retry(ctx context.Context, opts Options, fn func(context.Context) error) error {
+	delay := opts.RetryBackoff
+	attempts := max(opts.Retries, 0) + 1
+	var err error
+	for attempt := range attempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w (last error: %w)", ctx.Err(), err)
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		err = fn(attemptCtx)
+		cancel()
+		var permanent permanentError
+		if err == nil || errors.As(err, &permanent) {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
@@ -0,0 +1,193 @@
+package cdc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRecords = []Record{
+	{Namespace: "app.users", Key: "1", Value: []byte(`{"operation":"insert","object_id":"1"}`)},
+	{Namespace: "app.orders", Key: "2", Value: []byte(`{"operation":"delete","object_id":"2"}`)},
+}
+
+func TestFileSinkRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.ndjson")
+	sink, err := NewFileSink(path, Options{RotateBytes: 10})
+	if err != nil {
+		t.Fatalf("NewFileSink returned error: %v", err)
+	}
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	sink.nowFunc = func() time.Time { return clock }
+
+	ctx := context.Background()
+	if err := sink.Write(ctx, testRecords[:1]); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	if err := sink.Write(ctx, testRecords[1:]); err != nil {
+		t.Fatalf("second write: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	rotated, err := os.ReadFile(filepath.Join(dir, "events-20260102T030405Z.ndjson"))
+	if err != nil {
+		t.Fatalf("expected rotated file: %v", err)
+	}
+	current, _ := os.ReadFile(path)
+	if string(rotated) != string(testRecords[0].Value)+"\n" || string(current) != string(testRecords[1].Value)+"\n" {
+		t.Fatalf("unexpected contents rotated=%q current=%q", rotated, current)
+	}
+	if err := sink.Write(ctx, testRecords); !errors.Is(err, ErrSinkClosed) {
+		t.Fatalf("expected ErrSinkClosed, got %v", err)
+	}
+}
+
+func TestFileSinkTruncatesFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink, err := NewFileSink(path, Options{})
+	if err != nil {
+		t.Fatalf("NewFileSink returned error: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Write(context.Background(), testRecords[:1]); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// Simulate the first half of a batch reaching disk before the error.
+	if _, err := sink.f.Write([]byte(`{"operation":"ins`)); err != nil {
+		t.Fatal(err)
+	}
+	cause := errors.New("disk full")
+	if err := sink.truncate(cause); !errors.Is(err, cause) {
+		t.Fatalf("expected the write error back, got %v", err)
+	}
+	if err := sink.Write(context.Background(), testRecords[1:]); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	want := string(testRecords[0].Value) + "\n" + string(testRecords[1].Value) + "\n"
+	if string(got) != want {
+		t.Fatalf("unexpected contents %q", got)
+	}
+}
+
+func TestWebhookSinkRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, Options{Retries: 3, RetryBackoff: time.Millisecond}.WithDefaults())
+	if err := sink.Write(context.Background(), testRecords); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls.Load())
+	}
+	var events []map[string]string
+	if err := json.Unmarshal(body, &events); err != nil || len(events) != 2 {
+		t.Fatalf("expected JSON array of 2 events, got %s (%v)", body, err)
+	}
+}
+
+func TestWebhookSinkClientErrorIsPermanent(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, Options{Retries: 3, RetryBackoff: time.Millisecond}.WithDefaults())
+	if err := sink.Write(context.Background(), testRecords); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected no retries, got %d attempts", calls.Load())
+	}
+}
+
+// fakeNATS accepts one connection, records PUBs and answers PING with PONG.
+func fakeNATS(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	pubs := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 3 && fields[0] == "PUB":
+				n, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, n+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				pubs <- fields[1] + " " + string(payload[:n])
+			case len(fields) == 1 && fields[0] == "PING":
+				conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+	return ln.Addr().String(), pubs
+}
+
+func TestNATSSinkPublishesPerNamespace(t *testing.T) {
+	addr, pubs := fakeNATS(t)
+	u, _ := url.Parse("nats://" + addr + "/cdc")
+	sink := NewNATSSink(u, Options{Retries: -1}.WithDefaults())
+	defer sink.Close()
+
+	if err := sink.Write(context.Background(), testRecords); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	var got []string
+	for range testRecords {
+		got = append(got, <-pubs)
+	}
+	if !strings.HasPrefix(got[0], "cdc.app.users {") || !strings.HasPrefix(got[1], "cdc.app.orders {") {
+		t.Fatalf("unexpected publishes %q", got)
+	}
+}
+
+func TestOpenRejectsUnknownScheme(t *testing.T) {
+	if _, err := Open("kafka://localhost:9092/events", Options{}); !errors.Is(err, ErrUnsupportedSink) {
+		t.Fatalf("expected ErrUnsupportedSink, got %v", err)
+	}
+}
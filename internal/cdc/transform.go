@@ -0,0 +1,299 @@
+package cdc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/parser"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidTransform = errors.New("invalid transform file")
+
+// DefaultMaskValue replaces masked fields when the file sets no mask_value.
+const DefaultMaskValue = "***"
+
+// TransformConfig is the YAML transform file:
+//
+//	mask_value: "[redacted]"
+//	rules:
+//	  - match: "*.*"                 # every namespace
+//	    drop: [password]
+//	  - match: app.users
+//	    mask: [email, card.number]
+//	    rename: {name: full_name}
+//	  - match: app.orders
+//	    project: [status, total, items]
+//	    route: analytics.{coll}
+//	  - match: "app.tmp_*"
+//	    skip: true
+//
+// Every rule whose match glob fits an event's namespace is applied, in file
+// order. Within a rule the steps run as skip, drop, mask, rename, project,
+// route. Paths are dotted; _id always survives a projection. Paths do not
+// descend into arrays: mask card.number does not reach items.card.number when
+// items is an array, so drop or mask the whole array instead.
+type TransformConfig struct {
+	MaskValue string          `yaml:"mask_value"`
+	Rules     []TransformRule `yaml:"rules"`
+}
+
+type TransformRule struct {
+	Match   string            `yaml:"match"`
+	Skip    bool              `yaml:"skip"`
+	Drop    []string          `yaml:"drop"`
+	Mask    []string          `yaml:"mask"`
+	Rename  map[string]string `yaml:"rename"`
+	Project []string          `yaml:"project"`
+	Route   string            `yaml:"route"`
+}
+
+// Transformer resolves the rules that apply to a namespace.
+type Transformer struct {
+	cfg TransformConfig
+}
+
+// LoadTransforms reads and validates a transform file.
+func LoadTransforms(file string) (*Transformer, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTransforms(data)
+}
+
+// ParseTransforms decodes a transform file. Unknown keys are rejected so a
+// misspelt "mask" does not silently let PII through.
+func ParseTransforms(data []byte) (*Transformer, error) {
+	var cfg TransformConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTransform, err)
+	}
+	if cfg.MaskValue == "" {
+		cfg.MaskValue = DefaultMaskValue
+	}
+	for i, r := range cfg.Rules {
+		if r.Match == "" {
+			cfg.Rules[i].Match = "*"
+		}
+		if _, err := path.Match(cfg.Rules[i].Match, ""); err != nil {
+			return nil, fmt.Errorf("%w: rule %d match %q: %w", ErrInvalidTransform, i+1, r.Match, err)
+		}
+		for from, to := range r.Rename {
+			if from == "" || to == "" {
+				return nil, fmt.Errorf("%w: rule %d renames %q to %q", ErrInvalidTransform, i+1, from, to)
+			}
+		}
+		if r.Route != "" && !strings.Contains(r.Route, ".") {
+			return nil, fmt.Errorf("%w: rule %d route %q is not db.collection", ErrInvalidTransform, i+1, r.Route)
+		}
+	}
+	return &Transformer{cfg: cfg}, nil
+}
+
+// Transform is the combined effect of the rules matching one namespace.
+type Transform struct {
+	// Skip drops the event entirely.
+	Skip bool
+	// Namespace is where the event is routed; the source namespace if no
+	// rule routes it.
+	Namespace string
+
+	rules     []TransformRule
+	maskValue string
+}
+
+// For returns the transform for events from ns; match globs use path.Match,
+// so "app.*" selects every collection in app.
+func (t *Transformer) For(ns string) Transform {
+	out := Transform{Namespace: ns, maskValue: t.cfg.MaskValue}
+	for _, r := range t.cfg.Rules {
+		if ok, _ := path.Match(r.Match, ns); !ok {
+			continue
+		}
+		if r.Skip {
+			out.Skip = true
+		}
+		if r.Route != "" {
+			db, coll, _ := strings.Cut(ns, ".")
+			out.Namespace = strings.NewReplacer("{db}", db, "{coll}", coll).Replace(r.Route)
+		}
+		out.rules = append(out.rules, r)
+	}
+	return out
+}
+
+// Cleaner returns the field steps as a parser.Cleaner. It copies the document
+// before changing it, and handles both nested documents and the flat dotted
+// keys of an update description.
+func (tr Transform) Cleaner() parser.Cleaner {
+	return func(doc map[string]any) map[string]any {
+		if doc == nil || len(tr.rules) == 0 {
+			return doc
+		}
+		doc = cloneMap(doc)
+		for _, r := range tr.rules {
+			for _, p := range r.Drop {
+				walkPath(doc, nil, p, func(parent map[string]any, key string, _ []string) {
+					delete(parent, key)
+				})
+			}
+			for _, p := range r.Mask {
+				walkPath(doc, nil, p, func(parent map[string]any, key string, _ []string) {
+					parent[key] = tr.maskValue
+				})
+			}
+			for from, to := range r.Rename {
+				walkPath(doc, nil, from, func(parent map[string]any, key string, at []string) {
+					v := parent[key]
+					delete(parent, key)
+					if len(at) == 0 && strings.Contains(key, ".") {
+						// A flat dotted key stays flat under its new name.
+						doc[to+strings.TrimPrefix(key, from)] = v
+						return
+					}
+					setNested(doc, strings.Split(to, "."), v)
+				})
+			}
+			if len(r.Project) > 0 {
+				kept := map[string]any{}
+				if id, ok := doc["_id"]; ok {
+					kept["_id"] = id
+				}
+				for _, p := range r.Project {
+					walkPath(doc, nil, p, func(parent map[string]any, key string, at []string) {
+						setNested(kept, append(append([]string(nil), at...), key), parent[key])
+					})
+				}
+				doc = kept
+			}
+		}
+		return doc
+	}
+}
+
+// Paths applies drop, rename and project to a list of field paths, such as
+// an update's removed fields. Masking leaves paths unchanged.
+func (tr Transform) Paths(paths []string) []string {
+	if len(tr.rules) == 0 {
+		return paths
+	}
+	out := make([]string, 0, len(paths))
+next:
+	for _, p := range paths {
+		for _, r := range tr.rules {
+			for _, d := range r.Drop {
+				if coversPath(d, p) {
+					continue next
+				}
+			}
+			for from, to := range r.Rename {
+				if coversPath(from, p) {
+					p = to + strings.TrimPrefix(p, from)
+				}
+			}
+			if len(r.Project) > 0 {
+				kept := p == "_id"
+				for _, keep := range r.Project {
+					kept = kept || coversPath(keep, p) || coversPath(p, keep)
+				}
+				if !kept {
+					continue next
+				}
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// coversPath reports whether p is path itself or lies under it.
+func coversPath(path, p string) bool {
+	return p == path || strings.HasPrefix(p, path+".")
+}
+
+// walkPath calls fn for every key in doc that target addresses: a key equal
+// to the remaining path, a dotted key under it, or a nested document on the
+// way to it. at holds the keys walked through to reach parent.
+func walkPath(doc map[string]any, at []string, target string, fn func(map[string]any, string, []string)) {
+	for _, key := range sortedMapKeys(doc) {
+		switch {
+		case coversPath(target, key):
+			fn(doc, key, at)
+		case strings.HasPrefix(target, key+"."):
+			if child, ok := asMap(doc[key]); ok {
+				doc[key] = child
+				walkPath(child, append(at, key), strings.TrimPrefix(target, key+"."), fn)
+			}
+		}
+	}
+}
+
+func setNested(doc map[string]any, keys []string, v any) {
+	for _, k := range keys[:len(keys)-1] {
+		child, ok := asMap(doc[k])
+		if !ok {
+			child = map[string]any{}
+		}
+		doc[k] = child
+		doc = child
+	}
+	doc[keys[len(keys)-1]] = v
+}
+
+// asMap views a nested document as a map; bson.D is converted.
+func asMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case bson.M:
+		return map[string]any(m), true
+	case bson.D:
+		out := make(map[string]any, len(m))
+		for _, e := range m {
+			out[e.Key] = e.Value
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+func cloneMap(doc map[string]any) map[string]any {
+	out := make(map[string]any, len(doc))
+	for k, v := range doc {
+		out[k] = cloneTransformValue(v)
+	}
+	return out
+}
+
+func cloneTransformValue(v any) any {
+	if m, ok := asMap(v); ok {
+		return cloneMap(m)
+	}
+	if arr, ok := v.(bson.A); ok {
+		out := make(bson.A, len(arr))
+		for i := range arr {
+			out[i] = cloneTransformValue(arr[i])
+		}
+		return out
+	}
+	return v
+}
+
+func sortedMapKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+package cdc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const transformFixture = `
+mask_value: "#"
+rules:
+  - match: "*"
+    drop: [password]
+  - match: app.users
+    mask: [email, card.number]
+    rename: {name: full_name, "address.zip": postcode}
+  - match: app.orders
+    project: [status, customer.id]
+    route: analytics.{coll}
+  - match: "app.tmp_*"
+    skip: true
+`
+
+func TestTransformCleansNestedDocuments(t *testing.T) {
+	tf, err := ParseTransforms([]byte(transformFixture))
+	if err != nil {
+		t.Fatalf("ParseTransforms: %v", err)
+	}
+
+	users := tf.For("app.users")
+	if users.Skip || users.Namespace != "app.users" {
+		t.Fatalf("unexpected users transform: %+v", users)
+	}
+	in := map[string]any{
+		"_id":      1,
+		"name":     "Ada",
+		"email":    "ada@example.com",
+		"password": "secret",
+		"card":     bson.D{{Key: "number", Value: "4111"}, {Key: "exp", Value: "12/30"}},
+		"address":  bson.M{"zip": "10115", "city": "Berlin"},
+	}
+	got := users.Cleaner()(in)
+	want := map[string]any{
+		"_id":       1,
+		"full_name": "Ada",
+		"email":     "#",
+		"card":      map[string]any{"number": "#", "exp": "12/30"},
+		"address":   map[string]any{"city": "Berlin"},
+		"postcode":  "10115",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("cleaned = %#v\nwant %#v", got, want)
+	}
+	if in["password"] != "secret" {
+		t.Fatal("the cleaner must not modify its input")
+	}
+
+	orders := tf.For("app.orders")
+	if orders.Namespace != "analytics.orders" {
+		t.Fatalf("expected route to analytics.orders, got %s", orders.Namespace)
+	}
+	got = orders.Cleaner()(map[string]any{
+		"_id": 7, "status": "paid", "total": 10, "customer": bson.M{"id": 3, "email": "x"},
+	})
+	want = map[string]any{"_id": 7, "status": "paid", "customer": map[string]any{"id": 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("projected = %#v\nwant %#v", got, want)
+	}
+
+	if !tf.For("app.tmp_import").Skip {
+		t.Fatal("expected app.tmp_* to be skipped")
+	}
+}
+
+func TestTransformHandlesFlatUpdatePaths(t *testing.T) {
+	tf, err := ParseTransforms([]byte(transformFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := tf.For("app.users")
+	got := users.Cleaner()(map[string]any{"card.number": "4111", "address.zip": "10115", "password": "x", "age": 3})
+	want := map[string]any{"card.number": "#", "postcode": "10115", "age": 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("cleaned = %#v, want %#v", got, want)
+	}
+
+	paths := tf.For("app.orders").Paths([]string{"password", "status", "total", "customer", "customer.id.x"})
+	if !reflect.DeepEqual(paths, []string{"status", "customer", "customer.id.x"}) {
+		t.Fatalf("paths = %v", paths)
+	}
+}
+
+func TestParseTransformsRejectsMistakes(t *testing.T) {
+	for name, doc := range map[string]string{
+		"unknown key": "rules:\n  - match: app.users\n    masks: [email]\n",
+		"bad glob":    "rules:\n  - match: \"app.[\"\n",
+		"bad route":   "rules:\n  - route: nowhere\n",
+		"empty name":  "rules:\n  - rename: {name: \"\"}\n",
+	} {
+		if _, err := ParseTransforms([]byte(doc)); !errors.Is(err, ErrInvalidTransform) {
+			t.Errorf("%s: expected ErrInvalidTransform, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookSink POSTs each batch as a JSON array. 2xx acknowledges the batch;
+// 408, 429, 5xx and network errors are retried with exponential backoff,
+// other statuses fail immediately.
+type WebhookSink struct {
+	url    string
+	opts   Options
+	client *http.Client
+}
+
+func NewWebhookSink(url string, opts Options) *WebhookSink {
+	return &WebhookSink{url: url, opts: opts, client: &http.Client{}}
+}
+
+func (s *WebhookSink) Write(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	body := encodeJSONArray(records)
+	return retry(ctx, s.opts, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+		if err != nil {
+			return permanentError{err}
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "mongork-cdc")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests,
+			resp.StatusCode >= 500:
+			return fmt.Errorf("webhook %s: %s", s.url, resp.Status)
+		default:
+			return permanentError{fmt.Errorf("webhook %s: %s", s.url, resp.Status)}
+		}
+	})
+}
+
+func (s *WebhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func encodeJSONArray(records []Record) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, r := range records {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(r.Value)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
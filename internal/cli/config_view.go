@@ -18,6 +18,10 @@ type safeConfig struct {
 	Timeout              string               `json:"timeout"`
 	MigrationsPath       string               `json:"migrations_path"`
 	MigrationsCollection string               `json:"migrations_collection"`
+	SchemaPath           string               `json:"schema_path"`
+	SchemaSnapshot       safeSnapshotConfig   `json:"schema_snapshot"`
+	SchemaIgnore         []string             `json:"schema_ignore,omitempty"`
+	SchemaImportPrompt   bool                 `json:"schema_import_prompt"`
 }
 
 type safeMongoConfig struct {
@@ -32,6 +36,12 @@ type safeMongoConfig struct {
 	MinPoolSize int    `json:"min_pool_size"`
 }
 
+type safeSnapshotConfig struct {
+	Store      string `json:"store"`
+	Path       string `json:"path"`
+	Collection string `json:"collection"`
+}
+
 type safeGoogleDocsConfig struct {
 	Enabled         bool   `json:"enabled"`
 	CredentialsPath string `json:"credentials_path"`
@@ -47,6 +57,14 @@ func renderConfig(out io.Writer, cfg *config.Config) error {
 		Timeout:              cfg.Timeout.String(),
 		MigrationsPath:       cfg.MigrationsPath,
 		MigrationsCollection: cfg.MigrationsCollection,
+		SchemaPath:           cfg.SchemaPath,
+		SchemaIgnore:         cfg.SchemaIgnore,
+		SchemaImportPrompt:   cfg.SchemaImportPrompt,
+		SchemaSnapshot: safeSnapshotConfig{
+			Store:      cfg.SchemaSnapshot.Store,
+			Path:       cfg.SchemaSnapshot.Path,
+			Collection: cfg.SchemaSnapshot.Collection,
+		},
 		Mongo: safeMongoConfig{
 			URL:         cfg.Mongo.URL,
 			Database:    cfg.Mongo.Database,
@@ -25,6 +25,8 @@ func NewDBCmd() *cobra.Command {
 		newDBHealthCmd(),
 		newDBCollectionsCmd(),
 		newDBIndexesCmd(),
+		newDBIndexUsageCmd(),
+		newDBSuggestIndexesCmd(),
 		newDBStatsCmd(),
 		newDBCurrentOpsCmd(),
 		newDBUsersCmd(),
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/drewjocham/mongork/internal/jsonutil"
 	"github.com/drewjocham/mongork/internal/observability"
+	"github.com/dustin/go-humanize"
 	"github.com/spf13/cobra"
 )
 
@@ -26,9 +28,13 @@ func newDBCollectionsCmd() *cobra.Command {
 			}
 			return renderJSONOrTable(cmd, output, rows, func() {
 				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
-				fmt.Fprintln(w, "NAME\tTYPE")
+				fmt.Fprintln(w, "NAME\tTYPE\tVIEW ON")
 				for _, r := range rows {
-					fmt.Fprintf(w, "%s\t%s\n", r.Name, r.Type)
+					viewOn := r.ViewOn
+					if viewOn == "" {
+						viewOn = "-"
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Type, viewOn)
 				}
 				_ = w.Flush()
 			})
@@ -68,6 +74,61 @@ func newDBIndexesCmd() *cobra.Command {
 	return cmd
 }
 
+func newDBIndexUsageCmd() *cobra.Command {
+	var output string
+	var collection string
+	cmd := &cobra.Command{
+		Use:   "index-usage",
+		Short: "Report unused and prefix-redundant indexes with reclaimable space",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil {
+				return err
+			}
+			report, err := observability.AnalyzeIndexUsage(
+				cmd.Context(),
+				s.MongoClient.Database(s.Config.Mongo.Database),
+				collection,
+			)
+			if err != nil {
+				return err
+			}
+			return renderJSONOrTable(cmd, output, report, func() {
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+				fmt.Fprintln(w, "COLLECTION\tINDEX\tKEYS\tOPS\tSIZE\tFINDING")
+				for _, r := range report.Indexes {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+						r.Collection, r.Name, r.Keys, r.Ops, humanize.Bytes(uint64(r.SizeBytes)), indexUsageFinding(r))
+				}
+				_ = w.Flush()
+				fmt.Fprintf(cmd.OutOrStdout(), "\n%d unused, %d redundant, %s reclaimable\n",
+					report.Unused, report.Redundant, humanize.Bytes(uint64(report.ReclaimableBytes)))
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringVar(&collection, "collection", "", "Collection name filter")
+	return cmd
+}
+
+func indexUsageFinding(u observability.IndexUsage) string {
+	var findings []string
+	if u.Unused {
+		if u.Since != nil {
+			findings = append(findings, "unused since "+u.Since.Format(time.RFC3339))
+		} else {
+			findings = append(findings, "unused")
+		}
+	}
+	if u.RedundantWith != "" {
+		findings = append(findings, "prefix of "+u.RedundantWith)
+	}
+	if len(findings) == 0 {
+		return "-"
+	}
+	return strings.Join(findings, "; ")
+}
+
 func newDBStatsCmd() *cobra.Command {
 	var output string
 	var collection string
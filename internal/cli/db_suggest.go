@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/drewjocham/mongork/internal/migration"
+	"github.com/drewjocham/mongork/internal/observability"
+	"github.com/drewjocham/mongork/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+func newDBSuggestIndexesCmd() *cobra.Command {
+	var (
+		output         string
+		opts           observability.SuggestOptions
+		writeMigration string
+	)
+	cmd := &cobra.Command{
+		Use:   "suggest-indexes",
+		Short: "Propose indexes for collection scans and inefficient query shapes",
+		Long: "Reads system.profile (enable it with db.setProfilingLevel) or a $currentOp sample, " +
+			"groups queries by shape and proposes equality-sort-range indexes.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil {
+				return err
+			}
+			suggestions, err := observability.SuggestIndexes(
+				cmd.Context(),
+				s.MongoClient.Database(s.Config.Mongo.Database),
+				opts,
+			)
+			if err != nil {
+				return err
+			}
+			if writeMigration != "" && len(suggestions) > 0 {
+				gen := &migration.Generator{OutputPath: s.Config.MigrationsPath}
+				indexes := make([]schema.IndexSpec, 0, len(suggestions))
+				for _, sg := range suggestions {
+					indexes = append(indexes, sg.Index)
+				}
+				path, version, err := gen.CreateWithBody(writeMigration, renderCreateIndexes(indexes), renderDropIndexes(indexes))
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Wrote migration %s (%s)\n", path, version)
+			}
+			return renderJSONOrTable(cmd, output, suggestions, func() {
+				if len(suggestions) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "No index suggestions: no sampled query shapes scan or over-examine documents.")
+					return
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+				fmt.Fprintln(w, "COLLECTION\tSHAPE\tCOUNT\tCOLLSCANS\tRATIO\tMAX MS\tINDEX")
+				for _, r := range suggestions {
+					fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.1f\t%d\t%s\n",
+						r.Collection, r.Shape, r.Count, r.CollScans, r.Ratio, r.MaxMillis, r.Index.Name)
+				}
+				_ = w.Flush()
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringVar(&opts.Collection, "collection", "", "Collection name filter")
+	cmd.Flags().StringVar(&opts.Source, "source", observability.SuggestSourceProfile,
+		"Query sample source (profile, current-op)")
+	cmd.Flags().IntVar(&opts.Sample, "sample", 1000, "Maximum operations to sample")
+	cmd.Flags().Float64Var(&opts.MinRatio, "min-ratio", 10, "Suggest when docsExamined/nReturned reaches this ratio")
+	cmd.Flags().StringVar(&writeMigration, "write-migration", "",
+		"Write the suggested indexes as a migration with this name")
+	return cmd
+}
+
+func renderCreateIndexes(indexes []schema.IndexSpec) string {
+	var b strings.Builder
+	for _, coll := range indexCollections(indexes) {
+		fmt.Fprintf(&b, "\tif err := migration.CreateIndexesWithOptions(ctx, db.Collection(%q), nil,\n", coll)
+		for _, idx := range indexes {
+			if idx.Collection != coll {
+				continue
+			}
+			b.WriteString("\t\tmigration.Index()")
+			for _, k := range idx.Keys {
+				fmt.Fprintf(&b, ".Key(%q, %v)", k.Key, renderGoValue(k.Value))
+			}
+			fmt.Fprintf(&b, ".Name(%q),\n", idx.Name)
+		}
+		b.WriteString("\t); err != nil {\n\t\treturn err\n\t}\n")
+	}
+	return b.String()
+}
+
+func renderDropIndexes(indexes []schema.IndexSpec) string {
+	var b strings.Builder
+	for _, coll := range indexCollections(indexes) {
+		var names []string
+		for _, idx := range indexes {
+			if idx.Collection == coll {
+				names = append(names, fmt.Sprintf("%q", idx.Name))
+			}
+		}
+		fmt.Fprintf(&b, "\tif err := migration.DropIndexes(ctx, db.Collection(%q), %s); err != nil {\n",
+			coll, strings.Join(names, ", "))
+		b.WriteString("\t\treturn err\n\t}\n")
+	}
+	return b.String()
+}
+
+func indexCollections(indexes []schema.IndexSpec) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, idx := range indexes {
+		if !seen[idx.Collection] {
+			seen[idx.Collection] = true
+			out = append(out, idx.Collection)
+		}
+	}
+	return out
+}
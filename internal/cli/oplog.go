@@ -5,16 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/drewjocham/mongork/internal/cdc"
 	"github.com/drewjocham/mongork/internal/jsonutil"
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
 )
 
 var (
@@ -24,10 +26,10 @@ var (
 	ErrFailedToQueryOplog     = errors.New("failed to query oplog")
 	ErrStreamFailed           = errors.New("stream failed")
 	ErrInvalidTimeFormat      = errors.New("invalid time")
-	ErrOplogCollection        = errors.New("oplog collection not found (requires replica set)")
-	ErrFailedToList           = errors.New("failed to list local collections")
 	ErrFollowAndTo            = errors.New("--to is not supported with --follow")
 	ErrNamespaceOrRegex       = errors.New("use --namespace or --regex, not both")
+	ErrSinkDelivery           = errors.New("sink delivery failed")
+	ErrResumePointLost        = errors.New("resume point no longer in the oplog (remove the checkpoint to start from now)")
 )
 
 var operations = struct {
@@ -49,7 +51,22 @@ type oplogConfig struct {
 	limit      int64
 	follow     bool
 	fullDoc    bool
+	where      []string
+	beforeDoc  string
+	expanded   bool
 	resumeFile string
+	sink       oplogSinkConfig
+
+	fromArchive string
+	transform   string
+
+	resumeStore     string
+	checkpointEvery time.Duration
+	database        string
+
+	maxReconnects       int
+	reconnectBackoff    time.Duration
+	reconnectMaxBackoff time.Duration
 }
 
 type oplogEntry struct {
@@ -59,14 +76,50 @@ type oplogEntry struct {
 	Wall *time.Time     `bson:"wall,omitempty"`
 	O    bson.M         `bson:"o"`
 	O2   bson.M         `bson:"o2,omitempty"`
+
+	TxnNumber *int64 `bson:"txnNumber,omitempty"`
+	LSID      bson.M `bson:"lsid,omitempty"`
+
+	// Set from change stream events only; oplog queries derive them from O.
+	Type   string       `bson:"-"`
+	Update *updateDelta `bson:"-"`
+	Before bson.M       `bson:"-"`
+	DDL    bson.M       `bson:"-"`
 }
 
+// oplogOutput is the event document printed by -o json and delivered to
+// sinks. The bson tags give the file sink's Extended JSON the same field names.
 type oplogOutput struct {
-	Timestamp time.Time `json:"timestamp"`
-	Operation string    `json:"operation"`
-	Namespace string    `json:"namespace"`
-	ObjectID  string    `json:"object_id,omitempty"`
-	Data      bson.M    `json:"data,omitempty"`
+	Timestamp   time.Time     `json:"timestamp" bson:"timestamp"`
+	Operation   string        `json:"operation" bson:"operation"`
+	Namespace   string        `json:"namespace" bson:"namespace"`
+	ObjectID    string        `json:"object_id,omitempty" bson:"object_id,omitempty"`
+	DocumentKey bson.M        `json:"document_key,omitempty" bson:"document_key,omitempty"`
+	Data        bson.M        `json:"data,omitempty" bson:"data,omitempty"`
+	Update      *updateDelta  `json:"update,omitempty" bson:"update,omitempty"`
+	Before      bson.M        `json:"before,omitempty" bson:"before,omitempty"`
+	DDL         bson.M        `json:"ddl,omitempty" bson:"ddl,omitempty"`
+	Txn         *txnInfo      `json:"txn,omitempty" bson:"txn,omitempty"`
+	Stream      *streamHealth `json:"stream,omitempty" bson:"stream,omitempty"`
+}
+
+// streamHealth is attached to followed events so consumers can see how far
+// behind the tail is and how often it had to reconnect.
+type streamHealth struct {
+	Reconnects int   `json:"reconnects" bson:"reconnects"`
+	LagMillis  int64 `json:"lag_ms" bson:"lag_ms"`
+}
+
+// documentKey is o2 for updates and change events (documentKey), else the
+// _id of the inserted or deleted document.
+func (e *oplogEntry) documentKey() bson.M {
+	if _, ok := e.O2["_id"]; ok {
+		return e.O2
+	}
+	if id, ok := e.O["_id"]; ok {
+		return bson.M{"_id": id}
+	}
+	return nil
 }
 
 // Transform raw BSON entry to formatted output
@@ -84,30 +137,47 @@ func (e *oplogEntry) ToOutput() oplogOutput {
 		}
 	}
 
-	opName, ok := operations.codes[e.Op]
-	if !ok {
-		opName = e.Op
+	out := oplogOutput{
+		Timestamp:   ts,
+		Operation:   e.eventType(),
+		Namespace:   e.NS,
+		ObjectID:    id,
+		DocumentKey: e.documentKey(),
+		Data:        e.O,
+		Update:      e.Update,
+		Before:      e.Before,
+		DDL:         e.DDL,
+	}
+	if e.Type == "" {
+		switch e.Op {
+		case "u":
+			out.Update = updateDeltaFromOplog(e.O)
+		case "c":
+			out.DDL = e.O
+		}
 	}
-
-	return oplogOutput{
-		Timestamp: ts,
-		Operation: opName,
-		Namespace: e.NS,
-		ObjectID:  id,
-		Data:      e.O,
+	if e.TxnNumber != nil {
+		out.Txn = &txnInfo{Number: *e.TxnNumber, Session: sessionID(e.LSID)}
 	}
+	return out
 }
 
 func NewOplogCmd() *cobra.Command {
 	cfg := oplogConfig{}
 	cmd := &cobra.Command{
-		Use:   "oplog",
-		Short: "Query MongoDB oplog entries",
+		Use:         "oplog",
+		Short:       "Query MongoDB oplog entries",
+		Annotations: map[string]string{annotationOfflineFlags: "from-archive"},
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			if cfg.fromArchive != "" {
+				// Archives are read offline; no connection is needed.
+				return runOplog(cmd.Context(), cmd.OutOrStdout(), nil, cfg)
+			}
 			s, err := getServices(cmd.Context())
 			if err != nil || s.MongoClient == nil {
 				return ErrMongoClientUnavailable
 			}
+			cfg.database = s.Config.Mongo.Database
 			return runOplog(cmd.Context(), cmd.OutOrStdout(), s.MongoClient, cfg)
 		},
 	}
@@ -118,28 +188,74 @@ func NewOplogCmd() *cobra.Command {
 	f.StringVar(&cfg.regex, "regex", "", "Filter by namespace regex")
 	f.StringVar(&cfg.ops, "ops", "", "Filter by op codes/names (i,u,d or insert,update)")
 	f.StringVar(&cfg.objectID, "object-id", "", "Filter by _id")
-	f.StringVar(&cfg.from, "from", "", "Start time (RFC3339 or YYYY-MM-DD)")
+	f.StringVar(&cfg.from, "from", "", "Start time (RFC3339 or YYYY-MM-DD); with --follow, where the stream starts")
 	f.StringVar(&cfg.to, "to", "", "End time (RFC3339 or YYYY-MM-DD)")
-	f.Int64Var(&cfg.limit, "limit", 50, "Limit results")
+	f.StringArrayVar(&cfg.where, "where", nil,
+		"Field predicate on the document, e.g. 'o.status=paid' or 'total>=100' (repeatable, ANDed)")
+	f.Int64Var(&cfg.limit, "limit", 50, "Limit results to this many matching operations")
 	f.BoolVar(&cfg.follow, "follow", false, "Tail entries in real-time")
 	f.BoolVar(&cfg.fullDoc, "full-document", false, "Include full document on updates")
+	f.StringVar(&cfg.beforeDoc, "full-document-before-change", "",
+		"Include pre-images: whenAvailable or required (needs changeStreamPreAndPostImages on the collection)")
+	f.Lookup("full-document-before-change").NoOptDefVal = string(options.WhenAvailable)
+	f.BoolVar(&cfg.expanded, "expanded-events", false,
+		"Stream DDL events such as create, createIndexes and modify (MongoDB 6.0+)")
+	f.StringVar(&cfg.transform, "transform", "",
+		"YAML file of per-namespace drops, masks, renames, projections and routes applied before output or sink")
+	f.StringVar(&cfg.fromArchive, "from-archive", "",
+		"Query a directory written by 'mongo oplog archive' instead of the live oplog")
 	f.StringVar(&cfg.resumeFile, "resume-file", "", "File to store/read the resume token for persistent tailing")
+	f.StringVar(&cfg.resumeStore, "resume-store", "",
+		"Checkpoint store: file:<path>, mongo:<collection>[/<name>] or memory (overrides --resume-file)")
+	f.DurationVar(&cfg.checkpointEvery, "checkpoint-interval", 0,
+		"Save the checkpoint at most this often (0 saves after every delivered batch)")
+	f.IntVar(&cfg.maxReconnects, "max-reconnects", 0, "Give up after this many consecutive reconnects (0 retries forever)")
+	f.DurationVar(&cfg.reconnectBackoff, "reconnect-backoff", cdc.DefaultBackoff.Initial, "Initial reconnect delay")
+	f.DurationVar(&cfg.reconnectMaxBackoff, "reconnect-max-backoff", cdc.DefaultBackoff.Max, "Maximum reconnect delay")
+	addSinkFlags(f, &cfg.sink)
+
+	cmd.AddCommand(newOplogReplayCmd(), newOplogHistoryCmd(), newOplogStatsCmd(), newOplogArchiveCmd())
 	return cmd
 }
 
 func runOplog(ctx context.Context, w io.Writer, client *mongo.Client, cfg oplogConfig) error {
-	if cfg.namespace != "" && cfg.regex != "" {
-		return ErrNamespaceOrRegex
-	}
 	if cfg.follow && cfg.to != "" {
 		return ErrFollowAndTo
 	}
+	if cfg.follow && cfg.fromArchive != "" {
+		return ErrFollowArchive
+	}
+	filter, err := newOplogFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	var transforms *cdc.Transformer
+	if cfg.transform != "" {
+		if transforms, err = cdc.LoadTransforms(cfg.transform); err != nil {
+			return err
+		}
+	}
 
+	stats := &cdc.StreamStats{}
+	output := func(e oplogEntry) oplogOutput {
+		o := e.ToOutput()
+		if transforms != nil {
+			o = transformOutput(transforms.For(e.NS), o)
+		}
+		if cfg.follow {
+			o.Stream = &streamHealth{
+				Reconnects: stats.Reconnects,
+				LagMillis:  time.Since(o.Timestamp).Milliseconds(),
+			}
+		}
+		return o
+	}
 	render := func(entries []oplogEntry) error {
 		if strings.ToLower(cfg.output) == "json" {
 			out := make([]oplogOutput, len(entries))
 			for i, e := range entries {
-				out[i] = e.ToOutput()
+				out[i] = output(e)
 			}
 			enc := jsonutil.NewEncoder(w)
 			enc.SetIndent("", "  ")
@@ -147,219 +263,389 @@ func runOplog(ctx context.Context, w io.Writer, client *mongo.Client, cfg oplogC
 		}
 
 		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
-		if len(entries) > 0 {
-			fmt.Fprintln(tw, "TIME\tOPERATION\tNS\tOBJECT ID")
+		if len(entries) > 0 && cfg.follow {
+			fmt.Fprintln(tw, "TIME\tOPERATION\tNS\tOBJECT ID\tDETAIL\tLAG\tRECONNECTS")
+		} else if len(entries) > 0 {
+			fmt.Fprintln(tw, "TIME\tOPERATION\tNS\tOBJECT ID\tDETAIL")
 		}
 		for _, e := range entries {
-			o := e.ToOutput()
-			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			o := output(e)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s",
 				o.Timestamp.Format("2006-01-02 15:04:05"),
 				o.Operation,
 				o.Namespace,
 				o.ObjectID,
+				o.detail(),
 			)
+			if o.Stream != nil {
+				lag := time.Duration(o.Stream.LagMillis) * time.Millisecond
+				fmt.Fprintf(tw, "\t%s\t%d", lag.Truncate(time.Millisecond), o.Stream.Reconnects)
+			}
+			fmt.Fprintln(tw)
 		}
 		return tw.Flush()
 	}
 
-	if cfg.follow {
-		return streamOplog(ctx, client, cfg, render)
-	}
-
-	filter, err := buildFilter(cfg)
-	if err != nil {
-		return err
+	batch, flushEvery := 1, time.Duration(0)
+	if cfg.sink.spec != "" {
+		sink, err := cdc.Open(cfg.sink.spec, cfg.sink.opts)
+		if err != nil {
+			return err
+		}
+		defer sink.Close()
+		render = sinkDelivery(ctx, sink, sinkEncoder(cfg.sink.spec), output)
+		opts := cfg.sink.opts.WithDefaults()
+		batch, flushEvery = opts.BatchSize, opts.FlushInterval
 	}
 
-	entries, err := fetchOplog(ctx, client, filter, cfg.limit)
-	if err != nil {
-		return err
+	if transforms != nil {
+		render = dropSkipped(transforms, render)
 	}
-	return render(entries)
-}
 
-func buildFilter(cfg oplogConfig) (bson.D, error) {
-	filter := bson.D{}
-	add := func(k string, v interface{}) { filter = append(filter, bson.E{Key: k, Value: v}) }
-
-	if cfg.namespace != "" {
-		add("ns", cfg.namespace)
-	}
-	if cfg.regex != "" {
-		add("ns", bson.Regex{Pattern: cfg.regex})
+	if cfg.follow {
+		return streamOplog(ctx, client, cfg, batch, flushEvery, render, stats)
 	}
 
-	if cfg.ops != "" {
-		codes, err := parseOps(cfg.ops)
+	if cfg.fromArchive != "" {
+		entries, err := readArchivedOplog(cfg.fromArchive, filter, cfg.limit)
 		if err != nil {
-			return nil, err
-		}
-		if len(codes) > 0 {
-			add("op", bson.M{"$in": codes})
-		}
-	}
-
-	if cfg.objectID != "" {
-		var id interface{} = cfg.objectID
-		if oid, err := bson.ObjectIDFromHex(cfg.objectID); err == nil {
-			id = oid
+			return err
 		}
-		add("$or", bson.A{bson.M{"o._id": id}, bson.M{"o2._id": id}})
+		return render(entries)
 	}
 
-	// Time range processing
-	tsFilter := bson.M{}
-	for _, spec := range []struct {
-		val string
-		op  string
-	}{{cfg.from, "$gte"}, {cfg.to, "$lte"}} {
-		if spec.val != "" {
-			t, err := parseTime(spec.val)
-			if err != nil {
-				return nil, err
-			}
-			tsFilter[spec.op] = t
-		}
-	}
-	if len(tsFilter) > 0 {
-		add("ts", tsFilter)
+	entries, err := fetchOplog(ctx, client, filter, cfg.limit)
+	if err != nil {
+		return err
 	}
-
-	return filter, nil
+	return render(entries)
 }
 
-func fetchOplog(ctx context.Context, client *mongo.Client, filter bson.D, limit int64) ([]oplogEntry, error) {
-	coll, err := oplogCollection(client)
+// fetchOplog returns the newest limit operations matching filter, newest
+// first, with transactions expanded. The limit counts matching operations,
+// so it is applied here rather than on the server.
+func fetchOplog(ctx context.Context, client *mongo.Client, filter oplogFilter, limit int64) ([]oplogEntry, error) {
+	coll, err := cdc.OplogCollection(ctx, client)
 	if err != nil {
 		return nil, err
 	}
 
-	findOpts := options.Find().SetSort(bson.D{{Key: "ts", Value: -1}})
-	if limit > 0 {
-		findOpts.SetLimit(limit)
-	}
-
-	cur, err := coll.Find(ctx, filter, findOpts)
+	cur, err := coll.Find(ctx, filter.queryFilter(), options.Find().SetSort(bson.D{{Key: "ts", Value: -1}}))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrFailedToQueryOplog, err)
 	}
 	defer cur.Close(ctx)
 
 	var entries []oplogEntry
-	return entries, cur.All(ctx, &entries)
+	for cur.Next(ctx) && (limit <= 0 || int64(len(entries)) < limit) {
+		var e oplogEntry
+		if err := cur.Decode(&e); err != nil {
+			return nil, err
+		}
+		entries = filter.appendMatching(entries, e)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(entries)) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
 }
 
-func streamOplog(ctx context.Context, client *mongo.Client, cfg oplogConfig, render func([]oplogEntry) error) error {
-	pipeline := mongo.Pipeline{}
-
-	match := bson.M{}
-	if cfg.regex != "" {
-		match["$or"] = bson.A{
-			bson.M{"ns.db": bson.M{"$regex": cfg.regex}},
-			bson.M{"ns.coll": bson.M{"$regex": cfg.regex}},
-		}
+// streamOplog tails the change stream and hands events to deliver in batches
+// of up to batch entries, flushing early when the stream goes idle or the
+// oldest pending event is flushEvery old. The checkpoint only advances after
+// deliver returns nil, so a failed delivery is replayed on restart. Resumable
+// errors reconnect from the last checkpoint with exponential backoff.
+func streamOplog(
+	ctx context.Context, client *mongo.Client, cfg oplogConfig,
+	batch int, flushEvery time.Duration, deliver func([]oplogEntry) error, stats *cdc.StreamStats,
+) error {
+	filter, err := newOplogFilter(cfg)
+	if err != nil {
+		return err
 	}
-	if cfg.objectID != "" {
-		var id interface{} = cfg.objectID
-		if oid, err := bson.ObjectIDFromHex(cfg.objectID); err == nil {
-			id = oid
-		}
-		match["documentKey._id"] = id
+	store, err := openTokenStore(client, cfg)
+	if err != nil {
+		return err
 	}
-	if cfg.ops != "" {
-		codes, err := parseOps(cfg.ops)
-		if err != nil {
-			return err
-		}
-		names := mapOpsToNames(codes)
-		if len(names) > 0 {
-			match["operationType"] = bson.M{"$in": names}
-		}
+	if ms, ok := store.(*cdc.MongoTokenStore); ok {
+		filter.Exclude = append(filter.Exclude, ms.Namespace())
 	}
-	if len(match) > 0 {
+	pipeline := mongo.Pipeline{}
+	if match := filter.streamMatch(); len(match) > 0 {
 		pipeline = append(pipeline, bson.D{{Key: "$match", Value: match}})
 	}
 
-	opts := options.ChangeStream()
-	if cfg.fullDoc {
-		opts.SetFullDocument(options.UpdateLookup)
-	}
-	if cfg.resumeFile != "" {
-		if token, err := os.ReadFile(cfg.resumeFile); err == nil && len(token) > 0 {
-			opts.SetResumeAfter(bson.Raw(token))
-		}
+	checkpoint := cdc.NewCheckpointer(store, cfg.checkpointEvery)
+	// Shutdown must not lose the last acknowledged position to the interval.
+	defer checkpoint.Flush(context.WithoutCancel(ctx), true) //nolint:errcheck
+
+	// A stored checkpoint wins over --from so restarting the same command
+	// continues where it stopped instead of replaying from --from.
+	var resumeFrom *cdc.Checkpoint
+	cp, err := store.Load(ctx)
+	switch {
+	case err == nil:
+		resumeFrom = &cp
+	case !errors.Is(err, cdc.ErrNoCheckpoint):
+		return err
+	case !filter.From.IsZero():
+		resumeFrom = &cdc.Checkpoint{ClusterTime: filter.From}
 	}
 
 	// watch the whole cluster or specific DB based on namespace
-	var stream *mongo.ChangeStream
-	var err error
+	watch := func(opts *options.ChangeStreamOptionsBuilder) (*mongo.ChangeStream, error) {
+		return client.Watch(ctx, pipeline, opts)
+	}
 	if cfg.namespace != "" {
 		parts := strings.SplitN(cfg.namespace, ".", 2)
 		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
 			return fmt.Errorf("%w: %s (expected db.collection)", ErrInvalidNamespace, cfg.namespace)
 		}
-		stream, err = client.Database(parts[0]).Collection(parts[1]).Watch(ctx, pipeline, opts)
-	} else {
-		stream, err = client.Watch(ctx, pipeline, opts)
+		coll := client.Database(parts[0]).Collection(parts[1])
+		watch = func(opts *options.ChangeStreamOptionsBuilder) (*mongo.ChangeStream, error) {
+			return coll.Watch(ctx, pipeline, opts)
+		}
 	}
 
+	backoff := cdc.Backoff{Initial: cfg.reconnectBackoff, Max: cfg.reconnectMaxBackoff}
+	attempt := 0
+	for {
+		received, err := followStream(ctx, cfg, resumeFrom, watch, checkpoint, batch, flushEvery, deliver, stats)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if cdc.IsHistoryLost(err) && !errors.Is(err, ErrResumePointLost) {
+			err = fmt.Errorf("%w: %w", ErrResumePointLost, err)
+		}
+		if !cdc.IsResumable(err) {
+			return err
+		}
+
+		if received {
+			attempt = 0
+		}
+		attempt++
+		if cfg.maxReconnects > 0 && attempt > cfg.maxReconnects {
+			return fmt.Errorf("%w: gave up after %d reconnect attempts: %w", ErrStreamFailed, cfg.maxReconnects, err)
+		}
+		delay := backoff.Delay(attempt)
+		stats.Reconnects++
+		stats.LastError = err.Error()
+		zap.S().Warnw("Change stream interrupted, reconnecting",
+			"attempt", attempt, "reconnects", stats.Reconnects, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if latest, ok := checkpoint.Latest(); ok {
+			resumeFrom = &latest
+		}
+	}
+}
+
+// followStream runs one change stream connection until it fails. received
+// reports whether any event was delivered, which resets the reconnect backoff.
+func followStream(
+	ctx context.Context, cfg oplogConfig, resumeFrom *cdc.Checkpoint,
+	watch func(*options.ChangeStreamOptionsBuilder) (*mongo.ChangeStream, error),
+	checkpoint *cdc.Checkpointer, batch int, flushEvery time.Duration,
+	deliver func([]oplogEntry) error, stats *cdc.StreamStats,
+) (received bool, err error) {
+	stream, err := watchFromCheckpoint(cfg, resumeFrom, watch)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrStreamFailed, err)
+		return false, err
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+
+	pending := make([]oplogEntry, 0, batch)
+	var oldest time.Time
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := deliver(pending); err != nil {
+			return err
+		}
+		last := pending[len(pending)-1]
+		pending = pending[:0]
+		received = true
+		stats.LastEventAt = eventTime(last)
+		return checkpoint.Mark(ctx, cdc.Checkpoint{Token: stream.ResumeToken(), ClusterTime: last.TS})
 	}
-	defer stream.Close(ctx)
 
-	for stream.Next(ctx) {
+	for {
+		if !stream.TryNext(ctx) {
+			if err := stream.Err(); err != nil {
+				return received, err
+			}
+			if err := ctx.Err(); err != nil {
+				return received, err
+			}
+			if err := flush(); err != nil {
+				return received, err
+			}
+			continue
+		}
+
 		var event bson.M
 		if err := stream.Decode(&event); err != nil {
-			return err
+			return received, err
 		}
+		if len(pending) == 0 {
+			oldest = time.Now()
+		}
+		pending = append(pending, entryFromEvent(event))
+		if len(pending) >= batch || (flushEvery > 0 && time.Since(oldest) >= flushEvery) {
+			if err := flush(); err != nil {
+				return received, err
+			}
+		}
+	}
+}
 
-		entry := oplogEntry{}
-		if opType, ok := event["operationType"].(string); ok {
-			entry.Op = opFromType(opType)
+func openTokenStore(client *mongo.Client, cfg oplogConfig) (cdc.TokenStore, error) {
+	switch {
+	case cfg.resumeStore != "":
+		var db *mongo.Database
+		if cfg.database != "" {
+			db = client.Database(cfg.database)
 		}
-		if ns := formattedNamespace(event["ns"]); ns != "" {
-			entry.NS = ns
+		return cdc.OpenTokenStore(cfg.resumeStore, db)
+	case cfg.resumeFile != "":
+		return cdc.NewFileTokenStore(cfg.resumeFile), nil
+	default:
+		return &cdc.MemoryTokenStore{}, nil
+	}
+}
+
+// watchFromCheckpoint opens the change stream at the checkpoint. A token
+// saved from an invalidate event cannot be used with resumeAfter, so a
+// rejected token is retried once with startAfter. A token that has rolled off
+// the oplog cannot be resumed at all and fails with ErrResumePointLost.
+func watchFromCheckpoint(
+	cfg oplogConfig, cp *cdc.Checkpoint,
+	watch func(*options.ChangeStreamOptionsBuilder) (*mongo.ChangeStream, error),
+) (*mongo.ChangeStream, error) {
+	base := func() *options.ChangeStreamOptionsBuilder {
+		opts := options.ChangeStream()
+		if cfg.fullDoc {
+			opts.SetFullDocument(options.UpdateLookup)
 		}
-		if doc, ok := toBsonM(event["fullDocument"]); ok {
-			entry.O = doc
+		if cfg.beforeDoc != "" && cfg.beforeDoc != "off" {
+			opts.SetFullDocumentBeforeChange(options.FullDocument(cfg.beforeDoc))
 		}
-		if key, ok := toBsonM(event["documentKey"]); ok {
-			entry.O2 = key
+		if cfg.expanded {
+			opts.SetShowExpandedEvents(true)
 		}
-		if clusterTime, ok := event["clusterTime"].(bson.Timestamp); ok {
-			entry.TS = clusterTime
+		return opts
+	}
+	if cp == nil || len(cp.Token) == 0 {
+		opts := base()
+		if cp != nil && !cp.ClusterTime.IsZero() {
+			opts.SetStartAtOperationTime(&cp.ClusterTime)
 		}
-		if wall, ok := event["wallTime"].(bson.DateTime); ok {
-			t := wall.Time()
-			entry.Wall = &t
-			if entry.TS.T == 0 && entry.TS.I == 0 {
-				entry.TS = bson.Timestamp{T: uint32(t.Unix())}
-			}
+		stream, err := watch(opts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrStreamFailed, err)
 		}
+		return stream, nil
+	}
 
-		if cfg.resumeFile != "" {
-			if token := stream.ResumeToken(); len(token) > 0 {
-				_ = os.WriteFile(cfg.resumeFile, token, 0o644)
-			}
+	stream, err := watch(base().SetResumeAfter(cp.Token))
+	if err != nil && cdc.IsHistoryLost(err) && !cdc.IsTokenRolledOff(err) {
+		zap.S().Warnw("Resume token rejected; retrying with startAfter", "error", err)
+		stream, err = watch(base().SetStartAfter(cp.Token))
+	}
+	switch {
+	case err == nil:
+		return stream, nil
+	case cdc.IsHistoryLost(err):
+		return nil, fmt.Errorf("%w: %w", ErrResumePointLost, err)
+	default:
+		return nil, fmt.Errorf("%w: %w", ErrStreamFailed, err)
+	}
+}
+
+func entryFromEvent(event bson.M) oplogEntry {
+	entry := oplogEntry{}
+	if opType, ok := event["operationType"].(string); ok {
+		entry.Type = opType
+		entry.Op = opFromType(opType)
+	}
+	if ns := formattedNamespace(event["ns"]); ns != "" {
+		entry.NS = ns
+	} else if db, ok := cdc.ToBsonM(event["ns"]); ok {
+		entry.NS, _ = db["db"].(string)
+	}
+	if doc, ok := cdc.ToBsonM(event["fullDocument"]); ok {
+		entry.O = doc
+	}
+	if key, ok := cdc.ToBsonM(event["documentKey"]); ok {
+		entry.O2 = key
+	}
+	if before, ok := cdc.ToBsonM(event["fullDocumentBeforeChange"]); ok {
+		entry.Before = before
+	}
+	entry.Update = updateDeltaFromEvent(event["updateDescription"])
+	if _, ok := ddlEvents[entry.Type]; ok {
+		entry.DDL = ddlDetails(event)
+	}
+	if n, ok := event["txnNumber"].(int64); ok {
+		entry.TxnNumber = &n
+		entry.LSID, _ = cdc.ToBsonM(event["lsid"])
+	}
+	if clusterTime, ok := event["clusterTime"].(bson.Timestamp); ok {
+		entry.TS = clusterTime
+	}
+	if wall, ok := event["wallTime"].(bson.DateTime); ok {
+		t := wall.Time()
+		entry.Wall = &t
+		if entry.TS.T == 0 && entry.TS.I == 0 {
+			entry.TS = bson.Timestamp{T: uint32(t.Unix())}
 		}
+	}
+	return entry
+}
 
-		if err := render([]oplogEntry{entry}); err != nil {
-			return err
+// ddlDetails collects what a DDL event changed: the rename target and the
+// expanded operationDescription (index specs, collMod options, shard keys).
+func ddlDetails(event bson.M) bson.M {
+	out := bson.M{}
+	if desc, ok := cdc.ToBsonM(event["operationDescription"]); ok {
+		for k, v := range desc {
+			out[k] = v
 		}
 	}
-	return stream.Err()
+	if to := formattedNamespace(event["to"]); to != "" {
+		out["to"] = to
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
 }
 
+// opFromType maps a change stream operationType to its oplog op code so the
+// op filters apply to both: replace is an update and DDL events are commands.
 func opFromType(st string) string {
 	if code, ok := operations.names[st]; ok {
 		return code
 	}
+	if st == "replace" {
+		return "u"
+	}
+	if _, ok := ddlEvents[st]; ok {
+		return "c"
+	}
 	return st
 }
 
 func formattedNamespace(raw interface{}) string {
-	if ns, ok := toBsonM(raw); ok {
+	if ns, ok := cdc.ToBsonM(raw); ok {
 		db, _ := ns["db"].(string)
 		coll, _ := ns["coll"].(string)
 		if db != "" && coll != "" {
@@ -369,33 +655,6 @@ func formattedNamespace(raw interface{}) string {
 	return ""
 }
 
-func toBsonM(val interface{}) (bson.M, bool) {
-	if val == nil {
-		return nil, false
-	}
-	switch v := val.(type) {
-	case bson.M:
-		return v, true
-	case bson.D:
-		return bsonDToMap(v), true
-	case map[string]interface{}:
-		return bson.M(v), true
-	default:
-		return nil, false
-	}
-}
-
-func bsonDToMap(d bson.D) bson.M {
-	if len(d) == 0 {
-		return bson.M{}
-	}
-	out := make(bson.M, len(d))
-	for _, elem := range d {
-		out[elem.Key] = elem.Value
-	}
-	return out
-}
-
 func parseOps(raw string) ([]string, error) {
 	clean := strings.Split(strings.ReplaceAll(raw, " ", ""), ",")
 	out := make([]string, 0, len(clean))
@@ -423,7 +682,22 @@ func mapOpsToNames(codes []string) []string {
 		if name, ok := operations.codes[code]; ok {
 			out = append(out, name)
 		}
+		switch code {
+		case "u":
+			out = append(out, "replace")
+		case "c":
+			out = append(out, sortedDDLEvents()...)
+		}
+	}
+	return out
+}
+
+func sortedDDLEvents() []string {
+	out := make([]string, 0, len(ddlEvents))
+	for event := range ddlEvents {
+		out = append(out, event)
 	}
+	sort.Strings(out)
 	return out
 }
 
@@ -435,20 +709,3 @@ func parseTime(v string) (bson.Timestamp, error) {
 	}
 	return bson.Timestamp{}, fmt.Errorf("%w: %s", ErrInvalidTimeFormat, v)
 }
-
-func oplogCollection(client *mongo.Client) (*mongo.Collection, error) {
-	localDB := client.Database("local")
-	names, err := localDB.ListCollectionNames(context.Background(), bson.D{})
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrFailedToList, err)
-	}
-	for _, name := range names {
-		if name == "oplog.rs" {
-			return localDB.Collection("oplog.rs"), nil
-		}
-		if name == "oplog.$main" {
-			return localDB.Collection("oplog.$main"), nil
-		}
-	}
-	return nil, ErrOplogCollection
-}
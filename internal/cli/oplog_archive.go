@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrArchiveDir          = errors.New("--dir is required")
+	ErrFollowArchive       = errors.New("--from-archive cannot be combined with --follow")
+	ErrArchiveCursorClosed = errors.New("oplog cursor closed")
+)
+
+type oplogArchiveConfig struct {
+	dir        string
+	format     string
+	partition  time.Duration
+	follow     bool
+	flushEvery time.Duration
+	output     string
+	filter     oplogConfig
+}
+
+type archiveSummary struct {
+	Dir        string                 `json:"dir"`
+	Format     string                 `json:"format"`
+	Archived   int64                  `json:"archived"`
+	Partitions []cdc.ArchivePartition `json:"partitions"`
+}
+
+func newOplogArchiveCmd() *cobra.Command {
+	cfg := oplogArchiveConfig{}
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Export oplog entries to compressed, time-partitioned files",
+		Long: `Copy raw oplog entries into gzip files under --dir, one file per --partition
+of wall time, with an index.json listing each file's timestamp range.
+
+Without --follow the entries between --from and --to are exported once; with
+--follow the oplog is tailed and flushed every --flush-interval. Re-running
+against the same directory resumes after the newest archived entry. Query an
+archive offline with 'mongo oplog --from-archive <dir>'.`,
+		Example: `  mongo oplog archive --dir /backups/oplog --from 2026-10-01 --to 2026-10-02
+  mongo oplog archive --dir /backups/oplog --follow --format ndjson --partition 15m`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil || s.MongoClient == nil {
+				return ErrMongoClientUnavailable
+			}
+			return runOplogArchive(cmd.Context(), cmd.OutOrStdout(), s.MongoClient, cfg)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&cfg.dir, "dir", "", "Archive directory")
+	f.StringVar(&cfg.format, "format", cdc.ArchiveFormatBSON, "File format (bson, ndjson)")
+	f.DurationVar(&cfg.partition, "partition", cdc.DefaultArchivePartition, "Wall time covered by each file")
+	f.BoolVar(&cfg.follow, "follow", false, "Keep tailing the oplog")
+	f.DurationVar(&cfg.flushEvery, "flush-interval", 5*time.Second, "With --follow, how often to flush files and index")
+	f.StringVarP(&cfg.output, "output", "o", "table", "Output format (table, json)")
+	f.StringVar(&cfg.filter.namespace, "namespace", "", "Only archive this namespace (db.collection)")
+	f.StringVar(&cfg.filter.regex, "regex", "", "Only archive namespaces matching this regex")
+	f.StringVar(&cfg.filter.ops, "ops", "", "Only archive these op codes/names")
+	f.StringVar(&cfg.filter.from, "from", "", "Start time (RFC3339 or YYYY-MM-DD)")
+	f.StringVar(&cfg.filter.to, "to", "", "End time (RFC3339 or YYYY-MM-DD)")
+	return cmd
+}
+
+func runOplogArchive(ctx context.Context, w io.Writer, client *mongo.Client, cfg oplogArchiveConfig) error {
+	if cfg.dir == "" {
+		return ErrArchiveDir
+	}
+	if cfg.follow && cfg.filter.to != "" {
+		return ErrFollowAndTo
+	}
+	filter, err := newOplogFilter(cfg.filter)
+	if err != nil {
+		return err
+	}
+	coll, err := cdc.OplogCollection(ctx, client)
+	if err != nil {
+		return err
+	}
+	archive, err := cdc.OpenArchiveWriter(cfg.dir, cfg.format, cfg.partition)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	before := archive.Index()
+	var archived int64
+	for {
+		// Resume after the newest archived entry; the writer also skips
+		// anything at or before it.
+		if last := archive.Last(); last.After(filter.From) {
+			filter.From = last
+		}
+		n, err := archiveOplog(ctx, coll, archive, filter, cfg)
+		archived += n
+		if !cfg.follow || ctx.Err() != nil {
+			if ctx.Err() != nil {
+				err = nil
+			}
+			if ferr := archive.Flush(); err == nil {
+				err = ferr
+			}
+			if err != nil {
+				return err
+			}
+			return renderArchiveSummary(w, cfg, archived, before, archive.Index())
+		}
+		if err != nil && !cdc.IsResumable(err) && !errors.Is(err, ErrArchiveCursorClosed) {
+			return err
+		}
+		// A tailable cursor over an empty range closes at once; only real
+		// failures are worth a warning.
+		if err != nil && !errors.Is(err, ErrArchiveCursorClosed) {
+			zap.S().Warnw("Oplog archive cursor interrupted; reopening", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(cdc.DefaultBackoff.Initial):
+		}
+	}
+}
+
+// archiveOplog copies matching entries into archive. With --follow it tails
+// the oplog until the context ends or the cursor dies. Only the time range is
+// pushed to the server: transactions are logged under admin.$cmd, so other
+// filters are matched after expansion, and a transaction is archived whole
+// when any of its operations match.
+func archiveOplog(
+	ctx context.Context,
+	coll *mongo.Collection,
+	archive *cdc.ArchiveWriter,
+	filter oplogFilter,
+	cfg oplogArchiveConfig,
+) (int64, error) {
+	findOpts := options.Find()
+	if cfg.follow {
+		findOpts.SetCursorType(options.TailableAwait)
+	} else {
+		findOpts.SetSort(bson.D{{Key: "$natural", Value: 1}})
+	}
+	rangeOnly := oplogFilter{From: filter.From, To: filter.To}
+	cur, err := coll.Find(ctx, rangeOnly.findFilter(), findOpts)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrFailedToQueryOplog, err)
+	}
+	defer cur.Close(context.WithoutCancel(ctx))
+
+	var n int64
+	write := func(raw bson.Raw) error {
+		if ok, err := archiveMatches(filter, raw); err != nil || !ok {
+			return err
+		}
+		if err := archive.Write(raw); err != nil {
+			return err
+		}
+		n++
+		return nil
+	}
+	if !cfg.follow {
+		for cur.Next(ctx) {
+			if err := write(cur.Current); err != nil {
+				return n, err
+			}
+		}
+		return n, cur.Err()
+	}
+
+	lastFlush := time.Now()
+	for {
+		if cur.TryNext(ctx) {
+			if err := write(cur.Current); err != nil {
+				return n, err
+			}
+		} else if err := cur.Err(); err != nil {
+			return n, err
+		} else if cur.ID() == 0 {
+			return n, ErrArchiveCursorClosed
+		}
+		if time.Since(lastFlush) >= cfg.flushEvery && cur.RemainingBatchLength() == 0 {
+			if err := archive.Flush(); err != nil {
+				return n, err
+			}
+			lastFlush = time.Now()
+		}
+	}
+}
+
+// archiveMatches reports whether a raw oplog entry, or any operation of a
+// transaction, passes filter.
+func archiveMatches(filter oplogFilter, raw bson.Raw) (bool, error) {
+	var e oplogEntry
+	if err := bson.Unmarshal(raw, &e); err != nil {
+		return false, err
+	}
+	for _, inner := range expandTransactions([]oplogEntry{e}) {
+		if filter.matchesEntry(&inner) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func renderArchiveSummary(w io.Writer, cfg oplogArchiveConfig, archived int64, before, after cdc.ArchiveIndex) error {
+	counts := map[string]int64{}
+	for _, p := range before.Partitions {
+		counts[p.File] = p.Count
+	}
+	var touched []cdc.ArchivePartition
+	for _, p := range after.Partitions {
+		if p.Count != counts[p.File] {
+			touched = append(touched, p)
+		}
+	}
+	if strings.ToLower(cfg.output) == "json" {
+		return encodePrettyJSON(w, archiveSummary{
+			Dir:        cfg.dir,
+			Format:     after.Format,
+			Archived:   archived,
+			Partitions: touched,
+		})
+	}
+	fmt.Fprintf(w, "Archived %d entries into %d partition(s) in %s\n", archived, len(touched), cfg.dir)
+	for _, p := range touched {
+		fmt.Fprintf(w, "  %s  %d entries  %s → %s\n", p.File, p.Count,
+			time.Unix(int64(p.First.T), 0).UTC().Format(time.RFC3339),
+			time.Unix(int64(p.Last.T), 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// readArchivedOplog answers a query from an archive: the newest limit
+// matching entries, with transactions expanded, newest first like a live query.
+func readArchivedOplog(dir string, filter oplogFilter, limit int64) ([]oplogEntry, error) {
+	var matched []oplogEntry
+	err := cdc.ScanArchive(dir, filter.From, filter.To, func(raw bson.Raw) error {
+		var e oplogEntry
+		if err := bson.Unmarshal(raw, &e); err != nil {
+			return err
+		}
+		for _, inner := range expandTransactions([]oplogEntry{e}) {
+			if filter.matchesEntry(&inner) {
+				matched = append(matched, inner)
+			}
+		}
+		if limit > 0 && int64(len(matched)) > 2*limit {
+			matched = matched[int64(len(matched))-limit:]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(matched)) > limit {
+		matched = matched[int64(len(matched))-limit:]
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].TS.After(matched[j].TS) })
+	return matched, nil
+}
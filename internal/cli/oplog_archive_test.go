@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func writeTestArchive(t *testing.T, entries ...bson.D) string {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := cdc.OpenArchiveWriter(dir, cdc.ArchiveFormatNDJSON, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		raw, err := bson.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestReadArchivedOplogAppliesLiveFilters(t *testing.T) {
+	dir := writeTestArchive(t,
+		bson.D{{Key: "ts", Value: bson.Timestamp{T: 100}}, {Key: "op", Value: "i"}, {Key: "ns", Value: "app.orders"},
+			{Key: "o", Value: bson.D{{Key: "_id", Value: int32(1)}, {Key: "total", Value: int32(50)}}}},
+		bson.D{{Key: "ts", Value: bson.Timestamp{T: 101}}, {Key: "op", Value: "i"}, {Key: "ns", Value: "app.orders"},
+			{Key: "o", Value: bson.D{{Key: "_id", Value: int32(2)}, {Key: "total", Value: int32(500)}}}},
+		bson.D{{Key: "ts", Value: bson.Timestamp{T: 102}}, {Key: "op", Value: "c"}, {Key: "ns", Value: "admin.$cmd"},
+			{Key: "txnNumber", Value: int64(4)}, {Key: "o", Value: bson.D{{Key: "applyOps", Value: bson.A{
+				bson.D{{Key: "op", Value: "u"}, {Key: "ns", Value: "app.orders"},
+					{Key: "o", Value: bson.D{{Key: "$v", Value: int32(2)},
+						{Key: "diff", Value: bson.D{{Key: "u", Value: bson.D{{Key: "total", Value: 900.5}}}}}}},
+					{Key: "o2", Value: bson.D{{Key: "_id", Value: int32(1)}}}},
+			}}}}},
+		bson.D{{Key: "ts", Value: bson.Timestamp{T: 103}}, {Key: "op", Value: "d"}, {Key: "ns", Value: "app.carts"},
+			{Key: "o", Value: bson.D{{Key: "_id", Value: int32(1)}}}},
+	)
+
+	filter, err := newOplogFilter(oplogConfig{namespace: "app.orders", where: []string{"total>=100"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readArchivedOplog(dir, filter, 0)
+	if err != nil {
+		t.Fatalf("readArchivedOplog: %v", err)
+	}
+	if len(entries) != 2 || entries[0].TS.T != 102 || entries[1].TS.T != 101 {
+		t.Fatalf("expected the transaction update then insert 2, newest first; got %+v", entries)
+	}
+	if entries[0].TxnNumber == nil || *entries[0].TxnNumber != 4 {
+		t.Fatalf("expected the expanded update to keep its transaction number")
+	}
+
+	filter, err = newOplogFilter(oplogConfig{ops: "i,d", from: "1970-01-01T00:01:41Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, err = readArchivedOplog(dir, filter, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].NS != "app.carts" {
+		t.Fatalf("expected only the newest matching entry, got %+v", entries)
+	}
+}
+
+func TestWherePredicateEval(t *testing.T) {
+	doc := bson.M{"status": "paid", "total": int32(10), "customer": bson.M{"tier": "gold"}}
+	cases := []struct {
+		raw  string
+		want bool
+	}{
+		{"status=paid", true},
+		{"status!=paid", false},
+		{"missing!=x", true},
+		{"total>9.5", true},
+		{"total<=9", false},
+		{"customer.tier=gold", true},
+		{"status>=a", true},
+	}
+	for _, c := range cases {
+		p, err := parseWhere(c.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.eval(doc); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestArchiveMatchesTransactionsByInnerOperation(t *testing.T) {
+	txn, err := bson.Marshal(bson.D{{Key: "ts", Value: bson.Timestamp{T: 100}}, {Key: "op", Value: "c"},
+		{Key: "ns", Value: "admin.$cmd"}, {Key: "txnNumber", Value: int64(1)},
+		{Key: "o", Value: bson.D{{Key: "applyOps", Value: bson.A{
+			bson.D{{Key: "op", Value: "i"}, {Key: "ns", Value: "app.orders"}, {Key: "o", Value: bson.D{{Key: "_id", Value: 1}}}},
+		}}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		cfg  oplogConfig
+		want bool
+	}{
+		{oplogConfig{namespace: "app.orders", ops: "i"}, true},
+		{oplogConfig{namespace: "app.orders", ops: "d"}, false},
+		{oplogConfig{namespace: "app.other"}, false},
+	}
+	for _, tc := range cases {
+		filter, err := newOplogFilter(tc.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := archiveMatches(filter, txn); err != nil || got != tc.want {
+			t.Errorf("archiveMatches(%+v) = %v, %v; want %v", tc.cfg, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ddlEvents maps change stream DDL event types to the oplog command that
+// produces them. Most need showExpandedEvents (MongoDB 6.0+).
+var ddlEvents = map[string]string{
+	"create":                   "create",
+	"createIndexes":            "createIndexes",
+	"drop":                     "drop",
+	"dropDatabase":             "dropDatabase",
+	"dropIndexes":              "dropIndexes",
+	"modify":                   "collMod",
+	"rename":                   "renameCollection",
+	"shardCollection":          "shardCollection",
+	"refineCollectionShardKey": "refineCollectionShardKey",
+	"reshardCollection":        "reshardCollection",
+}
+
+// ddlFromCommand is the reverse of ddlEvents for oplog "c" entries.
+var ddlFromCommand = func() map[string]string {
+	out := make(map[string]string, len(ddlEvents))
+	for event, command := range ddlEvents {
+		out[command] = event
+	}
+	return out
+}()
+
+// updateDelta mirrors a change stream updateDescription.
+type updateDelta struct {
+	UpdatedFields   bson.M           `json:"updated_fields,omitempty" bson:"updated_fields,omitempty"`
+	RemovedFields   []string         `json:"removed_fields,omitempty" bson:"removed_fields,omitempty"`
+	TruncatedArrays []truncatedArray `json:"truncated_arrays,omitempty" bson:"truncated_arrays,omitempty"`
+}
+
+type truncatedArray struct {
+	Field   string `json:"field" bson:"field"`
+	NewSize int32  `json:"new_size" bson:"new_size"`
+}
+
+// txnInfo identifies the transaction an event belongs to; events with the same
+// session and number were committed together.
+type txnInfo struct {
+	Number  int64  `json:"number" bson:"number"`
+	Session string `json:"session,omitempty" bson:"session,omitempty"`
+}
+
+func (u *updateDelta) empty() bool {
+	return u == nil || (len(u.UpdatedFields) == 0 && len(u.RemovedFields) == 0 && len(u.TruncatedArrays) == 0)
+}
+
+// updateDeltaFromEvent reads a change stream updateDescription.
+func updateDeltaFromEvent(raw any) *updateDelta {
+	desc, ok := cdc.ToBsonM(raw)
+	if !ok {
+		return nil
+	}
+	delta := &updateDelta{}
+	if fields, ok := cdc.ToBsonM(desc["updatedFields"]); ok && len(fields) > 0 {
+		delta.UpdatedFields = fields
+	}
+	if removed, ok := desc["removedFields"].(bson.A); ok {
+		for _, f := range removed {
+			if s, ok := f.(string); ok {
+				delta.RemovedFields = append(delta.RemovedFields, s)
+			}
+		}
+	}
+	if truncated, ok := desc["truncatedArrays"].(bson.A); ok {
+		for _, t := range truncated {
+			if m, ok := cdc.ToBsonM(t); ok {
+				field, _ := m["field"].(string)
+				size, _ := m["newSize"].(int32)
+				delta.TruncatedArrays = append(delta.TruncatedArrays, truncatedArray{Field: field, NewSize: size})
+			}
+		}
+	}
+	if delta.empty() {
+		return nil
+	}
+	return delta
+}
+
+// updateDeltaFromOplog decodes the "o" of an oplog update into the shape of a
+// change stream updateDescription. A replacement has no delta.
+func updateDeltaFromOplog(o bson.M) *updateDelta {
+	parsed := cdc.ParseOplogUpdate(o)
+	if parsed.Empty() {
+		return nil
+	}
+	delta := &updateDelta{UpdatedFields: parsed.Set, RemovedFields: parsed.Unset}
+	for _, t := range parsed.Truncated {
+		delta.TruncatedArrays = append(delta.TruncatedArrays, truncatedArray{Field: t.Field, NewSize: t.NewSize})
+	}
+	return delta
+}
+
+// sessionID renders lsid.id (a UUID) as hex, falling back to its string form.
+func sessionID(lsid bson.M) string {
+	switch id := lsid["id"].(type) {
+	case nil:
+		return ""
+	case bson.Binary:
+		return fmt.Sprintf("%x", id.Data)
+	default:
+		return fmt.Sprintf("%v", id)
+	}
+}
+
+// expandTransactions replaces applyOps entries with the operations they
+// commit, each tagged with the transaction's session and number. Oplog
+// queries see multi-document transactions only in this form.
+func expandTransactions(entries []oplogEntry) []oplogEntry {
+	out := make([]oplogEntry, 0, len(entries))
+	for _, e := range entries {
+		ops, ok := e.O["applyOps"].(bson.A)
+		if e.Op != "c" || !ok {
+			out = append(out, e)
+			continue
+		}
+		for _, raw := range ops {
+			op, ok := cdc.ToBsonM(raw)
+			if !ok {
+				continue
+			}
+			inner := oplogEntry{TS: e.TS, Wall: e.Wall, TxnNumber: e.TxnNumber, LSID: e.LSID}
+			inner.Op, _ = op["op"].(string)
+			inner.NS, _ = op["ns"].(string)
+			inner.O, _ = cdc.ToBsonM(op["o"])
+			inner.O2, _ = cdc.ToBsonM(op["o2"])
+			out = append(out, inner)
+		}
+	}
+	return out
+}
+
+// eventType names an entry the way change streams do: insert, update,
+// replace, delete, a DDL type such as createIndexes, or the raw op.
+func (e *oplogEntry) eventType() string {
+	if e.Type != "" {
+		return e.Type
+	}
+	switch e.Op {
+	case "u":
+		if !cdc.IsOperatorUpdate(e.O) {
+			return "replace"
+		}
+	case "c":
+		for key := range e.O {
+			if event, ok := ddlFromCommand[key]; ok {
+				return event
+			}
+		}
+	}
+	if name, ok := operations.codes[e.Op]; ok {
+		return name
+	}
+	return e.Op
+}
+
+// detail summarizes the delta, DDL target or transaction for the table view.
+func (o oplogOutput) detail() string {
+	var parts []string
+	if o.Update != nil {
+		if fields := sortedKeys(o.Update.UpdatedFields); len(fields) > 0 {
+			parts = append(parts, "set "+strings.Join(fields, ","))
+		}
+		if len(o.Update.RemovedFields) > 0 {
+			parts = append(parts, "unset "+strings.Join(o.Update.RemovedFields, ","))
+		}
+		for _, t := range o.Update.TruncatedArrays {
+			parts = append(parts, fmt.Sprintf("truncate %s→%d", t.Field, t.NewSize))
+		}
+	}
+	if to, ok := o.DDL["to"]; ok {
+		parts = append(parts, fmt.Sprintf("to %v", to))
+	}
+	if name, ok := o.DDL["indexName"]; ok {
+		parts = append(parts, fmt.Sprintf("index %v", name))
+	}
+	if o.Before != nil {
+		parts = append(parts, "before-image")
+	}
+	if o.Txn != nil {
+		parts = append(parts, fmt.Sprintf("txn %d", o.Txn.Number))
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedKeys(m bson.M) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
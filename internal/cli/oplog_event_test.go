@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestUpdateDeltaFromOplogDiff(t *testing.T) {
+	o := bson.M{"$v": int32(2), "diff": bson.D{
+		{Key: "u", Value: bson.D{{Key: "status", Value: "paid"}}},
+		{Key: "d", Value: bson.D{{Key: "draft", Value: false}}},
+		{Key: "saddress", Value: bson.D{{Key: "i", Value: bson.D{{Key: "zip", Value: "10115"}}}}},
+		{Key: "sitems", Value: bson.D{{Key: "a", Value: true}, {Key: "l", Value: int32(2)},
+			{Key: "u1", Value: "b"}}},
+	}}
+	got := updateDeltaFromOplog(o)
+	want := &updateDelta{
+		UpdatedFields:   bson.M{"status": "paid", "address.zip": "10115", "items.1": "b"},
+		RemovedFields:   []string{"draft"},
+		TruncatedArrays: []truncatedArray{{Field: "items", NewSize: 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected delta\n got %+v\nwant %+v", got, want)
+	}
+
+	legacy := updateDeltaFromOplog(bson.M{"$set": bson.M{"a": 1}, "$unset": bson.M{"b": true}})
+	if legacy == nil || legacy.UpdatedFields["a"] != 1 || legacy.RemovedFields[0] != "b" {
+		t.Fatalf("unexpected legacy delta %+v", legacy)
+	}
+}
+
+func TestEventTypeFromOplog(t *testing.T) {
+	cases := map[string]oplogEntry{
+		"replace":           {Op: "u", O: bson.M{"_id": 1, "name": "x"}},
+		"update":            {Op: "u", O: bson.M{"$v": int32(2), "diff": bson.M{}}},
+		"rename":            {Op: "c", O: bson.M{"renameCollection": "app.a", "to": "app.b"}},
+		"createIndexes":     {Op: "c", O: bson.M{"createIndexes": "users", "name": "email_1"}},
+		"command":           {Op: "c", O: bson.M{"applyOps": bson.A{}}},
+		"insert":            {Op: "i"},
+		"shardCollection":   {Type: "shardCollection"},
+		"reshardCollection": {Op: "c", O: bson.M{"reshardCollection": "app.a"}},
+	}
+	for want, entry := range cases {
+		if got := entry.eventType(); got != want {
+			t.Errorf("eventType = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestExpandTransactions(t *testing.T) {
+	n := int64(7)
+	entries := expandTransactions([]oplogEntry{{
+		Op: "c", NS: "admin.$cmd", TxnNumber: &n,
+		LSID: bson.M{"id": bson.Binary{Subtype: 4, Data: []byte{0xab, 0xcd}}},
+		O: bson.M{"applyOps": bson.A{
+			bson.D{{Key: "op", Value: "i"}, {Key: "ns", Value: "app.orders"}, {Key: "o", Value: bson.D{{Key: "_id", Value: 1}}}},
+			bson.D{{Key: "op", Value: "d"}, {Key: "ns", Value: "app.carts"}, {Key: "o", Value: bson.D{{Key: "_id", Value: 2}}}},
+		}},
+	}})
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 expanded entries, got %d", len(entries))
+	}
+	out := entries[1].ToOutput()
+	if out.Operation != "delete" || out.Namespace != "app.carts" || out.Txn == nil ||
+		out.Txn.Number != 7 || out.Txn.Session != "abcd" {
+		t.Fatalf("unexpected expanded output %+v", out)
+	}
+}
+
+func TestEntryFromChangeEvent(t *testing.T) {
+	entry := entryFromEvent(bson.M{
+		"operationType": "update",
+		"ns":            bson.D{{Key: "db", Value: "app"}, {Key: "coll", Value: "orders"}},
+		"documentKey":   bson.D{{Key: "_id", Value: "o1"}},
+		"updateDescription": bson.D{
+			{Key: "updatedFields", Value: bson.D{{Key: "status", Value: "paid"}}},
+			{Key: "removedFields", Value: bson.A{"draft"}},
+			{Key: "truncatedArrays", Value: bson.A{}},
+		},
+		"fullDocumentBeforeChange": bson.D{{Key: "_id", Value: "o1"}, {Key: "status", Value: "new"}},
+		"txnNumber":                int64(3),
+	})
+	out := entry.ToOutput()
+	if out.Operation != "update" || out.Update == nil || out.Update.UpdatedFields["status"] != "paid" ||
+		out.Before["status"] != "new" || out.Txn == nil || out.Txn.Number != 3 {
+		t.Fatalf("unexpected output %+v", out)
+	}
+	if got := out.detail(); got != "set status unset draft before-image txn 3" {
+		t.Fatalf("unexpected detail %q", got)
+	}
+
+	rename := entryFromEvent(bson.M{
+		"operationType": "rename",
+		"ns":            bson.D{{Key: "db", Value: "app"}, {Key: "coll", Value: "a"}},
+		"to":            bson.D{{Key: "db", Value: "app"}, {Key: "coll", Value: "b"}},
+	})
+	if rename.Op != "c" || rename.ToOutput().detail() != "to app.b" {
+		t.Fatalf("unexpected rename entry %+v", rename)
+	}
+}
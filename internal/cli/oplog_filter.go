@@ -0,0 +1,389 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var ErrInvalidWhere = errors.New("invalid --where predicate (expected <path><op><value> with op one of = != > >= < <=)")
+
+// oplogFilter is the single filter model behind `mongo oplog`. It compiles to
+// a find filter on the oplog and to a change stream $match with the same
+// meaning, so a query and a follow with the same flags select the same events.
+type oplogFilter struct {
+	Namespace string
+	Regex     string
+	Ops       []string
+	ObjectID  any
+	From      bson.Timestamp
+	To        bson.Timestamp
+	Where     []wherePredicate
+	// Exclude lists namespaces a change stream never reports, such as the
+	// collection the stream saves its checkpoints in.
+	Exclude []string
+
+	re *regexp.Regexp
+}
+
+// wherePredicate compares a document field, written as in the oplog
+// (o.status or just status), against a literal.
+type wherePredicate struct {
+	Path  string
+	Op    string
+	Value any
+}
+
+var whereOperators = []struct {
+	token string
+	op    string
+}{
+	// Two-character tokens first so ">=" is not read as ">".
+	{"!=", "$ne"}, {">=", "$gte"}, {"<=", "$lte"}, {"=", "$eq"}, {">", "$gt"}, {"<", "$lt"},
+}
+
+func newOplogFilter(cfg oplogConfig) (oplogFilter, error) {
+	if cfg.namespace != "" && cfg.regex != "" {
+		return oplogFilter{}, ErrNamespaceOrRegex
+	}
+	f := oplogFilter{Namespace: cfg.namespace, Regex: cfg.regex}
+	if cfg.regex != "" {
+		re, err := regexp.Compile(cfg.regex)
+		if err != nil {
+			return oplogFilter{}, fmt.Errorf("%w: %w", ErrInvalidRegex, err)
+		}
+		f.re = re
+	}
+
+	if cfg.ops != "" {
+		codes, err := parseOps(cfg.ops)
+		if err != nil {
+			return oplogFilter{}, err
+		}
+		f.Ops = codes
+	}
+	if cfg.objectID != "" {
+		f.ObjectID = cdc.ParseDocumentID(cfg.objectID)
+	}
+	for _, spec := range []struct {
+		val string
+		dst *bson.Timestamp
+	}{{cfg.from, &f.From}, {cfg.to, &f.To}} {
+		if spec.val == "" {
+			continue
+		}
+		ts, err := parseTime(spec.val)
+		if err != nil {
+			return oplogFilter{}, err
+		}
+		*spec.dst = ts
+	}
+	for _, raw := range cfg.where {
+		p, err := parseWhere(raw)
+		if err != nil {
+			return oplogFilter{}, err
+		}
+		f.Where = append(f.Where, p)
+	}
+	return f, nil
+}
+
+func parseWhere(raw string) (wherePredicate, error) {
+	for _, candidate := range whereOperators {
+		path, value, ok := strings.Cut(raw, candidate.token)
+		if !ok {
+			continue
+		}
+		path = strings.TrimSpace(path)
+		path = strings.TrimPrefix(path, "o.")
+		if path == "" || strings.ContainsAny(path, "<>=!") || strings.HasPrefix(path, "$") {
+			return wherePredicate{}, fmt.Errorf("%w: %s", ErrInvalidWhere, raw)
+		}
+		return wherePredicate{Path: path, Op: candidate.op, Value: parseWhereValue(strings.TrimSpace(value))}, nil
+	}
+	return wherePredicate{}, fmt.Errorf("%w: %s", ErrInvalidWhere, raw)
+}
+
+// parseWhereValue reads numbers, booleans and null as such; anything else,
+// or a quoted value, is a string.
+func parseWhereValue(v string) any {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	switch v {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+// findFilter compiles the filter for a query on local.oplog.rs.
+func (f oplogFilter) findFilter() bson.D {
+	filter := bson.D{}
+	add := func(k string, v any) { filter = append(filter, bson.E{Key: k, Value: v}) }
+
+	if f.Namespace != "" {
+		add("ns", f.Namespace)
+	}
+	if f.Regex != "" {
+		add("ns", bson.Regex{Pattern: f.Regex})
+	}
+	if len(f.Ops) > 0 {
+		add("op", bson.M{"$in": f.Ops})
+	}
+	var and bson.A
+	if f.ObjectID != nil {
+		and = append(and, bson.M{"$or": bson.A{bson.M{"o._id": f.ObjectID}, bson.M{"o2._id": f.ObjectID}}})
+	}
+	for _, p := range f.Where {
+		// Inserts and replacements carry the document in o; updates carry a
+		// $v:2 diff or, on older servers, $set.
+		and = append(and, p.match("o."+p.Path, "o.diff.u."+p.Path, "o.diff.i."+p.Path, "o.$set."+p.Path))
+	}
+	if len(and) > 0 {
+		add("$and", and)
+	}
+
+	ts := bson.M{}
+	if !f.From.IsZero() {
+		ts["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		ts["$lte"] = f.To
+	}
+	if len(ts) > 0 {
+		add("ts", ts)
+	}
+	return filter
+}
+
+// streamMatch compiles the filter for a change stream $match. The namespace
+// is not matched here: the stream is opened on that collection instead, and
+// the start time becomes startAtOperationTime.
+func (f oplogFilter) streamMatch() bson.D {
+	match := bson.D{}
+	add := func(k string, v any) { match = append(match, bson.E{Key: k, Value: v}) }
+
+	if f.Regex != "" {
+		add("$expr", bson.M{"$regexMatch": bson.M{
+			"input": bson.M{"$concat": bson.A{"$ns.db", ".", bson.M{"$ifNull": bson.A{"$ns.coll", ""}}}},
+			"regex": f.Regex,
+		}})
+	}
+	if len(f.Ops) > 0 {
+		add("operationType", bson.M{"$in": mapOpsToNames(f.Ops)})
+	}
+	if len(f.Exclude) > 0 {
+		nor := make(bson.A, 0, len(f.Exclude))
+		for _, ns := range f.Exclude {
+			db, coll, _ := strings.Cut(ns, ".")
+			nor = append(nor, bson.M{"ns.db": db, "ns.coll": coll})
+		}
+		add("$nor", nor)
+	}
+	var and bson.A
+	if f.ObjectID != nil {
+		and = append(and, bson.M{"documentKey._id": f.ObjectID})
+	}
+	for _, p := range f.Where {
+		// updatedFields keys are literal dotted paths, so only top-level
+		// fields can be matched there; fullDocument covers the rest.
+		and = append(and, p.match("fullDocument."+p.Path, "updateDescription.updatedFields."+p.Path))
+	}
+	if len(and) > 0 {
+		add("$and", and)
+	}
+	return match
+}
+
+// match builds the predicate over the places a field can appear. A negation
+// must hold for the document itself, so it only checks the first path.
+func (p wherePredicate) match(paths ...string) bson.M {
+	if p.Op == "$ne" {
+		return bson.M{paths[0]: bson.M{"$ne": p.Value}}
+	}
+	alternatives := make(bson.A, 0, len(paths))
+	for _, path := range paths {
+		alternatives = append(alternatives, bson.M{path: bson.M{p.Op: p.Value}})
+	}
+	return bson.M{"$or": alternatives}
+}
+
+// queryFilter is what a historical query sends to the server. Transactions
+// are logged as admin.$cmd applyOps entries that namespace, op and document
+// filters cannot see into, so every applyOps entry in the range is fetched
+// too; results are then expanded and checked with matchesEntry.
+func (f oplogFilter) queryFilter() bson.D {
+	rangeOnly := oplogFilter{From: f.From, To: f.To}
+	direct := f
+	direct.From, direct.To = bson.Timestamp{}, bson.Timestamp{}
+	entries := direct.findFilter()
+	if len(entries) == 0 {
+		return rangeOnly.findFilter()
+	}
+	transactions := bson.D{{Key: "op", Value: "c"}, {Key: "o.applyOps", Value: bson.M{"$exists": true}}}
+	return append(bson.D{{Key: "$or", Value: bson.A{entries, transactions}}}, rangeOnly.findFilter()...)
+}
+
+// appendMatching expands e and appends the operations that pass the filter.
+func (f oplogFilter) appendMatching(out []oplogEntry, e oplogEntry) []oplogEntry {
+	for _, inner := range expandTransactions([]oplogEntry{e}) {
+		if f.matchesEntry(&inner) {
+			out = append(out, inner)
+		}
+	}
+	return out
+}
+
+// matchesEntry evaluates the filter in memory with the meaning findFilter has
+// on the server. Archives are queried this way, after transactions have been
+// expanded, so namespace filters also select operations inside transactions.
+func (f oplogFilter) matchesEntry(e *oplogEntry) bool {
+	if f.Namespace != "" && e.NS != f.Namespace {
+		return false
+	}
+	if f.Regex != "" && !f.regexp().MatchString(e.NS) {
+		return false
+	}
+	if len(f.Ops) > 0 && !slices.Contains(f.Ops, e.Op) {
+		return false
+	}
+	if f.ObjectID != nil && !whereEqual(e.O["_id"], f.ObjectID) && !whereEqual(e.O2["_id"], f.ObjectID) {
+		return false
+	}
+	if (!f.From.IsZero() && e.TS.Before(f.From)) || (!f.To.IsZero() && e.TS.After(f.To)) {
+		return false
+	}
+	for _, p := range f.Where {
+		diff, _ := cdc.ToBsonM(e.O["diff"])
+		inserted, _ := cdc.ToBsonM(diff["i"])
+		updated, _ := cdc.ToBsonM(diff["u"])
+		set, _ := cdc.ToBsonM(e.O["$set"])
+		if !p.eval(e.O, updated, inserted, set) {
+			return false
+		}
+	}
+	return true
+}
+
+// regexp returns the compiled --regex; filters built by hand compile it here.
+func (f oplogFilter) regexp() *regexp.Regexp {
+	if f.re != nil {
+		return f.re
+	}
+	re, err := regexp.Compile(f.Regex)
+	if err != nil {
+		return regexp.MustCompile(`\A\z.`)
+	}
+	return re
+}
+
+// eval mirrors match: a negation only looks at the first document, any other
+// operator holds if the field satisfies it in one of them. $set keys are
+// literal dotted paths.
+func (p wherePredicate) eval(docs ...bson.M) bool {
+	lookup := func(doc bson.M) (any, bool) {
+		if v, ok := doc[p.Path]; ok {
+			return v, true
+		}
+		return lookupPath(doc, p.Path)
+	}
+	if p.Op == "$ne" {
+		v, ok := lookup(docs[0])
+		return !ok || !whereEqual(v, p.Value)
+	}
+	for _, doc := range docs {
+		v, ok := lookup(doc)
+		if !ok {
+			continue
+		}
+		if p.Op == "$eq" {
+			if whereEqual(v, p.Value) {
+				return true
+			}
+			continue
+		}
+		c, ok := whereCompare(v, p.Value)
+		if !ok {
+			continue
+		}
+		switch p.Op {
+		case "$gt":
+			ok = c > 0
+		case "$gte":
+			ok = c >= 0
+		case "$lt":
+			ok = c < 0
+		case "$lte":
+			ok = c <= 0
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupPath(doc bson.M, path string) (any, bool) {
+	var cur any = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cdc.ToBsonM(cur)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func whereEqual(a, b any) bool {
+	if c, ok := whereCompare(a, b); ok {
+		return c == 0
+	}
+	return a == nil && b == nil
+}
+
+// whereCompare orders numbers of any width against each other and strings
+// against strings; other pairs only compare when equal.
+func whereCompare(a, b any) (int, bool) {
+	if x, ok := cdc.Number(a); ok {
+		if y, ok := cdc.Number(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+		return 0, false
+	}
+	if a != nil && b != nil && fmt.Sprintf("%T", a) == fmt.Sprintf("%T", b) && fmt.Sprint(a) == fmt.Sprint(b) {
+		return 0, true
+	}
+	return 0, false
+}
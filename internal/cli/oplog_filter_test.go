@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestParseWhere(t *testing.T) {
+	cases := map[string]wherePredicate{
+		"o.status=paid":    {Path: "status", Op: "$eq", Value: "paid"},
+		"total >= 100":     {Path: "total", Op: "$gte", Value: int64(100)},
+		"ratio<0.5":        {Path: "ratio", Op: "$lt", Value: 0.5},
+		"archived!=true":   {Path: "archived", Op: "$ne", Value: true},
+		`o.code="007"`:     {Path: "code", Op: "$eq", Value: "007"},
+		"address.city=Rio": {Path: "address.city", Op: "$eq", Value: "Rio"},
+	}
+	for raw, want := range cases {
+		got, err := parseWhere(raw)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("parseWhere(%q) = %+v, %v; want %+v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"status", "=paid", "$where=1"} {
+		if _, err := parseWhere(raw); !errors.Is(err, ErrInvalidWhere) {
+			t.Errorf("parseWhere(%q): expected ErrInvalidWhere, got %v", raw, err)
+		}
+	}
+}
+
+func TestOplogFilterReadsNumericObjectID(t *testing.T) {
+	f, err := newOplogFilter(oplogConfig{objectID: "42"})
+	if err != nil {
+		t.Fatalf("newOplogFilter returned error: %v", err)
+	}
+	if f.ObjectID != int64(42) {
+		t.Fatalf("expected --object-id 42 to be a number, got %#v", f.ObjectID)
+	}
+	if !f.matchesEntry(&oplogEntry{Op: "u", O2: bson.M{"_id": int32(42)}}) {
+		t.Fatal("expected an int32 _id to match")
+	}
+}
+
+func TestOplogFilterCompilesToBothModes(t *testing.T) {
+	oid := bson.NewObjectID()
+	f, err := newOplogFilter(oplogConfig{
+		regex:    "^app\\.ord",
+		ops:      "u",
+		objectID: oid.Hex(),
+		from:     "2026-01-02",
+		where:    []string{"o.status=paid"},
+	})
+	if err != nil {
+		t.Fatalf("newOplogFilter returned error: %v", err)
+	}
+
+	find := f.findFilter()
+	wantFind := bson.D{
+		{Key: "ns", Value: bson.Regex{Pattern: "^app\\.ord"}},
+		{Key: "op", Value: bson.M{"$in": []string{"u"}}},
+		{Key: "$and", Value: bson.A{
+			bson.M{"$or": bson.A{bson.M{"o._id": oid}, bson.M{"o2._id": oid}}},
+			bson.M{"$or": bson.A{
+				bson.M{"o.status": bson.M{"$eq": "paid"}},
+				bson.M{"o.diff.u.status": bson.M{"$eq": "paid"}},
+				bson.M{"o.diff.i.status": bson.M{"$eq": "paid"}},
+				bson.M{"o.$set.status": bson.M{"$eq": "paid"}},
+			}},
+		}},
+		{Key: "ts", Value: bson.M{"$gte": bson.Timestamp{T: 1767312000}}},
+	}
+	if !reflect.DeepEqual(find, wantFind) {
+		t.Fatalf("unexpected find filter\n got %v\nwant %v", find, wantFind)
+	}
+
+	match := f.streamMatch()
+	if match[0].Key != "$expr" || match[1].Key != "operationType" {
+		t.Fatalf("expected regex $expr and operationType first, got %v", match)
+	}
+	if got := match[1].Value.(bson.M)["$in"]; !reflect.DeepEqual(got, []string{"update", "replace"}) {
+		t.Fatalf("expected update and replace, got %v", got)
+	}
+	and := match[2].Value.(bson.A)
+	if !reflect.DeepEqual(and[0], bson.M{"documentKey._id": oid}) {
+		t.Fatalf("expected documentKey match, got %v", and[0])
+	}
+	if f.From != (bson.Timestamp{T: 1767312000}) {
+		t.Fatalf("expected --from to become the stream start time, got %v", f.From)
+	}
+
+	if _, err := newOplogFilter(oplogConfig{namespace: "app.a", regex: "a"}); !errors.Is(err, ErrNamespaceOrRegex) {
+		t.Fatalf("expected ErrNamespaceOrRegex, got %v", err)
+	}
+}
+
+func TestOplogFilterMatchesInsideTransactions(t *testing.T) {
+	f, err := newOplogFilter(oplogConfig{namespace: "app.orders", ops: "i", from: "2026-01-02"})
+	if err != nil {
+		t.Fatalf("newOplogFilter returned error: %v", err)
+	}
+
+	query := f.queryFilter()
+	or, _ := query[0].Value.(bson.A)
+	if query[0].Key != "$or" || len(or) != 2 || query[1].Key != "ts" {
+		t.Fatalf("expected the direct filter or applyOps, then the range; got %v", query)
+	}
+	if !reflect.DeepEqual(or[1], bson.D{{Key: "op", Value: "c"}, {Key: "o.applyOps", Value: bson.M{"$exists": true}}}) {
+		t.Fatalf("expected applyOps entries to be fetched, got %v", or[1])
+	}
+	if rangeOnly := (oplogFilter{From: f.From}).queryFilter(); len(rangeOnly) != 1 || rangeOnly[0].Key != "ts" {
+		t.Fatalf("expected only the range for an unfiltered query, got %v", rangeOnly)
+	}
+
+	n := int64(3)
+	txn := oplogEntry{
+		TS: bson.Timestamp{T: 1767312001}, Op: "c", NS: "admin.$cmd", TxnNumber: &n,
+		O: bson.M{"applyOps": bson.A{
+			bson.D{{Key: "op", Value: "i"}, {Key: "ns", Value: "app.orders"}, {Key: "o", Value: bson.D{{Key: "_id", Value: 1}}}},
+			bson.D{{Key: "op", Value: "i"}, {Key: "ns", Value: "app.carts"}, {Key: "o", Value: bson.D{{Key: "_id", Value: 2}}}},
+		}},
+	}
+	got := f.appendMatching(nil, txn)
+	if len(got) != 1 || got[0].NS != "app.orders" || got[0].TxnNumber == nil {
+		t.Fatalf("expected only the app.orders insert from the transaction, got %+v", got)
+	}
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"github.com/drewjocham/mongork/internal/jsonutil"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var ErrInvalidStartDocument = errors.New("invalid --start document (expected extended JSON)")
+
+const historyValueWidth = 40
+
+type oplogHistoryConfig struct {
+	output string
+	from   string
+	to     string
+	start  string
+	limit  int
+}
+
+func newOplogHistoryCmd() *cobra.Command {
+	cfg := oplogHistoryConfig{}
+	cmd := &cobra.Command{
+		Use:   "history <db.collection> <id>",
+		Short: "Show every version of one document with field-level diffs",
+		Long: `Reconstruct a document's versions from the oplog: its insert, every update and
+replacement (including writes inside transactions) and its delete, each with
+the fields that were added, changed or removed.
+
+The id is read as an ObjectID when it is 24 hex characters, as an integer when
+numeric, and as a string otherwise (quote it to force a string). If the insert
+has already rolled off the oplog, pass the document as it was at --from with
+--start to replay the later updates on top of it.`,
+		Example: `  mongo oplog history app.orders 652f1c2e9b1e8a0012345678
+  mongo oplog history app.users 42 --from 2026-10-01 -o json
+  mongo oplog history app.orders sku-1 --from 2026-10-01 --start '{"_id":"sku-1","qty":3}'`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil || s.MongoClient == nil {
+				return ErrMongoClientUnavailable
+			}
+			return runOplogHistory(cmd.Context(), cmd.OutOrStdout(), s.MongoClient, args[0], args[1], cfg)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&cfg.output, "output", "o", "table", "Output format (table, json)")
+	f.StringVar(&cfg.from, "from", "", "Start time (RFC3339 or YYYY-MM-DD)")
+	f.StringVar(&cfg.to, "to", "", "End time (RFC3339 or YYYY-MM-DD)")
+	f.StringVar(&cfg.start, "start", "", "Document as it was at --from, as extended JSON, when its insert is gone")
+	f.IntVar(&cfg.limit, "limit", 0, "Maximum versions to show (0 = all)")
+	return cmd
+}
+
+func runOplogHistory(
+	ctx context.Context,
+	w io.Writer,
+	client *mongo.Client,
+	ns, rawID string,
+	cfg oplogHistoryConfig,
+) error {
+	opts := cdc.HistoryOptions{Limit: cfg.limit}
+	for _, spec := range []struct {
+		val string
+		dst *bson.Timestamp
+	}{{cfg.from, &opts.From}, {cfg.to, &opts.To}} {
+		if spec.val == "" {
+			continue
+		}
+		ts, err := parseTime(spec.val)
+		if err != nil {
+			return err
+		}
+		*spec.dst = ts
+	}
+	if cfg.start != "" {
+		if err := bson.UnmarshalExtJSON([]byte(cfg.start), false, &opts.Start); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidStartDocument, err)
+		}
+	}
+
+	versions, err := cdc.DocumentHistory(ctx, client, ns, cdc.ParseDocumentID(rawID), opts)
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(cfg.output) == "json" {
+		return encodePrettyJSON(w, versions)
+	}
+	return renderHistoryTable(w, versions)
+}
+
+// renderHistoryTable prints one row per changed field, with the version's
+// time and operation on its first row only.
+func renderHistoryTable(w io.Writer, versions []cdc.DocumentVersion) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "#\tTIME\tOPERATION\tFIELD\tOLD\tNEW")
+	fmt.Fprintln(tw, "-\t----\t---------\t-----\t---\t---")
+	for i, v := range versions {
+		when := ""
+		if !v.Timestamp.IsZero() {
+			when = v.Timestamp.Format("2006-01-02 15:04:05")
+		}
+		if v.TxnNumber != nil {
+			when += fmt.Sprintf(" (txn %d)", *v.TxnNumber)
+		}
+		if len(v.Changes) == 0 {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t\t\t\n", i+1, when, v.Operation)
+			continue
+		}
+		for j, c := range v.Changes {
+			num, at, op := "", "", ""
+			if j == 0 {
+				num, at, op = fmt.Sprint(i+1), when, v.Operation
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				num, at, op, c.Path, historyValue(c.Kind != "added", c.Old), historyValue(c.Kind != "removed", c.New))
+		}
+	}
+	return tw.Flush()
+}
+
+// historyValue renders a field value as compact JSON, truncated to fit the
+// table; absent values show as a dash.
+func historyValue(present bool, v any) string {
+	if !present {
+		return "-"
+	}
+	out, err := jsonutil.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	s := string(out)
+	if len(s) > historyValueWidth {
+		s = s[:historyValueWidth-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+)
+
+func TestRenderHistoryTableOneRowPerField(t *testing.T) {
+	txn := int64(3)
+	versions := []cdc.DocumentVersion{
+		{
+			Timestamp: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC),
+			Operation: "insert",
+			Changes:   []cdc.FieldChange{{Path: "_id", Kind: "added", New: 1}, {Path: "status", Kind: "added", New: "new"}},
+		},
+		{
+			Timestamp: time.Date(2026, 10, 1, 10, 5, 0, 0, time.UTC),
+			Operation: "update",
+			TxnNumber: &txn,
+			Changes: []cdc.FieldChange{
+				{Path: "note", Kind: "changed", Old: strings.Repeat("x", 60), New: "short"},
+				{Path: "status", Kind: "removed", Old: "new"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := renderHistoryTable(&buf, versions); err != nil {
+		t.Fatalf("renderHistoryTable: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected header, dashes and 4 change rows, got %d:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[4], "(txn 3)") || !strings.Contains(lines[4], "...") {
+		t.Fatalf("expected transaction marker and truncated value, got %q", lines[4])
+	}
+	if fields := strings.Fields(lines[5]); len(fields) != 3 || fields[2] != "-" {
+		t.Fatalf("continuation row should only carry field, old and new: %q", lines[5])
+	}
+}
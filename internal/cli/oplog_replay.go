@@ -0,0 +1,505 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrReplaySource        = errors.New("use --from-file <path> or --source live")
+	ErrReplayTarget        = errors.New("set --target-db or at least one --map")
+	ErrReplaySameTarget    = errors.New("replaying a live source onto itself")
+	ErrInvalidNamespaceMap = errors.New("invalid --map (expected source=target, e.g. app=staging or app.x=staging.x)")
+	ErrReplayRecord        = errors.New("invalid replay record")
+)
+
+type oplogReplayConfig struct {
+	fromFile string
+	source   string
+	targetDB string
+	maps     []string
+	rate     float64
+	dryRun   bool
+	output   string
+	filter   oplogConfig
+}
+
+// replayOutcome is how a single event was (or would be) applied.
+type replayOutcome string
+
+const (
+	replayInserted replayOutcome = "insert"
+	replayUpdated  replayOutcome = "update"
+	replayReplaced replayOutcome = "replace"
+	replayDeleted  replayOutcome = "delete"
+	replaySkipped  replayOutcome = "skipped"
+)
+
+type replayCount struct {
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	Insert  int    `json:"insert"`
+	Update  int    `json:"update"`
+	Replace int    `json:"replace"`
+	Delete  int    `json:"delete"`
+	Skipped int    `json:"skipped"`
+}
+
+func (c *replayCount) add(o replayOutcome) {
+	switch o {
+	case replayInserted:
+		c.Insert++
+	case replayUpdated:
+		c.Update++
+	case replayReplaced:
+		c.Replace++
+	case replayDeleted:
+		c.Delete++
+	default:
+		c.Skipped++
+	}
+}
+
+func newOplogReplayCmd() *cobra.Command {
+	cfg := oplogReplayConfig{}
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-apply captured inserts, updates and deletes into another database",
+		Long: `Replay events captured by 'mongo oplog' (NDJSON from a file sink or -o json output)
+or read from the live oplog into another database or namespace.
+
+Every write is idempotent: inserts and full documents are upserted by _id,
+updates re-apply $set/$unset and deletes remove by _id, so replaying the same
+file twice leaves the target unchanged. DDL and command events are skipped.`,
+		Example: `  mongo oplog replay --from-file events.ndjson --target-db staging --dry-run
+  mongo oplog replay --source live --from 2026-10-01T10:00:00Z --to 2026-10-01T10:05:00Z \
+    --namespace app.orders --map app.orders=incident.orders --rate 200`,
+		Annotations: map[string]string{annotationOfflineFlags: "from-file,dry-run"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if cfg.fromFile != "" && cfg.dryRun {
+				// A dry run over a file only counts events; no connection is needed.
+				return runOplogReplay(cmd.Context(), cmd.OutOrStdout(), nil, cfg)
+			}
+			s, err := getServices(cmd.Context())
+			if err != nil || s.MongoClient == nil {
+				return ErrMongoClientUnavailable
+			}
+			return runOplogReplay(cmd.Context(), cmd.OutOrStdout(), s.MongoClient, cfg)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&cfg.fromFile, "from-file", "", "NDJSON or JSON file written by 'mongo oplog' (- for stdin)")
+	f.StringVar(&cfg.source, "source", "", "Set to 'live' to read the events from the oplog instead of a file")
+	f.StringVar(&cfg.targetDB, "target-db", "", "Database to write into (keeps collection names)")
+	f.StringArrayVar(&cfg.maps, "map", nil, "Namespace mapping source=target, database or db.collection (repeatable)")
+	f.Float64Var(&cfg.rate, "rate", 0, "Maximum writes per second (0 = unlimited)")
+	f.BoolVar(&cfg.dryRun, "dry-run", false, "Only count what would be applied per namespace")
+	f.StringVarP(&cfg.output, "output", "o", "table", "Output format (table, json)")
+	f.StringVar(&cfg.filter.namespace, "namespace", "", "Only replay this namespace (db.collection)")
+	f.StringVar(&cfg.filter.regex, "regex", "", "Only replay namespaces matching this regex")
+	f.StringVar(&cfg.filter.ops, "ops", "i,u,d", "Op codes/names to replay")
+	f.StringVar(&cfg.filter.from, "from", "", "Live source: start time (RFC3339 or YYYY-MM-DD)")
+	f.StringVar(&cfg.filter.to, "to", "", "Live source: end time (RFC3339 or YYYY-MM-DD)")
+	f.Int64Var(&cfg.filter.limit, "limit", 0, "Live source: maximum events to read (0 = no limit)")
+	return cmd
+}
+
+func runOplogReplay(ctx context.Context, w io.Writer, client *mongo.Client, cfg oplogReplayConfig) error {
+	if (cfg.fromFile == "") == (cfg.source != "live") {
+		return ErrReplaySource
+	}
+	mapper, err := newNamespaceMapper(cfg.targetDB, cfg.maps)
+	if err != nil {
+		return err
+	}
+	filter, err := newOplogFilter(cfg.filter)
+	if err != nil {
+		return err
+	}
+
+	var events []oplogOutput
+	if cfg.fromFile != "" {
+		events, err = readReplayFile(cfg.fromFile)
+	} else {
+		events, err = readLiveEvents(ctx, client, filter, cfg.filter.limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]*replayCount{}
+	limiter := newRateLimiter(cfg.rate)
+	defer limiter.Stop()
+	for _, ev := range events {
+		if !filter.matchesOutput(ev) {
+			continue
+		}
+		target, mapped := mapper.target(ev.Namespace)
+		if mapped && cfg.source == "live" && target == ev.Namespace {
+			return fmt.Errorf("%w: %s", ErrReplaySameTarget, ev.Namespace)
+		}
+		c, ok := counts[ev.Namespace]
+		if !ok {
+			c = &replayCount{Source: ev.Namespace, Target: target}
+			counts[ev.Namespace] = c
+		}
+
+		if !mapped {
+			c.add(replaySkipped)
+			continue
+		}
+		if cfg.dryRun {
+			c.add(replayPlan(ev))
+			continue
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+		outcome, err := applyReplayEvent(ctx, client, target, ev)
+		if err != nil {
+			return fmt.Errorf("replay %s %s %s: %w", ev.Operation, ev.Namespace, ev.ObjectID, err)
+		}
+		c.add(outcome)
+	}
+	return renderReplayCounts(w, cfg.output, cfg.dryRun, counts)
+}
+
+// namespaceMapper rewrites source namespaces: an exact db.collection mapping
+// wins over a database mapping, which wins over --target-db. Events in
+// namespaces nothing maps are skipped rather than written back in place.
+type namespaceMapper struct {
+	targetDB    string
+	collections map[string]string
+	databases   map[string]string
+}
+
+func newNamespaceMapper(targetDB string, maps []string) (namespaceMapper, error) {
+	m := namespaceMapper{targetDB: targetDB, collections: map[string]string{}, databases: map[string]string{}}
+	for _, raw := range maps {
+		from, to, ok := strings.Cut(raw, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" || strings.Contains(from, ".") != strings.Contains(to, ".") {
+			return m, fmt.Errorf("%w: %s", ErrInvalidNamespaceMap, raw)
+		}
+		if strings.Contains(from, ".") {
+			m.collections[from] = to
+		} else {
+			m.databases[from] = to
+		}
+	}
+	if targetDB == "" && len(maps) == 0 {
+		return m, ErrReplayTarget
+	}
+	return m, nil
+}
+
+func (m namespaceMapper) target(ns string) (string, bool) {
+	if to, ok := m.collections[ns]; ok {
+		return to, true
+	}
+	db, coll, _ := strings.Cut(ns, ".")
+	if to, ok := m.databases[db]; ok {
+		return to + "." + coll, true
+	}
+	if m.targetDB != "" {
+		return m.targetDB + "." + coll, true
+	}
+	return "", false
+}
+
+// readReplayFile accepts NDJSON (one event per line, as written by the file
+// sink) and the JSON arrays printed by `mongo oplog -o json`. Records are
+// decoded as Extended JSON: the file sink's canonical form restores every BSON
+// type, while plain JSON from -o json keeps only what JSON can express, so
+// its dates and ObjectIDs stay strings.
+func readReplayFile(path string) ([]oplogOutput, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var events []oplogOutput
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			return events, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrReplayRecord, err)
+		}
+		if raw[0] != '[' {
+			var ev oplogOutput
+			if err := bson.UnmarshalExtJSON(raw, false, &ev); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrReplayRecord, err)
+			}
+			events = append(events, ev)
+			continue
+		}
+		// Extended JSON documents cannot be arrays, so wrap the batch.
+		var batch struct {
+			Events []oplogOutput `bson:"events"`
+		}
+		wrapped := append(append([]byte(`{"events":`), raw...), '}')
+		if err := bson.UnmarshalExtJSON(wrapped, false, &batch); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrReplayRecord, err)
+		}
+		events = append(events, batch.Events...)
+	}
+}
+
+// readLiveEvents reads the oplog oldest first, expanding transactions before
+// the filter is applied so writes inside them are replayed too. --limit
+// counts matching events.
+func readLiveEvents(ctx context.Context, client *mongo.Client, filter oplogFilter, limit int64) ([]oplogOutput, error) {
+	coll, err := cdc.OplogCollection(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := coll.Find(ctx, filter.queryFilter(), options.Find().SetSort(bson.D{{Key: "$natural", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailedToQueryOplog, err)
+	}
+	defer cur.Close(ctx)
+	var entries []oplogEntry
+	for cur.Next(ctx) && (limit <= 0 || int64(len(entries)) < limit) {
+		var e oplogEntry
+		if err := cur.Decode(&e); err != nil {
+			return nil, err
+		}
+		entries = filter.appendMatching(entries, e)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(entries)) > limit {
+		entries = entries[:limit]
+	}
+	out := make([]oplogOutput, len(entries))
+	for i := range entries {
+		out[i] = entries[i].ToOutput()
+	}
+	return out, nil
+}
+
+// matchesOutput applies the namespace and op parts of the filter to events
+// read from a file; live reads are already filtered by matchesEntry.
+func (f oplogFilter) matchesOutput(ev oplogOutput) bool {
+	if f.Namespace != "" && ev.Namespace != f.Namespace {
+		return false
+	}
+	if f.Regex != "" && !f.regexp().MatchString(ev.Namespace) {
+		return false
+	}
+	if len(f.Ops) > 0 {
+		code := opFromType(ev.Operation)
+		for _, op := range f.Ops {
+			if op == code {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// replayPlan decides how an event would be applied without touching the target.
+func replayPlan(ev oplogOutput) replayOutcome {
+	switch ev.Operation {
+	case "insert":
+		if ev.Data != nil {
+			return replayInserted
+		}
+	case "replace":
+		if ev.Data != nil {
+			return replayReplaced
+		}
+	case "update":
+		if ev.Update != nil || isFullDocument(ev) {
+			return replayUpdated
+		}
+	case "delete":
+		return replayDeleted
+	}
+	return replaySkipped
+}
+
+func applyReplayEvent(ctx context.Context, client *mongo.Client, target string, ev oplogOutput) (replayOutcome, error) {
+	outcome := replayPlan(ev)
+	if outcome == replaySkipped {
+		return outcome, nil
+	}
+	db, collName, ok := strings.Cut(target, ".")
+	if !ok {
+		return replaySkipped, fmt.Errorf("%w: %s", ErrInvalidNamespace, target)
+	}
+	coll := client.Database(db).Collection(collName)
+	id := replayDocumentID(ev)
+	if id == nil {
+		return replaySkipped, nil
+	}
+	byID := bson.M{"_id": id}
+
+	switch {
+	case outcome == replayDeleted:
+		_, err := coll.DeleteOne(ctx, byID)
+		return outcome, err
+	case isFullDocument(ev):
+		doc := bson.M{}
+		for k, v := range ev.Data {
+			doc[k] = v
+		}
+		doc["_id"] = id
+		_, err := coll.ReplaceOne(ctx, byID, doc, options.Replace().SetUpsert(true))
+		return outcome, err
+	default:
+		update := bson.M{}
+		if len(ev.Update.UpdatedFields) > 0 {
+			update["$set"] = ev.Update.UpdatedFields
+		}
+		if len(ev.Update.RemovedFields) > 0 {
+			unset := bson.M{}
+			for _, f := range ev.Update.RemovedFields {
+				unset[f] = ""
+			}
+			update["$unset"] = unset
+		}
+		// An update whose document is not in the target changed nothing.
+		if len(update) > 0 {
+			res, err := coll.UpdateOne(ctx, byID, update)
+			if err != nil {
+				return outcome, err
+			}
+			if res.MatchedCount == 0 {
+				return replaySkipped, nil
+			}
+		}
+		for _, t := range ev.Update.TruncatedArrays {
+			truncate := bson.M{"$push": bson.M{t.Field: bson.M{"$each": bson.A{}, "$slice": t.NewSize}}}
+			res, err := coll.UpdateOne(ctx, byID, truncate)
+			if err != nil {
+				return outcome, err
+			}
+			if res.MatchedCount == 0 {
+				return replaySkipped, nil
+			}
+		}
+		return outcome, nil
+	}
+}
+
+// isFullDocument reports whether data holds a whole document (an insert,
+// replacement or --full-document post-image) rather than a raw oplog diff.
+func isFullDocument(ev oplogOutput) bool {
+	return ev.Data != nil && !cdc.IsOperatorUpdate(ev.Data)
+}
+
+var objectIDString = regexp.MustCompile(`^ObjectID\("([0-9a-f]{24})"\)$`)
+
+// replayDocumentID recovers the _id from document_key. Records written
+// without one fall back to the event data (inserts, replacements and
+// deletes), then to object_id, which holds the ObjectID("…") or plain value
+// printed by `oplog` and so cannot restore UUID or compound ids.
+func replayDocumentID(ev oplogOutput) any {
+	if id, ok := ev.DocumentKey["_id"]; ok {
+		return id
+	}
+	if id, ok := ev.Data["_id"]; ok {
+		return id
+	}
+	raw := ev.ObjectID
+	if raw == "" || raw == "N/A" {
+		return nil
+	}
+	if m := objectIDString.FindStringSubmatch(raw); m != nil {
+		oid, _ := bson.ObjectIDFromHex(m[1])
+		return oid
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return n
+	}
+	return raw
+}
+
+// rateLimiter spaces writes evenly; a zero rate never waits.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / perSecond))}
+}
+
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.ticker == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.ticker.C:
+		return nil
+	}
+}
+
+func (l *rateLimiter) Stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
+
+func renderReplayCounts(w io.Writer, format string, dryRun bool, counts map[string]*replayCount) error {
+	rows := make([]replayCount, 0, len(counts))
+	for _, c := range counts {
+		rows = append(rows, *c)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Source < rows[j].Source })
+
+	if strings.ToLower(format) == "json" {
+		return encodePrettyJSON(w, struct {
+			DryRun     bool          `json:"dry_run"`
+			Namespaces []replayCount `json:"namespaces"`
+		}{dryRun, rows})
+	}
+
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "No events to replay.")
+		return nil
+	}
+	if dryRun {
+		fmt.Fprintln(w, "Dry run: nothing was written.")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tTARGET\tINSERT\tUPDATE\tREPLACE\tDELETE\tSKIPPED")
+	fmt.Fprintln(tw, "------\t------\t------\t------\t-------\t------\t-------")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\n",
+			r.Source, r.Target, r.Insert, r.Update, r.Replace, r.Delete, r.Skipped)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const replayFixture = `{"timestamp":"2026-10-01T10:00:00Z","operation":"insert","namespace":"app.orders","object_id":"ObjectID(\"652f1c9e8b3e4a0001a1b2c3\")","data":{"_id":"652f1c9e8b3e4a0001a1b2c3","qty":2}}
+{"timestamp":"2026-10-01T10:00:01Z","operation":"update","namespace":"app.orders","object_id":"ObjectID(\"652f1c9e8b3e4a0001a1b2c3\")","update":{"updated_fields":{"status":"paid"}}}
+[{"timestamp":"2026-10-01T10:00:02Z","operation":"delete","namespace":"app.carts","object_id":"42"},
+ {"timestamp":"2026-10-01T10:00:03Z","operation":"createIndexes","namespace":"app.orders","object_id":"N/A"}]
+{"timestamp":"2026-10-01T10:00:04Z","operation":"insert","namespace":"audit.log","object_id":"7","data":{"_id":7}}
+`
+
+func TestReadReplayFileMixesNDJSONAndArrays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	if err := os.WriteFile(path, []byte(replayFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	events, err := readReplayFile(path)
+	if err != nil {
+		t.Fatalf("readReplayFile returned error: %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %d", len(events))
+	}
+	// Plain JSON carries no BSON types: the _id stays the string it was.
+	if id := replayDocumentID(events[0]); id != "652f1c9e8b3e4a0001a1b2c3" {
+		t.Fatalf("expected the string _id from data, got %#v", id)
+	}
+	oid, _ := bson.ObjectIDFromHex("652f1c9e8b3e4a0001a1b2c3")
+	if id := replayDocumentID(events[1]); id != oid {
+		t.Fatalf("expected ObjectID parsed from object_id, got %v", id)
+	}
+	if id := replayDocumentID(events[2]); id != int64(42) {
+		t.Fatalf("expected numeric id, got %#v", id)
+	}
+}
+
+func TestReplayFileRestoresFileSinkTypes(t *testing.T) {
+	oid, _ := bson.ObjectIDFromHex("652f1c9e8b3e4a0001a1b2c3")
+	ref, _ := bson.ObjectIDFromHex("652f1c9e8b3e4a0001a1b2c4")
+	created := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	ev := oplogOutput{
+		Timestamp: created,
+		Operation: "insert",
+		Namespace: "app.orders",
+		ObjectID:  fmt.Sprintf("%v", oid),
+		Data: bson.M{
+			"_id":      oid,
+			"price":    2.0,
+			"qty":      int64(3),
+			"created":  created,
+			"customer": bson.D{{Key: "ref", Value: ref}},
+		},
+	}
+	line, err := sinkEncoder("file:events.ndjson")(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	if err := os.WriteFile(path, append(line, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := readReplayFile(path)
+	if err != nil {
+		t.Fatalf("readReplayFile returned error: %v", err)
+	}
+	data := events[0].Data
+	if data["_id"] != oid || data["price"] != 2.0 || data["qty"] != int64(3) {
+		t.Fatalf("expected exact _id, double and int64, got %#v", data)
+	}
+	if got, ok := data["created"].(bson.DateTime); !ok || got.Time().UTC() != created {
+		t.Fatalf("expected a date, got %#v", data["created"])
+	}
+	if customer, ok := data["customer"].(bson.D); !ok || customer[0].Value != ref {
+		t.Fatalf("expected the nested ObjectID, got %#v", data["customer"])
+	}
+	if !events[0].Timestamp.Equal(created) {
+		t.Fatalf("unexpected timestamp %v", events[0].Timestamp)
+	}
+}
+
+func TestNamespaceMapperPrecedence(t *testing.T) {
+	m, err := newNamespaceMapper("staging", []string{"app.orders=incident.orders", "audit=audit_copy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ns, want := range map[string]string{
+		"app.orders": "incident.orders",
+		"app.carts":  "staging.carts",
+		"audit.log":  "audit_copy.log",
+	} {
+		if got, _ := m.target(ns); got != want {
+			t.Errorf("target(%q) = %q, want %q", ns, got, want)
+		}
+	}
+	if _, err := newNamespaceMapper("", []string{"app=staging.orders"}); !errors.Is(err, ErrInvalidNamespaceMap) {
+		t.Fatalf("expected ErrInvalidNamespaceMap, got %v", err)
+	}
+	if _, err := newNamespaceMapper("", nil); !errors.Is(err, ErrReplayTarget) {
+		t.Fatalf("expected ErrReplayTarget, got %v", err)
+	}
+}
+
+func TestReplayDryRunCountsPerNamespace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	if err := os.WriteFile(path, []byte(replayFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	cfg := oplogReplayConfig{
+		fromFile: path,
+		maps:     []string{"app=staging"},
+		dryRun:   true,
+		output:   "table",
+	}
+	if err := runOplogReplay(context.Background(), &out, nil, cfg); err != nil {
+		t.Fatalf("runOplogReplay returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"app.carts    staging.carts    0        0        0         1        0",
+		"app.orders   staging.orders   1        1        0         0        1",
+		"audit.log                     0        0        0         0        1",
+	}
+	if len(lines) != 6 {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	for i, w := range want {
+		if strings.Join(strings.Fields(lines[3+i]), " ") != strings.Join(strings.Fields(w), " ") {
+			t.Errorf("row %d = %q, want %q", i, lines[3+i], w)
+		}
+	}
+}
+
+func TestReplayUsesTypedDocumentKey(t *testing.T) {
+	uuid := bson.Binary{Subtype: 4, Data: []byte("0123456789abcdef")}
+	compound := bson.D{{Key: "tenant", Value: "acme"}, {Key: "n", Value: int32(7)}}
+	entries := []oplogEntry{
+		{Op: "u", NS: "app.a", O: bson.M{"$v": 2, "diff": bson.M{"u": bson.M{"x": 1}}}, O2: bson.M{"_id": uuid}},
+		{Op: "u", NS: "app.b", O: bson.M{"$v": 2, "diff": bson.M{"u": bson.M{"x": 1}}}, O2: bson.M{"_id": compound}},
+		{Op: "d", NS: "app.c", O: bson.M{"_id": "123"}},
+	}
+
+	var lines []byte
+	for i := range entries {
+		line, err := sinkEncoder("file:events.ndjson")(entries[i].ToOutput())
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	if err := os.WriteFile(path, lines, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	events, err := readReplayFile(path)
+	if err != nil {
+		t.Fatalf("readReplayFile returned error: %v", err)
+	}
+
+	if id := replayDocumentID(events[0]); !reflect.DeepEqual(id, uuid) {
+		t.Fatalf("expected the UUID _id, got %#v", id)
+	}
+	if id := replayDocumentID(events[1]); !reflect.DeepEqual(id, compound) {
+		t.Fatalf("expected the compound _id, got %#v", id)
+	}
+	if id := replayDocumentID(events[2]); id != "123" {
+		t.Fatalf("expected the string _id \"123\", got %#v", id)
+	}
+}
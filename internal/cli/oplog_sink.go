@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"github.com/drewjocham/mongork/internal/jsonutil"
+	"github.com/spf13/pflag"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type oplogSinkConfig struct {
+	spec string
+	opts cdc.Options
+}
+
+func addSinkFlags(f *pflag.FlagSet, cfg *oplogSinkConfig) {
+	f.StringVar(&cfg.spec, "sink", "",
+		"Forward events to file:<path>, http(s)://<webhook> or nats://<host>/<subject> instead of stdout")
+	f.IntVar(&cfg.opts.BatchSize, "sink-batch", 100, "Maximum events per sink delivery")
+	f.DurationVar(&cfg.opts.FlushInterval, "sink-flush", 0, "Deliver a partial batch after this long (default 1s)")
+	f.IntVar(&cfg.opts.Retries, "sink-retries", 0, "Delivery retries with exponential backoff (default 5, -1 disables)")
+	f.Int64Var(&cfg.opts.RotateBytes, "sink-rotate-size", 0, "Rotate the file sink after this many bytes")
+	f.DurationVar(&cfg.opts.RotateEvery, "sink-rotate-every", 0, "Rotate the file sink after this long")
+}
+
+// sinkEncoder picks how events are serialized. The file sink writes canonical
+// Extended JSON so 'mongo oplog replay' restores dates, ObjectIDs and number
+// types exactly; network sinks get the same plain JSON as -o json.
+func sinkEncoder(spec string) func(oplogOutput) ([]byte, error) {
+	if strings.HasPrefix(spec, "file:") {
+		return func(out oplogOutput) ([]byte, error) {
+			return bson.MarshalExtJSON(out, true, false)
+		}
+	}
+	return func(out oplogOutput) ([]byte, error) {
+		return jsonutil.Marshal(out)
+	}
+}
+
+// sinkDelivery returns a deliver func that encodes entries as output documents
+// and hands them to sink; it returns only once the sink acknowledged.
+func sinkDelivery(
+	ctx context.Context, sink cdc.Sink, encode func(oplogOutput) ([]byte, error), output func(oplogEntry) oplogOutput,
+) func([]oplogEntry) error {
+	return func(entries []oplogEntry) error {
+		records := make([]cdc.Record, 0, len(entries))
+		for _, e := range entries {
+			out := output(e)
+			value, err := encode(out)
+			if err != nil {
+				return err
+			}
+			records = append(records, cdc.Record{Namespace: out.Namespace, Key: out.ObjectID, Value: value})
+		}
+		if err := sink.Write(ctx, records); err != nil {
+			return fmt.Errorf("%w: %w", ErrSinkDelivery, err)
+		}
+		return nil
+	}
+}
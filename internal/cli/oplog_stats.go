@@ -0,0 +1,475 @@
+package cli
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type oplogStatsConfig struct {
+	output string
+	top    int
+	filter oplogConfig
+}
+
+// namespaceStat is the oplog volume one namespace produced in the range.
+// WindowShare is its percentage of all bytes written to the oplog in the
+// range, filtered or not, which is the share of the oplog window it consumes.
+type namespaceStat struct {
+	Namespace      string           `json:"namespace"`
+	Ops            map[string]int64 `json:"ops"`
+	Count          int64            `json:"count"`
+	Bytes          int64            `json:"bytes"`
+	OpsPerSec      float64          `json:"ops_per_sec"`
+	BytesPerSec    float64          `json:"bytes_per_sec"`
+	WindowShare    float64          `json:"window_share_pct"`
+	WindowConsumed string           `json:"window_consumed,omitempty"`
+}
+
+type hotDocument struct {
+	Namespace string `json:"namespace"`
+	ID        string `json:"id"`
+	Updates   int64  `json:"updates"`
+	Bytes     int64  `json:"bytes"`
+}
+
+type hotField struct {
+	Namespace string `json:"namespace"`
+	Field     string `json:"field"`
+	Updates   int64  `json:"updates"`
+}
+
+// oplogStatsReport covers the entries matching the filter; OplogBytes and
+// OplogBytesPerSec count every entry in the range and drive the window
+// figures, since the window is shared by all writes.
+type oplogStatsReport struct {
+	From             time.Time       `json:"from"`
+	To               time.Time       `json:"to"`
+	Seconds          float64         `json:"seconds"`
+	Entries          int64           `json:"entries"`
+	Bytes            int64           `json:"bytes"`
+	OpsPerSec        float64         `json:"ops_per_sec"`
+	BytesPerSec      float64         `json:"bytes_per_sec"`
+	OplogBytes       int64           `json:"oplog_bytes"`
+	OplogBytesPerSec float64         `json:"oplog_bytes_per_sec"`
+	OplogMaxBytes    int64           `json:"oplog_max_bytes,omitempty"`
+	ProjectedWindow  string          `json:"projected_window,omitempty"`
+	Namespaces       []namespaceStat `json:"namespaces"`
+	TopDocuments     []hotDocument   `json:"top_documents"`
+	TopFields        []hotField      `json:"top_fields"`
+}
+
+func newOplogStatsCmd() *cobra.Command {
+	cfg := oplogStatsConfig{}
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Report oplog volume, hot documents and window consumption per namespace",
+		Long: `Aggregate the oplog between --from and --to by namespace and operation.
+Without --from the last hour is read.
+
+For every namespace the report shows the operation mix, ops/sec, bytes written
+and the share of the oplog window those bytes consume; it also lists the most
+updated documents and fields. Transactions are counted per inner operation.
+Window figures are relative to every write in the range, not only the
+filtered ones. Hot documents and fields are tracked in a bounded table, so
+their counts are approximate when the range updates many distinct ones.
+Use it to find what is shrinking the oplog window reported by 'mongo db health'.`,
+		Example: `  mongo oplog stats --from 2026-10-01T10:00:00Z --to 2026-10-01T11:00:00Z
+  mongo oplog stats --regex '^app\.' --top 20 -o json`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil || s.MongoClient == nil {
+				return ErrMongoClientUnavailable
+			}
+			return runOplogStats(cmd.Context(), cmd.OutOrStdout(), s.MongoClient, cfg)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&cfg.output, "output", "o", "table", "Output format (table, json)")
+	f.IntVar(&cfg.top, "top", 10, "Number of hot documents and fields to list")
+	f.StringVar(&cfg.filter.from, "from", "", "Start time (RFC3339 or YYYY-MM-DD; default one hour ago)")
+	f.StringVar(&cfg.filter.to, "to", "", "End time (RFC3339 or YYYY-MM-DD)")
+	f.StringVar(&cfg.filter.namespace, "namespace", "", "Only count this namespace (db.collection)")
+	f.StringVar(&cfg.filter.regex, "regex", "", "Only count namespaces matching this regex")
+	f.StringVar(&cfg.filter.ops, "ops", "", "Only count these op codes/names")
+	return cmd
+}
+
+// defaultStatsWindow is how far back stats look when --from is not set, so a
+// bare `oplog stats` never reads the whole oplog.
+const defaultStatsWindow = time.Hour
+
+// oplogStatsProjection keeps only the fields the collector reads, plus the
+// size of the full entry.
+var oplogStatsProjection = bson.D{
+	{Key: "ts", Value: 1}, {Key: "op", Value: 1}, {Key: "ns", Value: 1}, {Key: "wall", Value: 1},
+	{Key: "o", Value: 1}, {Key: "o2", Value: 1}, {Key: "txnNumber", Value: 1}, {Key: "lsid", Value: 1},
+	{Key: "size", Value: bson.M{"$bsonSize": "$$ROOT"}},
+}
+
+// entrySize reads the projected size, or the length of the raw entry when
+// the server did not compute one.
+func entrySize(raw bson.Raw) int {
+	if n, ok := raw.Lookup("size").AsInt64OK(); ok {
+		return int(n)
+	}
+	return len(raw)
+}
+
+func runOplogStats(ctx context.Context, w io.Writer, client *mongo.Client, cfg oplogStatsConfig) error {
+	if cfg.filter.from == "" {
+		cfg.filter.from = time.Now().Add(-defaultStatsWindow).UTC().Format(time.RFC3339)
+	}
+	filter, err := newOplogFilter(cfg.filter)
+	if err != nil {
+		return err
+	}
+	coll, err := cdc.OplogCollection(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	// Only the time range is pushed to the server: transactions are logged
+	// under admin.$cmd, so namespaces and ops are matched after expansion.
+	rangeOnly := oplogFilter{From: filter.From, To: filter.To}
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "$natural", Value: 1}}).
+		SetProjection(oplogStatsProjection)
+	cur, err := coll.Find(ctx, rangeOnly.findFilter(), findOpts)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFailedToQueryOplog, err)
+	}
+	defer cur.Close(ctx)
+
+	stats := newOplogStatsCollector(filter)
+	for cur.Next(ctx) {
+		var e oplogEntry
+		if err := cur.Decode(&e); err != nil {
+			return err
+		}
+		stats.add(e, entrySize(cur.Current))
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	report := stats.report(cfg.top, oplogMaxBytes(ctx, coll))
+	if strings.ToLower(cfg.output) == "json" {
+		return encodePrettyJSON(w, report)
+	}
+	return renderOplogStats(w, report)
+}
+
+// oplogMaxBytes reads the configured oplog size; it is 0 when collStats is
+// not permitted, and the projected window is then left out.
+func oplogMaxBytes(ctx context.Context, coll *mongo.Collection) int64 {
+	var stats bson.M
+	cmd := bson.D{{Key: "collStats", Value: coll.Name()}}
+	if err := coll.Database().RunCommand(ctx, cmd).Decode(&stats); err != nil {
+		return 0
+	}
+	size, _ := cdc.Number(stats["maxSize"])
+	return int64(size)
+}
+
+// hotKeyCapacity bounds how many documents and fields the collector tracks.
+const hotKeyCapacity = 10000
+
+type docKey struct{ ns, id string }
+
+type oplogStatsCollector struct {
+	filter      oplogFilter
+	first, last bson.Timestamp
+	entries     int64
+	bytes       int64
+	oplogBytes  int64
+	namespaces  map[string]*namespaceStat
+	documents   *topCounter
+	fields      *topCounter
+}
+
+func newOplogStatsCollector(filter oplogFilter) *oplogStatsCollector {
+	return &oplogStatsCollector{
+		filter:     filter,
+		namespaces: map[string]*namespaceStat{},
+		documents:  newTopCounter(hotKeyCapacity),
+		fields:     newTopCounter(hotKeyCapacity),
+	}
+}
+
+// add counts one oplog entry of size bytes. The range and oplog byte totals
+// include entries the filter rejects. A transaction's applyOps entry is split
+// into its operations, which share its size in proportion to their encoding.
+func (c *oplogStatsCollector) add(e oplogEntry, size int) {
+	if c.first.IsZero() || e.TS.Before(c.first) {
+		c.first = e.TS
+	}
+	if e.TS.After(c.last) {
+		c.last = e.TS
+	}
+	c.oplogBytes += int64(size)
+	if _, ok := e.O["applyOps"].(bson.A); e.Op == "c" && ok {
+		inner := expandTransactions([]oplogEntry{e})
+		sizes := make([]int, len(inner))
+		total := 0
+		for i, op := range inner {
+			if raw, err := bson.Marshal(op); err == nil {
+				sizes[i] = len(raw)
+				total += len(raw)
+			}
+		}
+		for i, op := range inner {
+			if total > 0 {
+				c.count(op, size*sizes[i]/total)
+			}
+		}
+		return
+	}
+	c.count(e, size)
+}
+
+func (c *oplogStatsCollector) count(e oplogEntry, size int) {
+	out := e.ToOutput()
+	if !c.filter.matchesOutput(out) {
+		return
+	}
+	c.entries++
+	c.bytes += int64(size)
+
+	ns := out.Namespace
+	if ns == "" {
+		ns = "(noop)"
+	}
+	s, ok := c.namespaces[ns]
+	if !ok {
+		s = &namespaceStat{Namespace: ns, Ops: map[string]int64{}}
+		c.namespaces[ns] = s
+	}
+	s.Ops[out.Operation]++
+	s.Count++
+	s.Bytes += int64(size)
+
+	if out.Operation != "update" && out.Operation != "replace" {
+		return
+	}
+	c.documents.add(docKey{ns, out.ObjectID}, int64(size))
+
+	if out.Update == nil {
+		return
+	}
+	changed := append(sortedKeys(out.Update.UpdatedFields), out.Update.RemovedFields...)
+	for _, field := range changed {
+		c.fields.add(docKey{ns, field}, 0)
+	}
+}
+
+func (c *oplogStatsCollector) report(top int, maxBytes int64) oplogStatsReport {
+	r := oplogStatsReport{Entries: c.entries, Bytes: c.bytes, OplogBytes: c.oplogBytes, OplogMaxBytes: maxBytes}
+	if !c.first.IsZero() {
+		r.From = time.Unix(int64(c.first.T), 0).UTC()
+		r.To = time.Unix(int64(c.last.T), 0).UTC()
+	}
+	// A range inside one second still has a rate.
+	r.Seconds = max(r.To.Sub(r.From).Seconds(), 1)
+	r.OpsPerSec = float64(c.entries) / r.Seconds
+	r.BytesPerSec = float64(c.bytes) / r.Seconds
+	r.OplogBytesPerSec = float64(c.oplogBytes) / r.Seconds
+
+	var window time.Duration
+	if maxBytes > 0 && r.OplogBytesPerSec > 0 {
+		window = time.Duration(float64(maxBytes) / r.OplogBytesPerSec * float64(time.Second)).Round(time.Second)
+		r.ProjectedWindow = window.String()
+	}
+
+	for _, s := range c.namespaces {
+		s.OpsPerSec = float64(s.Count) / r.Seconds
+		s.BytesPerSec = float64(s.Bytes) / r.Seconds
+		if c.oplogBytes > 0 {
+			s.WindowShare = float64(s.Bytes) / float64(c.oplogBytes) * 100
+		}
+		if window > 0 {
+			s.WindowConsumed = time.Duration(float64(window) * s.WindowShare / 100).Round(time.Second).String()
+		}
+		r.Namespaces = append(r.Namespaces, *s)
+	}
+	sort.Slice(r.Namespaces, func(i, j int) bool {
+		if r.Namespaces[i].Bytes != r.Namespaces[j].Bytes {
+			return r.Namespaces[i].Bytes > r.Namespaces[j].Bytes
+		}
+		return r.Namespaces[i].Namespace < r.Namespaces[j].Namespace
+	})
+
+	r.TopDocuments = make([]hotDocument, 0, len(c.documents.items))
+	for _, d := range c.documents.items {
+		r.TopDocuments = append(r.TopDocuments,
+			hotDocument{Namespace: d.key.ns, ID: d.key.id, Updates: d.count, Bytes: d.bytes})
+	}
+	sort.Slice(r.TopDocuments, func(i, j int) bool {
+		a, b := r.TopDocuments[i], r.TopDocuments[j]
+		if a.Updates != b.Updates {
+			return a.Updates > b.Updates
+		}
+		return a.Namespace+a.ID < b.Namespace+b.ID
+	})
+	r.TopFields = make([]hotField, 0, len(c.fields.items))
+	for _, f := range c.fields.items {
+		r.TopFields = append(r.TopFields, hotField{Namespace: f.key.ns, Field: f.key.id, Updates: f.count})
+	}
+	sort.Slice(r.TopFields, func(i, j int) bool {
+		a, b := r.TopFields[i], r.TopFields[j]
+		if a.Updates != b.Updates {
+			return a.Updates > b.Updates
+		}
+		return a.Namespace+a.Field < b.Namespace+b.Field
+	})
+	if top > 0 {
+		r.TopDocuments = r.TopDocuments[:min(top, len(r.TopDocuments))]
+		r.TopFields = r.TopFields[:min(top, len(r.TopFields))]
+	}
+	return r
+}
+
+// topCounter keeps approximate update counts for at most capacity keys
+// (the Space-Saving algorithm): when full, a new key replaces the least
+// counted one and inherits its count, so heavy hitters are never lost and
+// counts are overestimated by at most the evicted count.
+type topCounter struct {
+	capacity int
+	index    map[docKey]*topItem
+	items    topHeap
+}
+
+type topItem struct {
+	key   docKey
+	count int64
+	bytes int64
+	pos   int
+}
+
+func newTopCounter(capacity int) *topCounter {
+	return &topCounter{capacity: capacity, index: map[docKey]*topItem{}}
+}
+
+func (t *topCounter) add(key docKey, bytes int64) {
+	if it, ok := t.index[key]; ok {
+		it.count++
+		it.bytes += bytes
+		heap.Fix(&t.items, it.pos)
+		return
+	}
+	if len(t.items) < t.capacity {
+		it := &topItem{key: key, count: 1, bytes: bytes}
+		t.index[key] = it
+		heap.Push(&t.items, it)
+		return
+	}
+	it := t.items[0]
+	delete(t.index, it.key)
+	it.key, it.count, it.bytes = key, it.count+1, bytes
+	t.index[key] = it
+	heap.Fix(&t.items, 0)
+}
+
+// topHeap is a min-heap on count.
+type topHeap []*topItem
+
+func (h topHeap) Len() int           { return len(h) }
+func (h topHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h topHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos, h[j].pos = i, j
+}
+
+func (h *topHeap) Push(x any) {
+	it := x.(*topItem)
+	it.pos = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *topHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
+
+func renderOplogStats(w io.Writer, r oplogStatsReport) error {
+	if r.Entries == 0 {
+		fmt.Fprintln(w, "No oplog entries in range.")
+		return nil
+	}
+	fmt.Fprintf(w, "Range:   %s → %s (%s)\n", r.From.Format("2006-01-02 15:04:05"), r.To.Format("2006-01-02 15:04:05"),
+		time.Duration(r.Seconds*float64(time.Second)).String())
+	fmt.Fprintf(w, "Volume:  %d entries, %s (%.1f ops/s, %s/s)\n",
+		r.Entries, humanize.Bytes(uint64(r.Bytes)), r.OpsPerSec, humanize.Bytes(uint64(r.BytesPerSec)))
+	if r.OplogBytes != r.Bytes {
+		fmt.Fprintf(w, "Oplog:   %s written in range by all namespaces (%s/s)\n",
+			humanize.Bytes(uint64(r.OplogBytes)), humanize.Bytes(uint64(r.OplogBytesPerSec)))
+	}
+	if r.ProjectedWindow != "" {
+		fmt.Fprintf(w, "Window:  %s oplog lasts %s at this rate\n",
+			humanize.Bytes(uint64(r.OplogMaxBytes)), r.ProjectedWindow)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tOPS\tOPS/S\tBYTES\tWINDOW %\tWINDOW\tBREAKDOWN")
+	fmt.Fprintln(tw, "---------\t---\t-----\t-----\t--------\t------\t---------")
+	for _, s := range r.Namespaces {
+		ops := make([]string, 0, len(s.Ops))
+		for _, op := range sortedOpNames(s.Ops) {
+			ops = append(ops, fmt.Sprintf("%s=%d", op, s.Ops[op]))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\t%.1f\t%s\t%s\n", s.Namespace, s.Count, s.OpsPerSec,
+			humanize.Bytes(uint64(s.Bytes)), s.WindowShare, s.WindowConsumed, strings.Join(ops, " "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.TopDocuments) > 0 {
+		fmt.Fprintln(w, "\nMost updated documents")
+		tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "NAMESPACE\tID\tUPDATES\tBYTES")
+		fmt.Fprintln(tw, "---------\t--\t-------\t-----")
+		for _, d := range r.TopDocuments {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", d.Namespace, d.ID, d.Updates, humanize.Bytes(uint64(d.Bytes)))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(r.TopFields) > 0 {
+		fmt.Fprintln(w, "\nMost updated fields")
+		tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "NAMESPACE\tFIELD\tUPDATES")
+		fmt.Fprintln(tw, "---------\t-----\t-------")
+		for _, f := range r.TopFields {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", f.Namespace, f.Field, f.Updates)
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+func sortedOpNames(ops map[string]int64) []string {
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func statusDiff(status string) bson.M {
+	return bson.M{"$v": 2, "diff": bson.M{"u": bson.M{"status": status}}}
+}
+
+func TestOplogStatsCollectorAggregates(t *testing.T) {
+	txn := int64(1)
+	entries := []oplogEntry{
+		{TS: bson.Timestamp{T: 100}, Op: "i", NS: "app.orders", O: bson.M{"_id": 1}},
+		{TS: bson.Timestamp{T: 101}, Op: "u", NS: "app.orders", O: statusDiff("paid"), O2: bson.M{"_id": 1}},
+		{TS: bson.Timestamp{T: 102}, Op: "u", NS: "app.orders", O: statusDiff("sent"), O2: bson.M{"_id": 1}},
+		{TS: bson.Timestamp{T: 103}, Op: "d", NS: "app.carts", O: bson.M{"_id": 9}},
+		{TS: bson.Timestamp{T: 110}, Op: "c", NS: "admin.$cmd", TxnNumber: &txn, O: bson.M{"applyOps": bson.A{
+			bson.M{"op": "u", "ns": "app.orders", "o": bson.M{"$set": bson.M{"total": 5}}, "o2": bson.M{"_id": 2}},
+		}}},
+	}
+
+	c := newOplogStatsCollector(oplogFilter{})
+	for _, e := range entries {
+		c.add(e, 100)
+	}
+	r := c.report(1, 1000)
+
+	if r.Entries != 5 || r.Seconds != 10 {
+		t.Fatalf("expected 5 entries over 10s, got %d over %v", r.Entries, r.Seconds)
+	}
+	if len(r.Namespaces) != 2 || r.Namespaces[0].Namespace != "app.orders" {
+		t.Fatalf("expected app.orders first by bytes, got %+v", r.Namespaces)
+	}
+	orders := r.Namespaces[0]
+	if orders.Ops["update"] != 3 || orders.Ops["insert"] != 1 {
+		t.Fatalf("unexpected op breakdown: %v", orders.Ops)
+	}
+	if orders.WindowShare <= 75 || orders.WindowConsumed == "" || r.ProjectedWindow == "" {
+		t.Fatalf("expected window share and projection, got %+v / %q", orders, r.ProjectedWindow)
+	}
+	if len(r.TopDocuments) != 1 || r.TopDocuments[0].ID != "1" || r.TopDocuments[0].Updates != 2 {
+		t.Fatalf("unexpected top documents: %+v", r.TopDocuments)
+	}
+	if len(r.TopFields) != 1 || r.TopFields[0].Field != "status" || r.TopFields[0].Updates != 2 {
+		t.Fatalf("unexpected top fields: %+v", r.TopFields)
+	}
+
+	var buf bytes.Buffer
+	if err := renderOplogStats(&buf, r); err != nil {
+		t.Fatalf("renderOplogStats: %v", err)
+	}
+	if !strings.Contains(buf.String(), "insert=1 update=3") {
+		t.Fatalf("expected op breakdown in table:\n%s", buf.String())
+	}
+}
+
+func TestOplogStatsCollectorAppliesFilterAfterExpansion(t *testing.T) {
+	txn := int64(1)
+	c := newOplogStatsCollector(oplogFilter{Namespace: "app.orders"})
+	c.add(oplogEntry{TS: bson.Timestamp{T: 1}, Op: "c", NS: "admin.$cmd", TxnNumber: &txn, O: bson.M{"applyOps": bson.A{
+		bson.M{"op": "i", "ns": "app.orders", "o": bson.M{"_id": 1}},
+		bson.M{"op": "i", "ns": "app.other", "o": bson.M{"_id": 1}},
+	}}}, 500)
+
+	r := c.report(10, 0)
+	if r.Entries != 1 || len(r.Namespaces) != 1 || r.ProjectedWindow != "" {
+		t.Fatalf("expected only the app.orders insert and no projection, got %+v", r)
+	}
+}
+
+func TestOplogStatsWindowCountsFilteredOutBytes(t *testing.T) {
+	c := newOplogStatsCollector(oplogFilter{Namespace: "app.orders"})
+	c.add(oplogEntry{TS: bson.Timestamp{T: 100}, Op: "i", NS: "app.orders", O: bson.M{"_id": 1}}, 100)
+	c.add(oplogEntry{TS: bson.Timestamp{T: 110}, Op: "i", NS: "app.other", O: bson.M{"_id": 1}}, 300)
+
+	r := c.report(10, 4000)
+	if r.Bytes != 100 || r.OplogBytes != 400 {
+		t.Fatalf("expected 100 filtered of 400 oplog bytes, got %d of %d", r.Bytes, r.OplogBytes)
+	}
+	if got := r.Namespaces[0].WindowShare; got != 25 {
+		t.Fatalf("expected app.orders to use 25%% of the window, got %v", got)
+	}
+	if r.ProjectedWindow != "1m40s" {
+		t.Fatalf("expected the window projected from all writes (40 B/s), got %q", r.ProjectedWindow)
+	}
+}
+
+func TestTopCounterKeepsHeavyHitters(t *testing.T) {
+	tc := newTopCounter(2)
+	hot := docKey{"app.orders", "1"}
+	for i := range 50 {
+		tc.add(hot, 1)
+		tc.add(docKey{"app.orders", fmt.Sprint(i + 2)}, 1)
+	}
+	if len(tc.items) != 2 || len(tc.index) != 2 {
+		t.Fatalf("expected the counter to stay at capacity, got %d items", len(tc.items))
+	}
+	if it := tc.index[hot]; it == nil || it.count != 50 {
+		t.Fatalf("expected the hot key to keep its exact count, got %+v", it)
+	}
+}
+
+func TestEntrySizeReadsProjectedSize(t *testing.T) {
+	projected, _ := bson.Marshal(bson.D{{Key: "op", Value: "i"}, {Key: "size", Value: int32(4096)}})
+	if got := entrySize(projected); got != 4096 {
+		t.Fatalf("expected the projected size, got %d", got)
+	}
+	raw, _ := bson.Marshal(bson.D{{Key: "op", Value: "i"}})
+	if got := entrySize(raw); got != len(raw) {
+		t.Fatalf("expected the raw length %d, got %d", len(raw), got)
+	}
+}
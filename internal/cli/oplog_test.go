@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestWatchFromCheckpointFallback(t *testing.T) {
+	cp := &cdc.Checkpoint{Token: bson.Raw{5, 0, 0, 0, 0}, ClusterTime: bson.Timestamp{T: 100}}
+	tests := []struct {
+		name    string
+		errs    []error
+		modes   []string
+		wantErr error
+	}{
+		{"resumes", []error{nil}, []string{"resumeAfter"}, nil},
+		{"invalidate token", []error{mongo.CommandError{Code: 260}, nil}, []string{"resumeAfter", "startAfter"}, nil},
+		{"rolled off", []error{mongo.CommandError{Code: 286}}, []string{"resumeAfter"}, ErrResumePointLost},
+		{"other", []error{mongo.CommandError{Code: 13}}, []string{"resumeAfter"}, ErrStreamFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var modes []string
+			watch := func(b *options.ChangeStreamOptionsBuilder) (*mongo.ChangeStream, error) {
+				opts := &options.ChangeStreamOptions{}
+				for _, set := range b.List() {
+					_ = set(opts)
+				}
+				switch {
+				case opts.ResumeAfter != nil:
+					modes = append(modes, "resumeAfter")
+				case opts.StartAfter != nil:
+					modes = append(modes, "startAfter")
+				default:
+					modes = append(modes, "startAtOperationTime")
+				}
+				return nil, tt.errs[len(modes)-1]
+			}
+			_, err := watchFromCheckpoint(oplogConfig{}, cp, watch)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if len(modes) != len(tt.modes) || modes[0] != tt.modes[0] || modes[len(modes)-1] != tt.modes[len(tt.modes)-1] {
+				t.Fatalf("watched with %v, want %v", modes, tt.modes)
+			}
+		})
+	}
+}
+
+func TestStreamMatchExcludesCheckpointNamespace(t *testing.T) {
+	f, err := newOplogFilter(oplogConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Exclude = []string{"app.oplog_checkpoints"}
+	match := f.streamMatch()
+	if len(match) != 1 || match[0].Key != "$nor" {
+		t.Fatalf("expected a $nor on the checkpoint namespace, got %v", match)
+	}
+	want := bson.A{bson.M{"ns.db": "app", "ns.coll": "oplog_checkpoints"}}
+	if !reflect.DeepEqual(match[0].Value, want) {
+		t.Fatalf("unexpected $nor %v", match[0].Value)
+	}
+}
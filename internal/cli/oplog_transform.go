@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"github.com/drewjocham/mongork/internal/cdc"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// transformOutput reshapes one event with the transform for its namespace:
+// the document, pre-image and update delta are cleaned, and the namespace is
+// the routed one. An oplog update's Data is its raw $v:2 diff or $set/$unset,
+// which field paths cannot address; it is dropped, since Update carries the
+// same change in a form the rules apply to.
+func transformOutput(tr cdc.Transform, o oplogOutput) oplogOutput {
+	clean := tr.Cleaner()
+	o.Namespace = tr.Namespace
+	if o.Operation == "update" && cdc.IsOperatorUpdate(o.Data) {
+		o.Data = nil
+	}
+	o.Data = cleanBson(clean, o.Data)
+	o.Before = cleanBson(clean, o.Before)
+	if o.Update != nil {
+		u := *o.Update
+		u.UpdatedFields = cleanBson(clean, u.UpdatedFields)
+		u.RemovedFields = tr.Paths(u.RemovedFields)
+		u.TruncatedArrays = nil
+		for _, t := range o.Update.TruncatedArrays {
+			if paths := tr.Paths([]string{t.Field}); len(paths) == 1 {
+				u.TruncatedArrays = append(u.TruncatedArrays, truncatedArray{Field: paths[0], NewSize: t.NewSize})
+			}
+		}
+		o.Update = &u
+		if u.empty() {
+			o.Update = nil
+		}
+	}
+	return o
+}
+
+func cleanBson(clean func(map[string]any) map[string]any, doc bson.M) bson.M {
+	if doc == nil {
+		return nil
+	}
+	return bson.M(clean(doc))
+}
+
+// dropSkipped wraps deliver so events whose namespace a transform skips are
+// never rendered or sent to a sink.
+func dropSkipped(transforms *cdc.Transformer, deliver func([]oplogEntry) error) func([]oplogEntry) error {
+	return func(entries []oplogEntry) error {
+		kept := make([]oplogEntry, 0, len(entries))
+		for _, e := range entries {
+			if !transforms.For(e.NS).Skip {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		return deliver(kept)
+	}
+}
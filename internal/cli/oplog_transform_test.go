@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestTransformOutputAndSkip(t *testing.T) {
+	tf, err := cdc.ParseTransforms([]byte(`
+rules:
+  - match: app.users
+    drop: [ssn]
+    mask: [email]
+    route: clean.users
+  - match: app.audit
+    skip: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := oplogEntry{Op: "u", NS: "app.users", O2: bson.M{"_id": 1},
+		O: bson.M{"$v": 2, "diff": bson.M{"u": bson.M{"email": "a@b.c"}, "d": bson.M{"ssn": false}}}}
+	out := transformOutput(tf.For(e.NS), e.ToOutput())
+	if out.Namespace != "clean.users" {
+		t.Fatalf("expected routed namespace, got %s", out.Namespace)
+	}
+	if u := out.Update; u == nil || u.UpdatedFields["email"] != cdc.DefaultMaskValue || len(u.RemovedFields) != 0 {
+		t.Fatalf("expected masked email and no removed ssn, got %+v", out.Update)
+	}
+	if e.O["diff"].(bson.M)["u"].(bson.M)["email"] != "a@b.c" {
+		t.Fatal("transform must not modify the source entry")
+	}
+
+	var delivered []oplogEntry
+	deliver := dropSkipped(tf, func(entries []oplogEntry) error {
+		delivered = append(delivered, entries...)
+		return nil
+	})
+	if err := deliver([]oplogEntry{{NS: "app.audit"}, e}); err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 1 || delivered[0].NS != "app.users" {
+		t.Fatalf("expected only the app.users event, got %+v", delivered)
+	}
+}
+
+func TestTransformOutputMasksOplogUpdatePayload(t *testing.T) {
+	tf, err := cdc.ParseTransforms([]byte(`
+rules:
+  - match: app.users
+    mask: [profile.email]
+    drop: [ssn]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []oplogEntry{
+		{Op: "u", NS: "app.users", O2: bson.M{"_id": 1}, O: bson.M{"$v": 2, "diff": bson.M{
+			"i":        bson.M{"ssn": "123-45-6789"},
+			"sprofile": bson.M{"u": bson.M{"email": "a@b.c"}},
+		}}},
+		{Op: "u", NS: "app.users", O2: bson.M{"_id": 1}, O: bson.M{"$set": bson.M{"profile.email": "a@b.c"}}},
+	}
+	for _, e := range entries {
+		out := transformOutput(tf.For(e.NS), e.ToOutput())
+		if out.Data != nil {
+			t.Fatalf("expected the raw update payload to be dropped, got %v", out.Data)
+		}
+		if u := out.Update; u == nil || u.UpdatedFields["profile.email"] != cdc.DefaultMaskValue {
+			t.Fatalf("expected profile.email masked in the delta, got %+v", out.Update)
+		}
+		if _, ok := out.Update.UpdatedFields["ssn"]; ok {
+			t.Fatalf("expected ssn dropped from the delta, got %+v", out.Update)
+		}
+	}
+}
@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/drewjocham/mongork/internal/config"
 	logging "github.com/drewjocham/mongork/internal/log"
 
 	"github.com/drewjocham/mongork/internal/migration"
+	"github.com/drewjocham/mongork/internal/schema"
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
@@ -21,9 +23,15 @@ import (
 
 const (
 	annotationOffline = "offline"
-	maxPingRetries    = 5
-	pingRetryDelay    = 1 * time.Second
-	pingTimeout       = 2 * time.Second
+	// annotationOfflineRefs lists schema reference flags; the command runs
+	// offline when none of them needs the database (see snapshotRefsOffline).
+	annotationOfflineRefs = "offline-refs"
+	// annotationOfflineFlags lists flags that together make the command run
+	// offline when all of them are set, e.g. a dry run over a file.
+	annotationOfflineFlags = "offline-flags"
+	maxPingRetries         = 5
+	pingRetryDelay         = 1 * time.Second
+	pingTimeout            = 2 * time.Second
 )
 
 var (
@@ -127,6 +135,15 @@ func bootstrap(cmd *cobra.Command, path string, show bool, out io.Writer, offlin
 		return nil, ErrShowConfigDisplayed
 	}
 
+	if usesSchemaRegistry(cmd, offline) {
+		if _, err := schema.LoadDir(cfg.SchemaPath); err != nil {
+			return nil, err
+		}
+		if err := registerIgnoreRules(cfg); err != nil {
+			return nil, err
+		}
+	}
+
 	if offline {
 		return &Services{Config: cfg}, nil
 	}
@@ -219,7 +236,7 @@ func validateRegistry() error {
 }
 
 func isOffline(cmd *cobra.Command) bool {
-	if cmd.Annotations[annotationOffline] == "true" {
+	if cmd.Annotations[annotationOffline] == "true" || snapshotRefsOffline(cmd) || offlineFlagsSet(cmd) {
 		return true
 	}
 	offlineCommands := map[string]bool{
@@ -230,6 +247,38 @@ func isOffline(cmd *cobra.Command) bool {
 	return offlineCommands[cmd.Name()]
 }
 
+// offlineFlagsSet reports whether every flag in the command's
+// annotationOfflineFlags annotation was set.
+func offlineFlagsSet(cmd *cobra.Command) bool {
+	names := cmd.Annotations[annotationOfflineFlags]
+	if names == "" {
+		return false
+	}
+	for _, name := range strings.Split(names, ",") {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || !flag.Changed || flag.Value.String() == "false" {
+			return false
+		}
+	}
+	return true
+}
+
+// usesSchemaRegistry reports whether cmd reads schema files: every command
+// that connects (the schema import prompt compares against them) and the
+// offline schema subcommands. Other offline commands such as create or
+// version must not fail on a malformed schema directory.
+func usesSchemaRegistry(cmd *cobra.Command, offline bool) bool {
+	if !offline {
+		return true
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "schema" {
+			return true
+		}
+	}
+	return false
+}
+
 func teardown(s *Services) {
 	if s.MongoClient != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
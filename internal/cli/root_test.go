@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestUsesSchemaRegistry(t *testing.T) {
+	root := newRootCmd()
+	find := func(args ...string) *cobra.Command {
+		t.Helper()
+		cmd, _, err := root.Find(args)
+		if err != nil {
+			t.Fatalf("find %v: %v", args, err)
+		}
+		return cmd
+	}
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"version"}, false},
+		{[]string{"create"}, false},
+		{[]string{"schema", "indexes"}, true},
+		{[]string{"schema", "compare"}, true},
+		{[]string{"status"}, true},
+	}
+	for _, tt := range tests {
+		cmd := find(tt.args...)
+		if got := usesSchemaRegistry(cmd, isOffline(cmd)); got != tt.want {
+			t.Errorf("usesSchemaRegistry(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestReplayDryRunFromFileIsOffline(t *testing.T) {
+	cmd, _, err := newRootCmd().Find([]string{"oplog", "replay"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isOffline(cmd) {
+		t.Fatal("replay without flags needs a connection")
+	}
+	if err := cmd.ParseFlags([]string{"--from-file", "events.ndjson", "--dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+	if !isOffline(cmd) {
+		t.Fatal("a dry run over a file should run offline")
+	}
+}
+
+func TestOplogFromArchiveIsOffline(t *testing.T) {
+	cmd, _, err := newRootCmd().Find([]string{"oplog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isOffline(cmd) {
+		t.Fatal("oplog without --from-archive needs a connection")
+	}
+	if err := cmd.ParseFlags([]string{"--from-archive", "/var/backups/oplog"}); err != nil {
+		t.Fatal(err)
+	}
+	if !isOffline(cmd) || usesSchemaRegistry(cmd, isOffline(cmd)) {
+		t.Fatal("querying an archive should run offline without reading schema files")
+	}
+}
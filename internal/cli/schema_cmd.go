@@ -4,15 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/drewjocham/mongork/internal/migration"
 	"github.com/drewjocham/mongork/internal/schema"
 	"github.com/drewjocham/mongork/internal/schema/diff"
 	"github.com/spf13/cobra"
 )
 
 var (
-	ErrUnsupportedOutputFormat = errors.New("unsupported output format")
+	ErrUnsupportedOutputFormat  = errors.New("unsupported output format")
+	ErrValidatorNotRegistered   = errors.New("no validator registered for collection")
+	ErrCheckValidatorsNeedsLive = errors.New("--check-validators requires --from live")
+	ErrSchemaDriftDetected      = errors.New("schema drift detected")
 )
 
 func newSchemaCmd() *cobra.Command {
@@ -21,7 +26,16 @@ func newSchemaCmd() *cobra.Command {
 		Short: "Schema utilities",
 	}
 
-	cmd.AddCommand(newSchemaIndexesCmd(), newSchemaDiffCmd())
+	cmd.AddCommand(
+		newSchemaIndexesCmd(),
+		newSchemaDiffCmd(),
+		newSchemaCheckValidatorCmd(),
+		newSchemaInferCmd(),
+		newSchemaApplyCmd(),
+		newSchemaSnapshotCmd(),
+		newSchemaCompareCmd(),
+		newSchemaImportCmd(),
+	)
 	return cmd
 }
 
@@ -42,44 +56,148 @@ func newSchemaIndexesCmd() *cobra.Command {
 }
 
 func newSchemaDiffCmd() *cobra.Command {
-	var output string
+	var (
+		output          string
+		checkValidators bool
+		from            string
+		to              string
+		failOn          string
+		ignoreFile      string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "diff",
 		Short: "Compare registered schema/index specs against live MongoDB",
+		Long: "Compares two schema references. By default --from is the live database and --to the registry. " +
+			"References may also be a snapshot name, a snapshot file, latest or mongo:<name|latest>; " +
+			"comparing two file snapshots works offline.",
+		Annotations: map[string]string{annotationOfflineRefs: "from,to"},
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			s, err := getServices(cmd.Context())
-			if err != nil || s.MongoClient == nil {
-				return fmt.Errorf("mongo client unavailable")
+			if failOn != "" {
+				risk, err := diff.ParseRisk(failOn)
+				if err != nil {
+					return err
+				}
+				failOn = risk
+			}
+			var ignore diff.IgnoreRules
+			if ignoreFile != "" {
+				rules, err := diff.LoadIgnoreFile(ignoreFile)
+				if err != nil {
+					return err
+				}
+				ignore = rules
 			}
 
-			live, err := diff.InspectLive(cmd.Context(), s.MongoClient.Database(s.Config.Mongo.Database))
+			cfg, err := getConfig(cmd.Context())
 			if err != nil {
 				return err
 			}
-			target := diff.FromRegistry()
-			if len(live.Indexes) == 0 && len(live.Validators) == 0 &&
-				len(target.Indexes) == 0 && len(target.Validators) == 0 {
+			s, _ := getServices(cmd.Context())
+
+			live, err := resolveSchemaRef(cmd.Context(), from, cfg, s)
+			if err != nil {
+				return err
+			}
+			target, err := resolveSchemaRef(cmd.Context(), to, cfg, s)
+			if err != nil {
+				return err
+			}
+			report := isDiffReportFormat(output)
+			if live.Empty() && target.Empty() && !report {
 				fmt.Fprintln(cmd.OutOrStdout(), "Nothing to compare: no collections and no registered schema metadata.")
 				return nil
 			}
-			if len(target.Indexes) == 0 && len(target.Validators) == 0 {
+			if to == snapshotRefRegistry && len(target.Indexes) == 0 && len(target.Validators) == 0 &&
+				len(target.Options) == 0 && len(target.Views) == 0 && len(target.MaterializedViews) == 0 &&
+				len(target.ShardKeys) == 0 && len(target.SearchIndexes) == 0 && !report {
 				fmt.Fprintln(cmd.OutOrStdout(), "No registered schema metadata to compare.")
 				return nil
 			}
-			diffs := diff.Compare(live, target)
+			if checkValidators {
+				if from != snapshotRefLive || s == nil || s.MongoClient == nil {
+					return ErrCheckValidatorsNeedsLive
+				}
+				db := s.MongoClient.Database(s.Config.Mongo.Database)
+				if err := diff.CheckValidators(cmd.Context(), db, live, target, 0); err != nil {
+					return err
+				}
+			}
+			rules := diff.RegisteredIgnoreRules()
+			diffs, ignored := diff.CompareWithIgnored(live, target, rules)
+			diffs, fileIgnored := ignore.Filter(diffs)
+			if len(ignored) > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "ignored %d difference(s): %s\n",
+					len(ignored), summarizeIgnored(rules, ignored))
+			}
+			if len(fileIgnored) > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "ignored %d difference(s) matched by %s\n", len(fileIgnored), ignoreFile)
+			}
+
+			if err := renderDiffReport(cmd.OutOrStdout(), output, diffs, failOn); err != nil {
+				return err
+			}
+			if failOn != "" {
+				if failing := diff.FilterRisk(diffs, failOn); len(failing) > 0 {
+					return fmt.Errorf("%w: %d difference(s) at %s risk or higher", ErrSchemaDriftDetected, len(failing), failOn)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json, junit, sarif or markdown")
+	cmd.Flags().StringVar(&failOn, "fail-on", "",
+		"Exit with code 2 when drift of this risk or higher exists: low, medium, high or critical")
+	cmd.Flags().StringVar(&ignoreFile, "ignore", "",
+		"File of collection/index globs (optionally component:glob) for intentional differences")
+	cmd.Flags().BoolVar(&checkValidators, "check-validators", false,
+		"Count live documents violating registered validators and factor them into risk")
+	cmd.Flags().StringVar(&from, "from", snapshotRefLive,
+		"Current schema: live, registry, latest, mongo:<name>, a snapshot name or file")
+	cmd.Flags().StringVar(&to, "to", snapshotRefRegistry,
+		"Desired schema: live, registry, latest, mongo:<name>, a snapshot name or file")
+	return cmd
+}
+
+func newSchemaCheckValidatorCmd() *cobra.Command {
+	var (
+		output string
+		sample int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check-validator <collection>",
+		Short: "Count live documents that would violate the registered validator",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil || s.MongoClient == nil {
+				return fmt.Errorf("mongo client unavailable")
+			}
+
+			spec, ok := diff.FromRegistry().Validators[args[0]]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrValidatorNotRegistered, args[0])
+			}
+			db := s.MongoClient.Database(s.Config.Mongo.Database)
+			impact, err := diff.CheckValidator(cmd.Context(), db, spec, sample)
+			if err != nil {
+				return err
+			}
 
 			return renderWithOutput(
 				cmd.OutOrStdout(),
 				output,
 				ErrUnsupportedOutputFormat,
-				func(w io.Writer) error { return renderDiffTable(w, diffs) },
-				func(w io.Writer) error { return renderDiffJSON(w, diffs) },
+				func(w io.Writer) error { return renderValidatorImpactTable(w, impact) },
+				func(w io.Writer) error { return encodePrettyJSON(w, impact) },
 			)
 		},
 	}
 
 	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table or json")
+	cmd.Flags().IntVar(&sample, "sample", 10, "Number of violating _id values to show")
 	return cmd
 }
 
@@ -127,6 +245,35 @@ func renderIndexesTable(w io.Writer) error {
 	return tw.Flush()
 }
 
+func isDiffReportFormat(output string) bool {
+	switch strings.ToLower(strings.TrimSpace(output)) {
+	case "junit", "sarif", "markdown", "md":
+		return true
+	default:
+		return false
+	}
+}
+
+// renderDiffReport adds the CI report formats to the table and json output.
+func renderDiffReport(w io.Writer, output string, diffs []diff.Diff, failOn string) error {
+	switch strings.ToLower(strings.TrimSpace(output)) {
+	case "junit":
+		return diff.WriteJUnit(w, diffs, failOn)
+	case "sarif":
+		return diff.WriteSARIF(w, diffs)
+	case "markdown", "md":
+		return diff.WriteMarkdown(w, diffs)
+	default:
+		return renderWithOutput(
+			w,
+			output,
+			ErrUnsupportedOutputFormat,
+			func(w io.Writer) error { return renderDiffTable(w, diffs) },
+			func(w io.Writer) error { return renderDiffJSON(w, diffs) },
+		)
+	}
+}
+
 func renderDiffJSON(w io.Writer, diffs []diff.Diff) error {
 	return encodePrettyJSON(w, diffs)
 }
@@ -143,7 +290,7 @@ func renderDiffTable(w io.Writer, diffs []diff.Diff) error {
 
 	for _, d := range diffs {
 		action := d.Action
-		if d.Risk == "HIGH" || d.Risk == "CRITICAL" {
+		if d.AtLeast(diff.RiskHigh) {
 			action = "!! " + action
 		}
 		fmt.Fprintf(
@@ -160,3 +307,104 @@ func renderDiffTable(w io.Writer, diffs []diff.Diff) error {
 
 	return tw.Flush()
 }
+
+func renderValidatorImpactTable(w io.Writer, impact diff.ValidatorImpact) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "COLLECTION\tDOCUMENTS\tVIOLATIONS")
+	fmt.Fprintln(tw, "----------\t---------\t----------")
+	fmt.Fprintf(tw, "%s\t%d\t%d\n", impact.Collection, impact.Scanned, impact.Violations)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(impact.SampleIDs) == 0 {
+		fmt.Fprintln(w, "\nAll documents satisfy the validator.")
+		return nil
+	}
+	fmt.Fprintln(w, "\nSample violating _id values:")
+	for _, id := range impact.SampleIDs {
+		fmt.Fprintf(w, "  - %s\n", id)
+	}
+	return nil
+}
+
+func newSchemaApplyCmd() *cobra.Command {
+	var (
+		output       string
+		dryRun       bool
+		hiddenBuild  bool
+		commitQuorum string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply non-destructive schema drift (collections, indexes, validators, mutable options)",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil || s.MongoClient == nil {
+				return fmt.Errorf("mongo client unavailable")
+			}
+
+			db := s.MongoClient.Database(s.Config.Mongo.Database)
+			live, err := diff.InspectLive(cmd.Context(), db)
+			if err != nil {
+				return err
+			}
+			target := diff.FromRegistry()
+			opts := diff.ApplyOptions{
+				DryRun: dryRun,
+				IndexOptions: []migration.IndexCreateOption{
+					migration.WithBuildProgress(0, func(p migration.IndexBuildProgress) {
+						fmt.Fprintf(cmd.ErrOrStderr(), "building index on %s: %.1f%% (%d/%d)\n",
+							p.Namespace, p.Percent(), p.Done, p.Total)
+					}),
+				},
+			}
+			if hiddenBuild {
+				opts.IndexOptions = append(opts.IndexOptions, migration.WithHiddenBuild())
+			}
+			if commitQuorum != "" {
+				opts.IndexOptions = append(opts.IndexOptions, migration.WithCommitQuorum(commitQuorum))
+			}
+			diffs, _ := diff.CompareWithIgnored(live, target, diff.RegisteredIgnoreRules())
+			results, err := diff.Apply(cmd.Context(), db, target, diffs, opts)
+			renderErr := renderWithOutput(
+				cmd.OutOrStdout(),
+				output,
+				ErrUnsupportedOutputFormat,
+				func(w io.Writer) error { return renderApplyTable(w, results) },
+				func(w io.Writer) error { return encodePrettyJSON(w, results) },
+			)
+			if err != nil {
+				return err
+			}
+			return renderErr
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table or json")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be applied without changing the database")
+	cmd.Flags().BoolVar(&hiddenBuild, "hidden-build", false, "Build indexes hidden and unhide them once committed")
+	cmd.Flags().StringVar(&commitQuorum, "commit-quorum", "",
+		"Index build commitQuorum (majority, votingMembers or a tag)")
+	return cmd
+}
+
+func renderApplyTable(w io.Writer, results []diff.ApplyResult) error {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No schema drift detected.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tTARGET\tRISK\tSTATUS\tNOTE")
+	fmt.Fprintln(tw, "------\t------\t----\t------\t----")
+	for _, r := range results {
+		note := r.Note
+		if note == "" {
+			note = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Action, r.Target, r.Risk, r.Status, note)
+	}
+	return tw.Flush()
+}
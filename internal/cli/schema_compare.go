@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/config"
+	"github.com/drewjocham/mongork/internal/migration"
+	"github.com/drewjocham/mongork/internal/schema/diff"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const snapshotRefPrefix = "snapshot:"
+
+var ErrCompareRefRequired = errors.New("--source and --target are required")
+
+// compareEndpoint is one side of `schema compare`: a live database, which
+// also has a migration history, or a snapshot.
+type compareEndpoint struct {
+	Label   string                      `json:"label"`
+	Spec    diff.SchemaSpec             `json:"-"`
+	Applied []migration.MigrationRecord `json:"-"`
+	Live    bool                        `json:"live"`
+}
+
+type schemaComparison struct {
+	Source     compareEndpoint              `json:"source"`
+	Target     compareEndpoint              `json:"target"`
+	Diffs      []diff.Diff                  `json:"diffs"`
+	Migrations *migration.AppliedComparison `json:"migrations,omitempty"`
+}
+
+func newSchemaCompareCmd() *cobra.Command {
+	var (
+		output string
+		source string
+		target string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compare",
+		Short: "Compare the schema and migration history of two environments",
+		Long: "Each side is a MongoDB URI with the database in its path (mongodb://host/app), " +
+			"a database name on the configured cluster, live, or a snapshot (a file path or snapshot:<ref>). " +
+			"Differences are reported as changes needed to turn --target into --source.",
+		Annotations: map[string]string{annotationOffline: "true"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if source == "" || target == "" {
+				return ErrCompareRefRequired
+			}
+			cfg, err := getConfig(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			src, closeSrc, err := openCompareEndpoint(cmd.Context(), source, cfg)
+			if err != nil {
+				return fmt.Errorf("source %s: %w", source, err)
+			}
+			defer closeSrc()
+			dst, closeDst, err := openCompareEndpoint(cmd.Context(), target, cfg)
+			if err != nil {
+				return fmt.Errorf("target %s: %w", target, err)
+			}
+			defer closeDst()
+
+			diffs, _ := diff.CompareWithIgnored(dst.Spec, src.Spec, diff.RegisteredIgnoreRules())
+			result := schemaComparison{
+				Source: src,
+				Target: dst,
+				Diffs:  diffs,
+			}
+			if src.Live && dst.Live {
+				applied := migration.CompareApplied(src.Applied, dst.Applied)
+				result.Migrations = &applied
+			}
+
+			return renderWithOutput(
+				cmd.OutOrStdout(),
+				output,
+				ErrUnsupportedOutputFormat,
+				func(w io.Writer) error { return renderComparison(w, result) },
+				func(w io.Writer) error { return encodePrettyJSON(w, result) },
+			)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table or json")
+	cmd.Flags().StringVar(&source, "source", "", "Reference environment (URI, database name, live or snapshot)")
+	cmd.Flags().StringVar(&target, "target", "", "Environment checked against the source")
+	return cmd
+}
+
+// openCompareEndpoint resolves ref and returns a func releasing any
+// connection opened for it.
+func openCompareEndpoint(ctx context.Context, ref string, cfg *config.Config) (compareEndpoint, func(), error) {
+	noop := func() {}
+	if strings.HasPrefix(ref, snapshotRefPrefix) || isSnapshotFile(ref) {
+		name := strings.TrimPrefix(ref, snapshotRefPrefix)
+		spec, err := resolveSchemaRef(ctx, name, cfg, nil)
+		return compareEndpoint{Label: "snapshot " + name, Spec: spec}, noop, err
+	}
+
+	connCfg, label, err := compareEndpointConfig(ref, cfg)
+	if err != nil {
+		return compareEndpoint{}, noop, err
+	}
+	client, err := dial(ctx, &connCfg)
+	if err != nil {
+		return compareEndpoint{}, noop, err
+	}
+	closeFn := func() {
+		dctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = client.Disconnect(dctx)
+	}
+
+	endpoint, err := inspectEndpoint(ctx, client.Database(connCfg.Mongo.Database), cfg.MigrationsCollection)
+	if err != nil {
+		closeFn()
+		return compareEndpoint{}, noop, err
+	}
+	endpoint.Label = label
+	return endpoint, closeFn, nil
+}
+
+// compareEndpointConfig is the connection config for a database name, live or
+// URI ref. An explicit URI may name another host, so the configured
+// credentials are never sent to it; it authenticates only with its own.
+func compareEndpointConfig(ref string, cfg *config.Config) (config.Config, string, error) {
+	connCfg := *cfg
+	label := ref
+	switch {
+	case strings.HasPrefix(ref, "mongodb://") || strings.HasPrefix(ref, "mongodb+srv://"):
+		u, err := url.Parse(ref)
+		if err != nil {
+			return config.Config{}, "", err
+		}
+		if db := strings.Trim(u.Path, "/"); db != "" {
+			connCfg.Mongo.Database = db
+		}
+		connCfg.Mongo.URL = ref
+		connCfg.Mongo.Username, connCfg.Mongo.Password = "", ""
+		label = u.Redacted()
+	case ref == snapshotRefLive:
+		label = connCfg.Mongo.Database
+	default:
+		connCfg.Mongo.Database = ref
+	}
+	return connCfg, label, nil
+}
+
+func inspectEndpoint(ctx context.Context, db *mongo.Database, migrationsCollection string) (compareEndpoint, error) {
+	spec, err := diff.InspectLive(ctx, db)
+	if err != nil {
+		return compareEndpoint{}, err
+	}
+	applied, err := migration.NewEngine(db, migrationsCollection).ListApplied(ctx)
+	if err != nil {
+		return compareEndpoint{}, err
+	}
+	return compareEndpoint{Spec: spec, Applied: applied, Live: true}, nil
+}
+
+func isSnapshotFile(ref string) bool {
+	info, err := os.Stat(ref)
+	return err == nil && !info.IsDir()
+}
+
+func renderComparison(w io.Writer, c schemaComparison) error {
+	fmt.Fprintf(w, "Source: %s\nTarget: %s\n\n", c.Source.Label, c.Target.Label)
+	if err := renderDiffTable(w, c.Diffs); err != nil {
+		return err
+	}
+	if c.Migrations == nil {
+		fmt.Fprintln(w, "\nMigration history not compared: one side is a snapshot.")
+		return nil
+	}
+	fmt.Fprintln(w)
+	if c.Migrations.InSync() {
+		fmt.Fprintf(w, "Migration history in sync (%d versions applied on both).\n", c.Migrations.Common)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDESCRIPTION\tAPPLIED IN")
+	fmt.Fprintln(tw, "-------\t-----------\t----------")
+	for _, r := range c.Migrations.SourceOnly {
+		fmt.Fprintf(tw, "%s\t%s\tsource only\n", r.Version, r.Description)
+	}
+	for _, r := range c.Migrations.TargetOnly {
+		fmt.Fprintf(tw, "%s\t%s\ttarget only\n", r.Version, r.Description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/drewjocham/mongork/internal/config"
+)
+
+func TestCompareEndpointConfigKeepsCredentialsOffExplicitURIs(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Mongo.URL = "mongodb://prod:27017"
+	cfg.Mongo.Database = "app"
+	cfg.Mongo.Username, cfg.Mongo.Password = "admin", "secret"
+
+	for _, ref := range []string{"mongodb://staging:27017/app", "mongodb://other:pw@staging:27017/app"} {
+		got, _, err := compareEndpointConfig(ref, cfg)
+		if err != nil {
+			t.Fatalf("compareEndpointConfig(%q): %v", ref, err)
+		}
+		if got.Mongo.Username != "" || got.Mongo.Password != "" || got.Mongo.URL != ref {
+			t.Fatalf("expected %q to connect without the configured user, got %+v", ref, got.Mongo)
+		}
+	}
+	for _, ref := range []string{snapshotRefLive, "app_staging"} {
+		got, _, err := compareEndpointConfig(ref, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Mongo.Username != "admin" || got.Mongo.URL != cfg.Mongo.URL {
+			t.Fatalf("expected %q to reuse the configured connection, got %+v", ref, got.Mongo)
+		}
+	}
+}
@@ -19,7 +19,7 @@ func renderSchemaDiff(ctx context.Context, out io.Writer) error {
 		return err
 	}
 	target := diff.FromRegistry()
-	diffs := diff.Compare(live, target)
+	diffs, _ := diff.CompareWithIgnored(live, target, diff.RegisteredIgnoreRules())
 	err = renderDiffTable(out, diffs)
 	if err != nil {
 		return err
@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"github.com/drewjocham/mongork/internal/schema/diff"
+	"github.com/spf13/cobra"
+)
+
+// annotationNoImportPrompt keeps bootstrap from offering the implicit import
+// prompt, e.g. for `schema import` itself.
+const annotationNoImportPrompt = "no-import-prompt"
+
+var ErrUntrackedSchema = errors.New("untracked schema found")
+
+// schemaImportFilter selects what to import. Globs use path.Match syntax;
+// index globs match "<collection>.<index>". Empty include lists select all.
+type schemaImportFilter struct {
+	Collections []string
+	Indexes     []string
+	Exclude     []string
+}
+
+func (f schemaImportFilter) collection(name string) bool {
+	return matchAnyGlob(f.Collections, name, true) && !matchAnyGlob(f.Exclude, name, false)
+}
+
+func (f schemaImportFilter) index(collection, name string) bool {
+	full := collection + "." + name
+	return f.collection(collection) && matchAnyGlob(f.Indexes, full, true) && !matchAnyGlob(f.Exclude, full, false)
+}
+
+func matchAnyGlob(patterns []string, name string, emptyMatches bool) bool {
+	if len(patterns) == 0 {
+		return emptyMatches
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaImportSelection is the live schema that is not yet in the registry.
+type schemaImportSelection struct {
+	Collections []string                            `json:"collections"`
+	Indexes     []schema.IndexSpec                  `json:"indexes"`
+	Validators  []schema.ValidatorSpec              `json:"validators,omitempty"`
+	Options     map[string]schema.CollectionOptions `json:"options,omitempty"`
+}
+
+func (s schemaImportSelection) empty() bool {
+	return len(s.Collections) == 0 && len(s.Indexes) == 0 && len(s.Validators) == 0
+}
+
+// selectUntrackedSchema collects untracked collections and indexes, plus
+// validators and collection options when requested, honoring ignore rules.
+func selectUntrackedSchema(
+	live, target diff.SchemaSpec,
+	rules diff.IgnoreRules,
+	filter schemaImportFilter,
+	withValidators, withOptions bool,
+) schemaImportSelection {
+	var sel schemaImportSelection
+	for _, coll := range findUntrackedCollections(live, target, rules) {
+		if !filter.collection(coll) {
+			continue
+		}
+		sel.Collections = append(sel.Collections, coll)
+		if opts, ok := live.Options[coll]; withOptions && ok && !opts.IsZero() {
+			if sel.Options == nil {
+				sel.Options = make(map[string]schema.CollectionOptions)
+			}
+			sel.Options[coll] = opts
+		}
+	}
+	for _, idx := range findUntrackedIndexes(live, target, rules) {
+		if filter.index(idx.Collection, idx.Name) {
+			sel.Indexes = append(sel.Indexes, idx)
+		}
+	}
+	if withValidators {
+		for _, coll := range sortedStringKeys(live.Validators) {
+			if _, tracked := target.Validators[coll]; tracked || !filter.collection(coll) {
+				continue
+			}
+			if _, ignored := rules.RuleFor("validator", coll); ignored {
+				continue
+			}
+			v := live.Validators[coll]
+			sel.Validators = append(sel.Validators, schema.ValidatorSpec{
+				Collection: coll,
+				Schema:     v.Schema,
+				Level:      v.Level,
+			})
+		}
+	}
+	return sel
+}
+
+func sortedStringKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newSchemaImportCmd() *cobra.Command {
+	var (
+		output         string
+		filter         schemaImportFilter
+		out            string
+		pkg            string
+		withValidators bool
+		withOptions    bool
+		check          bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Generate registry code for live collections, indexes and validators not yet tracked",
+		Long: "Writes a Go file that registers the untracked live schema and records it in the import cache. " +
+			"--check only lists what would be imported and fails when anything is untracked, for CI.",
+		Annotations: map[string]string{annotationNoImportPrompt: "true"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrMongoClientUnavailable, err)
+			}
+			if s.MongoClient == nil {
+				return ErrMongoClientUnavailable
+			}
+			cfg := s.Config
+
+			live, err := diff.InspectLive(cmd.Context(), s.MongoClient.Database(cfg.Mongo.Database))
+			if err != nil {
+				return err
+			}
+			cache, err := loadSchemaImportCache(cfg.MigrationsPath)
+			if err != nil {
+				return err
+			}
+			applySchemaImportCache(cache, live)
+
+			sel := selectUntrackedSchema(live, diff.FromRegistry(), diff.RegisteredIgnoreRules(),
+				filter, withValidators, withOptions)
+
+			if check || sel.empty() {
+				if err := renderWithOutput(cmd.OutOrStdout(), output, ErrUnsupportedOutputFormat,
+					func(w io.Writer) error { return renderImportSelection(w, sel) },
+					func(w io.Writer) error { return encodePrettyJSON(w, sel) },
+				); err != nil {
+					return err
+				}
+				if check && !sel.empty() {
+					return fmt.Errorf("%w: run `mongo schema import` to track it", ErrUntrackedSchema)
+				}
+				return nil
+			}
+
+			dest := importedSchemaPath(out, cfg.MigrationsPath, time.Now())
+			if err := writeImportedSchemaFile(dest, pkg, sel); err != nil {
+				return err
+			}
+			cache = mergeSchemaImportCache(cache, sel)
+			if err := saveSchemaImportCache(cfg.MigrationsPath, cache); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(),
+				"Imported %d collection(s), %d index(es) and %d validator(s) into %s\n",
+				len(sel.Collections), len(sel.Indexes), len(sel.Validators), dest)
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&output, "output", "o", "table", "Output format for --check: table or json")
+	f.StringSliceVar(&filter.Collections, "collection", nil, "Only import collections matching these globs")
+	f.StringSliceVar(&filter.Indexes, "index", nil, "Only import indexes matching these <collection>.<index> globs")
+	f.StringSliceVar(&filter.Exclude, "exclude", nil, "Skip collections or <collection>.<index> matching these globs")
+	f.StringVar(&out, "out", "", "Output .go file or directory (default MIGRATIONS_PATH)")
+	f.StringVar(&pkg, "package", "migrations", "Package name of the generated file")
+	f.BoolVar(&withValidators, "with-validators", false, "Also import validators of collections without a registered one")
+	f.BoolVar(&withOptions, "with-options", false,
+		"Also import options (capped, timeseries, collation, ...) of new collections")
+	f.BoolVar(&check, "check", false, "List untracked schema without writing; exit non-zero if any is found")
+	return cmd
+}
+
+// importedSchemaPath returns out when it names a .go file, otherwise a
+// timestamped file in out or, when empty, in the migrations directory.
+func importedSchemaPath(out, migrationsPath string, now time.Time) string {
+	if strings.HasSuffix(out, ".go") {
+		return out
+	}
+	dir := out
+	if dir == "" {
+		dir = migrationsPath
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s_import_schema_metadata.go", now.UTC().Format("20060102_150405")))
+}
+
+func renderImportSelection(w io.Writer, sel schemaImportSelection) error {
+	if sel.empty() {
+		fmt.Fprintln(w, "No untracked schema found.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tTARGET\tDETAILS")
+	fmt.Fprintln(tw, "----\t------\t-------")
+	for _, coll := range sel.Collections {
+		details := "-"
+		if opts, ok := sel.Options[coll]; ok {
+			details = renderCollectionOptions(opts)
+		}
+		fmt.Fprintf(tw, "collection\t%s\t%s\n", coll, details)
+	}
+	for _, idx := range sel.Indexes {
+		fmt.Fprintf(tw, "index\t%s.%s\t%s\n", idx.Collection, idx.Name, renderBsonD(idx.Keys))
+	}
+	for _, v := range sel.Validators {
+		fmt.Fprintf(tw, "validator\t%s\tlevel=%s\n", v.Collection, v.Level)
+	}
+	return tw.Flush()
+}
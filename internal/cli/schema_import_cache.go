@@ -15,9 +15,12 @@ import (
 const schemaImportCacheFile = ".schema_import_cache.json"
 
 type schemaImportCache struct {
-	Collections      []string           `json:"collections"`
-	IndexSpecs       []schema.IndexSpec `json:"index_specs"`
-	LegacyIndexNames []string           `json:"indexes,omitempty"` // legacy cache shape: collection.index_name
+	Collections []string           `json:"collections"`
+	IndexSpecs  []schema.IndexSpec `json:"index_specs"`
+	// LegacyIndexNames is the old cache shape: collection.index_name.
+	LegacyIndexNames []string                            `json:"indexes,omitempty"`
+	Validators       []schema.ValidatorSpec              `json:"validators,omitempty"`
+	Options          map[string]schema.CollectionOptions `json:"options,omitempty"`
 }
 
 func loadSchemaImportCache(basePath string) (schemaImportCache, error) {
@@ -57,18 +60,23 @@ func saveSchemaImportCache(basePath string, cache schemaImportCache) error {
 	return os.WriteFile(path, data, 0o644)
 }
 
-func mergeSchemaImportCache(
-	base schemaImportCache,
-	collections []string,
-	indexes []schema.IndexSpec,
-) schemaImportCache {
+func mergeSchemaImportCache(base schemaImportCache, sel schemaImportSelection) schemaImportCache {
 	merged := schemaImportCache{
 		Collections:      append([]string{}, base.Collections...),
 		IndexSpecs:       append([]schema.IndexSpec{}, base.IndexSpecs...),
 		LegacyIndexNames: append([]string{}, base.LegacyIndexNames...),
+		Validators:       append([]schema.ValidatorSpec{}, base.Validators...),
+		Options:          make(map[string]schema.CollectionOptions, len(base.Options)+len(sel.Options)),
 	}
-	merged.Collections = append(merged.Collections, collections...)
-	merged.IndexSpecs = append(merged.IndexSpecs, indexes...)
+	for name, opts := range base.Options {
+		merged.Options[name] = opts
+	}
+	for name, opts := range sel.Options {
+		merged.Options[name] = opts
+	}
+	merged.Collections = append(merged.Collections, sel.Collections...)
+	merged.IndexSpecs = append(merged.IndexSpecs, sel.Indexes...)
+	merged.Validators = append(merged.Validators, sel.Validators...)
 	merged.Collections = uniqueSorted(merged.Collections)
 	merged.IndexSpecs = dedupeIndexSpecs(merged.IndexSpecs)
 	merged.LegacyIndexNames = uniqueSorted(merged.LegacyIndexNames)
@@ -77,7 +85,10 @@ func mergeSchemaImportCache(
 
 func applySchemaImportCache(cache schemaImportCache, live diff.SchemaSpec) {
 	for _, collection := range cache.Collections {
-		_ = schema.RegisterCollection(collection)
+		_ = schema.RegisterCollectionWithOptions(collection, cache.Options[collection])
+	}
+	for _, v := range cache.Validators {
+		_ = schema.RegisterValidator(v)
 	}
 
 	for _, idx := range cache.IndexSpecs {
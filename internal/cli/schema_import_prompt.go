@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"go/format"
 	"os"
 	"path/filepath"
 	"sort"
@@ -17,11 +18,14 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
+// maybePromptSchemaImport offers to import untracked collections and indexes
+// when SCHEMA_IMPORT_PROMPT is enabled; `mongo schema import` is the
+// non-interactive equivalent.
 func maybePromptSchemaImport(ctx context.Context, cmd *cobra.Command, cfg *config.Config, db *mongo.Database) error {
-	if cmd == nil || cfg == nil || db == nil {
+	if cmd == nil || cfg == nil || db == nil || !cfg.SchemaImportPrompt {
 		return nil
 	}
-	if !isInteractiveCommand(cmd) {
+	if cmd.Annotations[annotationNoImportPrompt] == "true" || !isInteractiveCommand(cmd) {
 		return nil
 	}
 
@@ -36,24 +40,23 @@ func maybePromptSchemaImport(ctx context.Context, cmd *cobra.Command, cfg *confi
 	applySchemaImportCache(cache, live)
 	target := diff.FromRegistry()
 
-	untrackedCollections := findUntrackedCollections(live, target, cfg.MigrationsCollection)
-	untrackedIndexes := findUntrackedIndexes(live, target)
-	if len(untrackedCollections) == 0 && len(untrackedIndexes) == 0 {
+	sel := selectUntrackedSchema(live, target, diff.RegisteredIgnoreRules(), schemaImportFilter{}, false, false)
+	if sel.empty() {
 		return nil
 	}
 
 	fmt.Fprintf(
 		cmd.OutOrStdout(),
 		"\nDetected %d untracked collection(s) and %d untracked index(es).\n",
-		len(untrackedCollections),
-		len(untrackedIndexes),
+		len(sel.Collections),
+		len(sel.Indexes),
 	)
-	if len(untrackedCollections) > 0 {
-		fmt.Fprintf(cmd.OutOrStdout(), "Collections: %s\n", strings.Join(untrackedCollections, ", "))
+	if len(sel.Collections) > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Collections: %s\n", strings.Join(sel.Collections, ", "))
 	}
-	if len(untrackedIndexes) > 0 {
-		preview := make([]string, 0, len(untrackedIndexes))
-		for _, idx := range untrackedIndexes {
+	if len(sel.Indexes) > 0 {
+		preview := make([]string, 0, len(sel.Indexes))
+		for _, idx := range sel.Indexes {
 			preview = append(preview, idx.Collection+"."+idx.Name)
 		}
 		sort.Strings(preview)
@@ -64,22 +67,14 @@ func maybePromptSchemaImport(ctx context.Context, cmd *cobra.Command, cfg *confi
 		return nil
 	}
 
-	for _, c := range untrackedCollections {
-		if err := schema.RegisterCollection(c); err != nil {
-			return err
-		}
-	}
-	for _, idx := range untrackedIndexes {
-		if err := schema.Register(idx); err != nil {
-			return err
-		}
+	if err := registerImportSelection(sel); err != nil {
+		return err
 	}
-
-	path, err := writeImportedSchemaFile(cfg.MigrationsPath, untrackedCollections, untrackedIndexes)
-	if err != nil {
+	path := importedSchemaPath("", cfg.MigrationsPath, time.Now())
+	if err := writeImportedSchemaFile(path, "migrations", sel); err != nil {
 		return err
 	}
-	cache = mergeSchemaImportCache(cache, untrackedCollections, untrackedIndexes)
+	cache = mergeSchemaImportCache(cache, sel)
 	if err := saveSchemaImportCache(cfg.MigrationsPath, cache); err != nil {
 		return err
 	}
@@ -88,15 +83,36 @@ func maybePromptSchemaImport(ctx context.Context, cmd *cobra.Command, cfg *confi
 	return nil
 }
 
-func findUntrackedCollections(live, target diff.SchemaSpec, migrationCollection string) []string {
-	ignored := map[string]struct{}{
-		migrationCollection:           {},
-		migrationCollection + "_lock": {},
-		"schema_migrations_lock":      {},
+// registerImportSelection adds imported items to the running registries so
+// the current command already sees them as tracked.
+func registerImportSelection(sel schemaImportSelection) error {
+	for _, c := range sel.Collections {
+		if err := schema.RegisterCollectionWithOptions(c, sel.Options[c]); err != nil {
+			return err
+		}
 	}
+	for _, idx := range sel.Indexes {
+		if err := schema.Register(idx); err != nil {
+			return err
+		}
+	}
+	for _, v := range sel.Validators {
+		if err := schema.RegisterValidator(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findUntrackedCollections skips collections covered by ignore and ownership
+// rules, which include system.* and mongork's own bookkeeping collections.
+func findUntrackedCollections(live, target diff.SchemaSpec, rules diff.IgnoreRules) []string {
 	out := make([]string, 0)
 	for collection := range live.Collections {
-		if _, skip := ignored[collection]; skip || strings.HasPrefix(collection, "system.") {
+		if _, ignored := rules.RuleFor("collection", collection); ignored {
+			continue
+		}
+		if _, materialized := target.MaterializedViews[collection]; materialized {
 			continue
 		}
 		if _, tracked := target.Collections[collection]; !tracked {
@@ -107,13 +123,16 @@ func findUntrackedCollections(live, target diff.SchemaSpec, migrationCollection
 	return out
 }
 
-func findUntrackedIndexes(live, target diff.SchemaSpec) []schema.IndexSpec {
+func findUntrackedIndexes(live, target diff.SchemaSpec, rules diff.IgnoreRules) []schema.IndexSpec {
 	out := make([]schema.IndexSpec, 0)
 	for collection, indexes := range live.Indexes {
 		for name, idx := range indexes {
 			if _, tracked := target.Indexes[collection][name]; tracked {
 				continue
 			}
+			if _, ignored := rules.RuleFor("index", collection+"."+name); ignored {
+				continue
+			}
 			out = append(out, schema.IndexSpec{
 				Collection:         idx.Collection,
 				Name:               idx.Name,
@@ -134,32 +153,49 @@ func findUntrackedIndexes(live, target diff.SchemaSpec) []schema.IndexSpec {
 	return out
 }
 
-func writeImportedSchemaFile(basePath string, collections []string, indexes []schema.IndexSpec) (string, error) {
-	if err := os.MkdirAll(basePath, 0o755); err != nil {
-		return "", err
+func writeImportedSchemaFile(fullPath, pkg string, sel schemaImportSelection) error {
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return err
 	}
-	filename := fmt.Sprintf("%s_import_schema_metadata.go", time.Now().UTC().Format("20060102_150405"))
-	fullPath := filepath.Join(basePath, filename)
+	src, err := format.Source([]byte(renderImportedSchema(pkg, sel)))
+	if err != nil {
+		return fmt.Errorf("format generated schema %s: %w", fullPath, err)
+	}
+	return os.WriteFile(fullPath, src, 0o644)
+}
 
+// renderImportedSchema renders an init func registering sel. Pointer fields
+// are built inline so several generated files can share a package.
+func renderImportedSchema(pkg string, sel schemaImportSelection) string {
 	var b strings.Builder
-	b.WriteString("package migrations\n\n")
+	b.WriteString("package " + pkg + "\n\n")
 	b.WriteString("import (\n")
 	b.WriteString("\t\"github.com/drewjocham/mongork/internal/schema\"\n")
-	if len(indexes) > 0 {
+	if needsBsonImport(sel) {
 		b.WriteString("\t\"go.mongodb.org/mongo-driver/v2/bson\"\n")
 	}
 	b.WriteString(")\n\n")
 	b.WriteString("func init() { //nolint:gochecknoinits // generated schema import\n")
-	if len(collections) > 0 {
+
+	var plain []string
+	for _, collection := range sel.Collections {
+		if opts, ok := sel.Options[collection]; ok {
+			b.WriteString(fmt.Sprintf("\tschema.MustRegisterCollectionWithOptions(%q, %s)\n",
+				collection, renderCollectionOptions(opts)))
+			continue
+		}
+		plain = append(plain, collection)
+	}
+	if len(plain) > 0 {
 		b.WriteString("\tschema.MustRegisterCollections(\n")
-		for _, collection := range collections {
+		for _, collection := range plain {
 			b.WriteString(fmt.Sprintf("\t\t%q,\n", collection))
 		}
 		b.WriteString("\t)\n")
 	}
-	if len(indexes) > 0 {
+	if len(sel.Indexes) > 0 {
 		b.WriteString("\tschema.MustRegister(\n")
-		for _, idx := range indexes {
+		for _, idx := range sel.Indexes {
 			b.WriteString("\t\tschema.IndexSpec{\n")
 			b.WriteString(fmt.Sprintf("\t\t\tCollection: %q,\n", idx.Collection))
 			b.WriteString(fmt.Sprintf("\t\t\tName: %q,\n", idx.Name))
@@ -173,7 +209,8 @@ func writeImportedSchemaFile(basePath string, collections []string, indexes []sc
 				b.WriteString("\t\t\tSparse: true,\n")
 			}
 			if idx.ExpireAfterSeconds != nil {
-				b.WriteString(fmt.Sprintf("\t\t\tExpireAfterSeconds: int32Ptr(%d),\n", *idx.ExpireAfterSeconds))
+				b.WriteString(fmt.Sprintf("\t\t\tExpireAfterSeconds: %s,\n",
+					renderPtr("int32", fmt.Sprint(*idx.ExpireAfterSeconds))))
 			}
 			if len(idx.PartialFilter) > 0 {
 				b.WriteString("\t\t\tPartialFilter: ")
@@ -184,28 +221,70 @@ func writeImportedSchemaFile(basePath string, collections []string, indexes []sc
 		}
 		b.WriteString("\t)\n")
 	}
-	if hasTTL(indexes) {
-		b.WriteString("}\n\n")
-		b.WriteString("func int32Ptr(v int32) *int32 { return &v }\n")
-	} else {
-		b.WriteString("}\n")
-	}
-
-	if err := os.WriteFile(fullPath, []byte(b.String()), 0o644); err != nil {
-		return "", err
+	if len(sel.Validators) > 0 {
+		b.WriteString("\tschema.MustRegisterValidator(\n")
+		for _, v := range sel.Validators {
+			b.WriteString("\t\tschema.ValidatorSpec{\n")
+			b.WriteString(fmt.Sprintf("\t\t\tCollection: %q,\n", v.Collection))
+			b.WriteString(fmt.Sprintf("\t\t\tSchema: %s,\n", renderBsonM(v.Schema)))
+			if v.Level != "" {
+				b.WriteString(fmt.Sprintf("\t\t\tLevel: %q,\n", v.Level))
+			}
+			b.WriteString("\t\t},\n")
+		}
+		b.WriteString("\t)\n")
 	}
-	return fullPath, nil
+	b.WriteString("}\n")
+	return b.String()
 }
 
-func hasTTL(indexes []schema.IndexSpec) bool {
-	for _, idx := range indexes {
-		if idx.ExpireAfterSeconds != nil {
+func needsBsonImport(sel schemaImportSelection) bool {
+	if len(sel.Indexes) > 0 || len(sel.Validators) > 0 {
+		return true
+	}
+	for _, opts := range sel.Options {
+		if len(opts.Collation) > 0 {
 			return true
 		}
 	}
 	return false
 }
 
+func renderCollectionOptions(o schema.CollectionOptions) string {
+	var parts []string
+	if o.Capped {
+		parts = append(parts, "Capped: true")
+	}
+	if o.SizeBytes > 0 {
+		parts = append(parts, fmt.Sprintf("SizeBytes: %d", o.SizeBytes))
+	}
+	if o.MaxDocuments > 0 {
+		parts = append(parts, fmt.Sprintf("MaxDocuments: %d", o.MaxDocuments))
+	}
+	if ts := o.TimeSeries; ts != nil {
+		parts = append(parts, fmt.Sprintf(
+			"TimeSeries: &schema.TimeSeriesOptions{TimeField: %q, MetaField: %q, Granularity: %q}",
+			ts.TimeField, ts.MetaField, ts.Granularity))
+	}
+	if o.ClusteredIndex {
+		parts = append(parts, "ClusteredIndex: true")
+	}
+	if o.PreAndPostImages != nil {
+		parts = append(parts, "PreAndPostImages: "+renderPtr("bool", fmt.Sprint(*o.PreAndPostImages)))
+	}
+	if len(o.Collation) > 0 {
+		parts = append(parts, "Collation: "+renderBsonM(o.Collation))
+	}
+	if o.ExpireAfterSeconds != nil {
+		parts = append(parts, "ExpireAfterSeconds: "+renderPtr("int64", fmt.Sprint(*o.ExpireAfterSeconds)))
+	}
+	return "schema.CollectionOptions{" + strings.Join(parts, ", ") + "}"
+}
+
+func renderPtr(typ, value string) string {
+	return fmt.Sprintf("func() *%s { v := %s(%s); return &v }()", typ, typ, value)
+}
+
 func renderBsonD(doc bson.D) string {
 	if len(doc) == 0 {
 		return "bson.D{}"
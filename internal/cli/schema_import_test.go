@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"github.com/drewjocham/mongork/internal/schema/diff"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSelectUntrackedSchemaFiltersAndRenders(t *testing.T) {
+	ttl := int32(3600)
+	images := true
+	live := diff.NewSchemaSpec()
+	for _, coll := range []string{"orders", "events", "tmp_scratch", "schema_migrations"} {
+		live.Collections[coll] = struct{}{}
+	}
+	live.Options["events"] = schema.CollectionOptions{
+		TimeSeries:       &schema.TimeSeriesOptions{TimeField: "ts", Granularity: "hours"},
+		PreAndPostImages: &images,
+	}
+	live.Indexes["orders"] = map[string]diff.IndexSpec{
+		"idx_customer": {Collection: "orders", Name: "idx_customer", Keys: bson.D{{Key: "customer", Value: int32(1)}}},
+		"idx_ttl": {Collection: "orders", Name: "idx_ttl", Keys: bson.D{{Key: "at", Value: int32(1)}},
+			ExpireAfterSeconds: &ttl},
+	}
+	live.Validators["orders"] = diff.ValidatorSpec{
+		Collection: "orders",
+		Schema:     bson.M{"$jsonSchema": bson.M{"required": bson.A{"customer"}}},
+		Level:      "strict",
+	}
+
+	rules := diff.IgnoreRules{{Pattern: "schema_migrations", Owner: "mongork"}}
+	filter := schemaImportFilter{Exclude: []string{"tmp_*", "orders.idx_customer"}}
+	sel := selectUntrackedSchema(live, diff.NewSchemaSpec(), rules, filter, true, true)
+
+	if len(sel.Collections) != 2 || sel.Collections[0] != "events" || sel.Collections[1] != "orders" {
+		t.Fatalf("unexpected collections %v", sel.Collections)
+	}
+	if len(sel.Indexes) != 1 || sel.Indexes[0].Name != "idx_ttl" {
+		t.Fatalf("unexpected indexes %+v", sel.Indexes)
+	}
+	if len(sel.Validators) != 1 || sel.Options["events"].TimeSeries == nil {
+		t.Fatalf("expected validator and options, got %+v", sel)
+	}
+
+	src := renderImportedSchema("imported", sel)
+	if _, err := parser.ParseFile(token.NewFileSet(), "imported.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+}
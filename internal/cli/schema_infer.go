@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var ErrUnsupportedEmit = errors.New("unsupported emit format (use builder, validator or none)")
+
+func newSchemaInferCmd() *cobra.Command {
+	var (
+		output string
+		sample int
+		emit   string
+		level  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "infer <collection>",
+		Short: "Infer document shape from sampled documents",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil || s.MongoClient == nil {
+				return fmt.Errorf("mongo client unavailable")
+			}
+
+			inferred, err := schema.InferSchema(
+				cmd.Context(),
+				s.MongoClient.Database(s.Config.Mongo.Database),
+				args[0],
+				sample,
+			)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			switch strings.ToLower(strings.TrimSpace(emit)) {
+			case "builder":
+				_, err := io.WriteString(out, renderSchemaBuilderSnippet(inferred))
+				return err
+			case "validator":
+				_, err := io.WriteString(out, renderValidatorSnippet(inferred.ValidatorSpec(level)))
+				return err
+			case "", "none":
+			default:
+				return fmt.Errorf("%w: %s", ErrUnsupportedEmit, emit)
+			}
+
+			return renderWithOutput(
+				out,
+				output,
+				ErrUnsupportedOutputFormat,
+				func(w io.Writer) error { return renderInferredTable(w, inferred) },
+				func(w io.Writer) error { return encodePrettyJSON(w, inferred) },
+			)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table or json")
+	cmd.Flags().IntVar(&sample, "sample", 500, "Number of documents to sample")
+	cmd.Flags().StringVar(&emit, "emit", "none", "Emit a Go snippet instead of stats: builder, validator or none")
+	cmd.Flags().StringVar(&level, "level", "moderate", "Validation level used with --emit validator")
+	return cmd
+}
+
+func renderInferredTable(w io.Writer, inferred schema.InferredSchema) error {
+	if inferred.Sampled == 0 {
+		fmt.Fprintf(w, "No documents sampled from %s.\n", inferred.Collection)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Sampled %d document(s) from %s\n\n", inferred.Sampled, inferred.Collection)
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tPRESENT\tNULLS\tTYPES\tARRAY ITEMS")
+	fmt.Fprintln(tw, "----\t-------\t-----\t-----\t-----------")
+	for _, f := range inferred.Fields {
+		items := "-"
+		if f.MinItems != nil && f.MaxItems != nil {
+			items = fmt.Sprintf("%d..%d", *f.MinItems, *f.MaxItems)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", f.Path, f.Present, f.Nulls, formatTypeCounts(f.Types), items)
+	}
+	return tw.Flush()
+}
+
+func formatTypeCounts(types map[string]int64) string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s(%d)", name, types[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func renderSchemaBuilderSnippet(inferred schema.InferredSchema) string {
+	var b strings.Builder
+	b.WriteString("migration.Schema().\n")
+	if required := inferred.Required(); len(required) > 0 {
+		quoted := make([]string, len(required))
+		for i, name := range required {
+			quoted[i] = fmt.Sprintf("%q", name)
+		}
+		b.WriteString(fmt.Sprintf("\tRequired(%s).\n", strings.Join(quoted, ", ")))
+	}
+	for _, f := range inferred.TopLevel() {
+		b.WriteString(fmt.Sprintf("\tField(%q, %s).\n", f.Path, renderBsonM(inferred.PropertySchema(f))))
+	}
+	b.WriteString("\tBuild()\n")
+	return b.String()
+}
+
+func renderValidatorSnippet(spec schema.ValidatorSpec) string {
+	var b strings.Builder
+	b.WriteString("schema.MustRegisterValidator(schema.ValidatorSpec{\n")
+	b.WriteString(fmt.Sprintf("\tCollection:  %q,\n", spec.Collection))
+	b.WriteString(fmt.Sprintf("\tDescription: %q,\n", spec.Description))
+	b.WriteString(fmt.Sprintf("\tLevel:       %q,\n", spec.Level))
+	b.WriteString(fmt.Sprintf("\tSchema:      %s,\n", renderBsonM(spec.Schema)))
+	b.WriteString("})\n")
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/cdc"
+	"github.com/drewjocham/mongork/internal/config"
+	"github.com/drewjocham/mongork/internal/schema"
+	"github.com/drewjocham/mongork/internal/schema/diff"
+)
+
+const mongorkOwner = "mongork"
+
+// registerIgnoreRules marks mongork's own bookkeeping collections as owned by
+// mongork and adds the SCHEMA_IGNORE globs, so none of them show up as drift,
+// in the import prompt or in schema apply.
+func registerIgnoreRules(cfg *config.Config) error {
+	internal := []string{
+		cfg.MigrationsCollection,
+		cfg.MigrationsCollection + "_lock",
+		"schema_migrations_lock",
+		"migration_progress",
+		"migration_control",
+		cfg.SchemaSnapshot.Collection,
+		cdc.DefaultCheckpointColl,
+	}
+	for _, name := range internal {
+		if name == "" {
+			continue
+		}
+		if err := schema.RegisterIgnore(schema.IgnoreRule{Pattern: name, Owner: mongorkOwner}); err != nil {
+			return err
+		}
+	}
+
+	rules, err := diff.ParseIgnore(strings.NewReader(strings.Join(cfg.SchemaIgnore, "\n")))
+	if err != nil {
+		return fmt.Errorf("SCHEMA_IGNORE: %w", err)
+	}
+	for _, rule := range rules {
+		if err := schema.RegisterIgnore(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summarizeIgnored groups ignored diffs by owner, e.g. "3 owned by billing, 1 by rule".
+func summarizeIgnored(rules diff.IgnoreRules, ignored []diff.Diff) string {
+	counts := make(map[string]int)
+	for _, d := range ignored {
+		rule, _ := rules.Rule(d)
+		key := "by rule"
+		if rule.Owner != "" {
+			key = "owned by " + rule.Owner
+		}
+		counts[key]++
+	}
+	parts := make([]string, 0, len(counts))
+	for key, n := range counts {
+		parts = append(parts, fmt.Sprintf("%d %s", n, key))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/config"
+	"github.com/drewjocham/mongork/internal/schema/diff"
+	"github.com/spf13/cobra"
+)
+
+const (
+	snapshotRefLive     = "live"
+	snapshotRefRegistry = "registry"
+	snapshotRefLatest   = "latest"
+	snapshotRefMongo    = "mongo:"
+
+	snapshotStoreFile  = "file"
+	snapshotStoreMongo = "mongo"
+	snapshotStoreNone  = "none"
+)
+
+var ErrUnknownSnapshotStore = errors.New("unknown schema snapshot store")
+
+func newSchemaSnapshotCmd() *cobra.Command {
+	var (
+		output string
+		store  string
+		list   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Capture the live schema as a versioned snapshot",
+		Long: "Serializes collections, options, indexes, validators and views to a JSON file " +
+			"(SCHEMA_SNAPSHOT_PATH) or a Mongo collection (SCHEMA_SNAPSHOT_COLLECTION). " +
+			"Compare snapshots with `mongo schema diff --from <ref> --to <ref>`.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := getServices(cmd.Context())
+			if err != nil || s.MongoClient == nil {
+				return fmt.Errorf("mongo client unavailable")
+			}
+			if store != "" {
+				s.Config.SchemaSnapshot.Store = store
+			}
+			st, err := snapshotStore(s.Config.SchemaSnapshot, s)
+			if err != nil {
+				return err
+			}
+
+			if list {
+				names, err := st.List(cmd.Context())
+				if err != nil {
+					return err
+				}
+				return renderWithOutput(cmd.OutOrStdout(), output, ErrUnsupportedOutputFormat,
+					func(w io.Writer) error {
+						for _, name := range names {
+							fmt.Fprintln(w, name)
+						}
+						return nil
+					},
+					func(w io.Writer) error { return encodePrettyJSON(w, names) },
+				)
+			}
+
+			snap, location, err := captureSnapshot(cmd.Context(), s, st)
+			if err != nil {
+				return err
+			}
+			return renderWithOutput(cmd.OutOrStdout(), output, ErrUnsupportedOutputFormat,
+				func(w io.Writer) error { return renderSnapshotSummary(w, snap, location) },
+				func(w io.Writer) error {
+					return encodePrettyJSON(w, map[string]any{
+						"name":              snap.Name,
+						"location":          location,
+						"database":          snap.Database,
+						"migration_version": snap.MigrationVersion,
+						"collections":       len(snap.Collections),
+						"indexes":           len(snap.Indexes),
+						"validators":        len(snap.Validators),
+						"views":             len(snap.Views),
+					})
+				},
+			)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table or json")
+	cmd.Flags().StringVar(&store, "store", "", "Override SCHEMA_SNAPSHOT_STORE (file or mongo)")
+	cmd.Flags().BoolVar(&list, "list", false, "List stored snapshots instead of taking one")
+	return cmd
+}
+
+func renderSnapshotSummary(w io.Writer, snap diff.Snapshot, location string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SNAPSHOT\tMIGRATION\tCOLLECTIONS\tINDEXES\tVALIDATORS\tVIEWS\tLOCATION")
+	fmt.Fprintln(tw, "--------\t---------\t-----------\t-------\t----------\t-----\t--------")
+	version := snap.MigrationVersion
+	if version == "" {
+		version = "-"
+	}
+	fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", snap.Name, version, len(snap.Collections),
+		len(snap.Indexes), len(snap.Validators), len(snap.Views)+len(snap.MaterializedViews), location)
+	return tw.Flush()
+}
+
+// snapshotStore returns the store selected by cfg, or nil when snapshots are disabled.
+func snapshotStore(cfg config.SnapshotConfig, s *Services) (diff.SnapshotStore, error) {
+	switch strings.ToLower(cfg.Store) {
+	case "", snapshotStoreFile:
+		return diff.FileSnapshotStore{Dir: cfg.Path}, nil
+	case snapshotStoreMongo:
+		if s == nil || s.MongoClient == nil {
+			return nil, fmt.Errorf("mongo client unavailable")
+		}
+		coll := s.MongoClient.Database(s.Config.Mongo.Database).Collection(cfg.Collection)
+		return diff.MongoSnapshotStore{Collection: coll}, nil
+	case snapshotStoreNone:
+		return nil, diff.ErrSnapshotStoreRequired
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSnapshotStore, cfg.Store)
+	}
+}
+
+func captureSnapshot(ctx context.Context, s *Services, st diff.SnapshotStore) (diff.Snapshot, string, error) {
+	db := s.MongoClient.Database(s.Config.Mongo.Database)
+	live, err := diff.InspectLive(ctx, db)
+	if err != nil {
+		return diff.Snapshot{}, "", err
+	}
+	var version string
+	if s.Engine != nil {
+		records, err := s.Engine.ListApplied(ctx)
+		if err != nil {
+			return diff.Snapshot{}, "", err
+		}
+		for _, r := range records {
+			version = max(version, r.Version)
+		}
+	}
+	snap := diff.NewSnapshot(live, db.Name(), version, time.Now())
+	location, err := st.Save(ctx, snap)
+	return snap, location, err
+}
+
+// snapshotAfterUp records the schema reached by a successful `up`. Failures
+// are reported but never fail the migration run that already succeeded.
+func snapshotAfterUp(cmd *cobra.Command) {
+	s, err := getServices(cmd.Context())
+	if err != nil || s.MongoClient == nil {
+		return
+	}
+	st, err := snapshotStore(s.Config.SchemaSnapshot, s)
+	if errors.Is(err, diff.ErrSnapshotStoreRequired) {
+		return
+	}
+	if err == nil {
+		var location string
+		if _, location, err = captureSnapshot(cmd.Context(), s, st); err == nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "📸 Schema snapshot saved to %s\n", location)
+			return
+		}
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "warning: schema snapshot failed: %v\n", err)
+}
+
+// resolveSchemaRef turns a --from/--to reference into a SchemaSpec:
+// "live", "registry", "latest", "mongo:<name|latest>", a snapshot file path,
+// or the name of a snapshot in SCHEMA_SNAPSHOT_PATH.
+func resolveSchemaRef(ctx context.Context, ref string, cfg *config.Config, s *Services) (diff.SchemaSpec, error) {
+	switch {
+	case ref == snapshotRefLive:
+		if s == nil || s.MongoClient == nil {
+			return diff.SchemaSpec{}, fmt.Errorf("mongo client unavailable")
+		}
+		return diff.InspectLive(ctx, s.MongoClient.Database(cfg.Mongo.Database))
+	case ref == snapshotRefRegistry:
+		return diff.FromRegistry(), nil
+	case strings.HasPrefix(ref, snapshotRefMongo):
+		st, err := snapshotStore(config.SnapshotConfig{
+			Store:      snapshotStoreMongo,
+			Collection: cfg.SchemaSnapshot.Collection,
+		}, s)
+		if err != nil {
+			return diff.SchemaSpec{}, err
+		}
+		return loadSnapshotSpec(ctx, st, strings.TrimPrefix(ref, snapshotRefMongo))
+	}
+	if info, err := os.Stat(ref); err == nil && !info.IsDir() {
+		snap, err := diff.ReadSnapshotFile(ref)
+		if err != nil {
+			return diff.SchemaSpec{}, err
+		}
+		return snap.Spec(), nil
+	}
+	return loadSnapshotSpec(ctx, diff.FileSnapshotStore{Dir: cfg.SchemaSnapshot.Path}, ref)
+}
+
+func loadSnapshotSpec(ctx context.Context, st diff.SnapshotStore, name string) (diff.SchemaSpec, error) {
+	var (
+		snap diff.Snapshot
+		err  error
+	)
+	if name == snapshotRefLatest {
+		snap, err = diff.LatestSnapshot(ctx, st)
+	} else {
+		snap, err = st.Load(ctx, name)
+	}
+	if err != nil {
+		return diff.SchemaSpec{}, err
+	}
+	return snap.Spec(), nil
+}
+
+// snapshotRefsOffline reports whether every flag named in the command's
+// annotationOfflineRefs annotation is set to a reference that can be resolved
+// without a database connection.
+func snapshotRefsOffline(cmd *cobra.Command) bool {
+	names := cmd.Annotations[annotationOfflineRefs]
+	if names == "" {
+		return false
+	}
+	for _, name := range strings.Split(names, ",") {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			return false
+		}
+		ref := flag.Value.String()
+		if ref == snapshotRefLive || strings.HasPrefix(ref, snapshotRefMongo) {
+			return false
+		}
+	}
+	return true
+}
@@ -3,13 +3,13 @@ package cli
 import (
 	"context"
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/drewjocham/mongork/internal/cdc"
 	"github.com/drewjocham/mongork/internal/migration"
 	"github.com/drewjocham/mongork/internal/observability"
 	"github.com/drewjocham/mongork/internal/schema/diff"
@@ -200,6 +200,8 @@ type uiRefreshMsg struct {
 	lock        migration.LockInfo
 	dryRun      string
 	stream      []oplogEntry
+	streamErr   error
+	polled      bool
 	mcpEvents   []mcp.Activity
 	schemaDiffs []diff.Diff
 	playbook    playbookState
@@ -253,6 +255,11 @@ type uiModel struct {
 	epsPos        int
 	lastEpsTick   time.Time
 
+	streamFailures   int
+	streamRetryAt    time.Time
+	streamErr        error
+	streamReconnects int
+
 	mcpEvents   []mcp.Activity
 	schemaDiffs []diff.Diff
 	playbook    playbookState
@@ -336,7 +343,7 @@ func (m uiModel) refreshCmd() tea.Cmd {
 			live, e1 := diff.InspectLive(m.ctx, db)
 			target := diff.FromRegistry()
 			if e1 == nil {
-				diffs := diff.Compare(live, target)
+				diffs, _ := diff.CompareWithIgnored(live, target, diff.RegisteredIgnoreRules())
 				msg.dryRun = summarizeDryRun(plan, diffs)
 				msg.schemaDiffs = diffs
 			} else {
@@ -348,14 +355,17 @@ func (m uiModel) refreshCmd() tea.Cmd {
 		if !m.lastSeenTS.IsZero() {
 			streamCfg.from = m.lastSeenTS.UTC().Format(time.RFC3339)
 		}
-		filter, err := buildFilter(streamCfg)
-		if err == nil {
+		filter, err := newOplogFilter(streamCfg)
+		if err == nil && !time.Now().Before(m.streamRetryAt) {
+			msg.polled = true
 			streamEntries, e2 := fetchOplog(m.ctx, client, filter, streamCfg.limit)
 			if e2 == nil {
 				sort.Slice(streamEntries, func(i, j int) bool {
 					return streamEntries[i].TS.T < streamEntries[j].TS.T
 				})
 				msg.stream = streamEntries
+			} else {
+				msg.streamErr = e2
 			}
 		}
 
@@ -417,6 +427,9 @@ func (m uiModel) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 		for _, drift := range msg.drifts {
 			m.driftByVer[drift.Version] = drift.DriftDetected
 		}
+		if msg.polled {
+			m.trackStreamHealth(msg.streamErr)
+		}
 		if !m.paused {
 			before := len(m.streamEvents)
 			for _, event := range msg.stream {
@@ -759,7 +772,13 @@ func (m uiModel) viewLiveStream() string {
 	if !m.lastSeenTS.IsZero() {
 		lag = time.Since(m.lastSeenTS).Truncate(time.Second).String()
 	}
-	fmt.Fprintf(&b, "Live Stream (%s) • EPS %s • Lag %s\n", pauseState, renderSparkline(m.epsRing, m.epsPos), lag)
+	fmt.Fprintf(&b, "Live Stream (%s) • EPS %s • Lag %s • Reconnects %d\n",
+		pauseState, renderSparkline(m.epsRing, m.epsPos), lag, m.streamReconnects)
+	if m.streamErr != nil {
+		retry := max(time.Until(m.streamRetryAt), 0).Truncate(time.Second)
+		fmt.Fprintf(&b, "Stream interrupted (attempt %d, retry in %s): %s\n",
+			m.streamFailures, retry, truncate(m.streamErr.Error(), 80))
+	}
 	fmt.Fprintf(&b, "Filters: %s %s %s\n", m.renderFilterBadge("i"), m.renderFilterBadge("u"), m.renderFilterBadge("d"))
 	if m.resumeToken != "" {
 		fmt.Fprintf(&b, "Resume token (%s): %s\n", m.resumeAt.Format(time.RFC3339), truncate(m.resumeToken, 80))
@@ -971,16 +990,14 @@ func summarizeDryRun(plan []string, diffs []diff.Diff) string {
 }
 
 func readResumeToken(path string) (string, time.Time) {
-	data, err := os.ReadFile(path)
+	cp, err := cdc.NewFileTokenStore(path).Load(context.Background())
 	if err != nil {
 		return "", time.Time{}
 	}
-	stat, _ := os.Stat(path)
-	mod := time.Time{}
-	if stat != nil {
-		mod = stat.ModTime()
+	if data, ok := cp.Token.Lookup("_data").StringValueOK(); ok {
+		return data, cp.UpdatedAt
 	}
-	return string(data), mod
+	return cp.Token.String(), cp.UpdatedAt
 }
 
 func readPlaybookState(ctx context.Context, db *mongo.Database) playbookState {
@@ -1072,6 +1089,21 @@ func eventTime(e oplogEntry) time.Time {
 	return time.Unix(int64(e.TS.T), 0)
 }
 
+// trackStreamHealth backs off oplog polling after failures and counts a
+// reconnect when polling succeeds again.
+func (m *uiModel) trackStreamHealth(err error) {
+	switch {
+	case err != nil:
+		m.streamFailures++
+		m.streamErr = err
+		m.streamRetryAt = time.Now().Add(cdc.DefaultBackoff.Delay(m.streamFailures))
+	case m.streamFailures > 0:
+		m.streamFailures = 0
+		m.streamErr = nil
+		m.streamReconnects++
+	}
+}
+
 func (m *uiModel) bumpEPS(added int) {
 	now := time.Now()
 	if now.Sub(m.lastEpsTick) >= time.Second {
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackStreamHealthCountsReconnects(t *testing.T) {
+	m := uiModel{}
+	m.trackStreamHealth(errors.New("connection refused"))
+	m.trackStreamHealth(errors.New("connection refused"))
+	if m.streamFailures != 2 || !m.streamRetryAt.After(time.Now()) {
+		t.Fatalf("expected 2 failures and a future retry, got %d at %s", m.streamFailures, m.streamRetryAt)
+	}
+
+	m.trackStreamHealth(nil)
+	if m.streamReconnects != 1 || m.streamFailures != 0 || m.streamErr != nil {
+		t.Fatalf("expected one reconnect after recovery, got reconnects=%d failures=%d err=%v",
+			m.streamReconnects, m.streamFailures, m.streamErr)
+	}
+	m.trackStreamHealth(nil)
+	if m.streamReconnects != 1 {
+		t.Fatalf("healthy polls must not count as reconnects, got %d", m.streamReconnects)
+	}
+}
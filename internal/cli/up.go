@@ -54,6 +54,7 @@ func newUpCmd() *cobra.Command {
 			}
 
 			fmt.Fprintln(cmd.OutOrStdout(), "✨ Database is up to date!")
+			snapshotAfterUp(cmd)
 			return nil
 		},
 	}
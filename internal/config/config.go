@@ -26,6 +26,18 @@ type Config struct {
 	LogFile              string           `env:"LOG_FILE" envDefault:"mcp.log"`
 	MigrationsPath       string           `env:"MIGRATIONS_PATH" envDefault:"./migrations"`
 	MigrationsCollection string           `env:"MIGRATIONS_COLLECTION" envDefault:"schema_migrations"`
+	SchemaPath           string           `env:"SCHEMA_PATH" envDefault:"./schema"`
+	SchemaSnapshot       SnapshotConfig   `envPrefix:"SCHEMA_SNAPSHOT_"`
+	SchemaIgnore         []string         `env:"SCHEMA_IGNORE" envSeparator:","`
+	SchemaImportPrompt   bool             `env:"SCHEMA_IMPORT_PROMPT" envDefault:"false"`
+}
+
+// SnapshotConfig controls where schema snapshots are written. Store is
+// "file", "mongo" or "none"; "none" disables the snapshot taken after `up`.
+type SnapshotConfig struct {
+	Store      string `env:"STORE" envDefault:"file"`
+	Path       string `env:"PATH" envDefault:"./schema_snapshots"`
+	Collection string `env:"COLLECTION" envDefault:"schema_snapshots"`
 }
 
 type MongoConfig struct {
@@ -0,0 +1,42 @@
+package migration
+
+import "sort"
+
+// AppliedComparison lists migration versions applied in only one of two
+// environments, e.g. staging and production.
+type AppliedComparison struct {
+	SourceOnly []MigrationRecord `json:"source_only"`
+	TargetOnly []MigrationRecord `json:"target_only"`
+	Common     int               `json:"common"`
+}
+
+// InSync reports whether both environments applied the same versions.
+func (c AppliedComparison) InSync() bool {
+	return len(c.SourceOnly) == 0 && len(c.TargetOnly) == 0
+}
+
+// CompareApplied compares two migration histories as returned by ListApplied.
+func CompareApplied(source, target []MigrationRecord) AppliedComparison {
+	inTarget := make(map[string]struct{}, len(target))
+	for _, r := range target {
+		inTarget[r.Version] = struct{}{}
+	}
+	inSource := make(map[string]struct{}, len(source))
+	out := AppliedComparison{SourceOnly: []MigrationRecord{}, TargetOnly: []MigrationRecord{}}
+	for _, r := range source {
+		inSource[r.Version] = struct{}{}
+		if _, ok := inTarget[r.Version]; ok {
+			out.Common++
+		} else {
+			out.SourceOnly = append(out.SourceOnly, r)
+		}
+	}
+	for _, r := range target {
+		if _, ok := inSource[r.Version]; !ok {
+			out.TargetOnly = append(out.TargetOnly, r)
+		}
+	}
+	sort.Slice(out.SourceOnly, func(i, j int) bool { return out.SourceOnly[i].Version < out.SourceOnly[j].Version })
+	sort.Slice(out.TargetOnly, func(i, j int) bool { return out.TargetOnly[i].Version < out.TargetOnly[j].Version })
+	return out
+}
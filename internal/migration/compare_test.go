@@ -0,0 +1,19 @@
+package migration
+
+import "testing"
+
+func TestCompareApplied(t *testing.T) {
+	source := []MigrationRecord{{Version: "003"}, {Version: "001"}, {Version: "002"}}
+	target := []MigrationRecord{{Version: "001"}, {Version: "004"}}
+
+	got := CompareApplied(source, target)
+	if got.Common != 1 || got.InSync() {
+		t.Fatalf("unexpected comparison %+v", got)
+	}
+	if len(got.SourceOnly) != 2 || got.SourceOnly[0].Version != "002" || got.SourceOnly[1].Version != "003" {
+		t.Fatalf("unexpected source-only versions %+v", got.SourceOnly)
+	}
+	if len(got.TargetOnly) != 1 || got.TargetOnly[0].Version != "004" {
+		t.Fatalf("unexpected target-only versions %+v", got.TargetOnly)
+	}
+}
@@ -26,6 +26,13 @@ type Generator struct {
 }
 
 func (g *Generator) Create(name string) (string, string, error) {
+	return g.CreateWithBody(name, "", "")
+}
+
+// CreateWithBody writes a migration whose Up and Down methods contain the given
+// Go statements instead of the commented examples. Each body runs before the
+// final "return nil" and may return early on error.
+func (g *Generator) CreateWithBody(name, up, down string) (string, string, error) {
 	timestamp := time.Now().Format("20060102_150405")
 
 	cleanName := strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(name))
@@ -41,11 +48,15 @@ func (g *Generator) Create(name string) (string, string, error) {
 		Version     string
 		Description string
 		StructName  string
+		Up          string
+		Down        string
 	}{
 		PackageName: filepath.Base(g.OutputPath),
 		Version:     version,
 		Description: name,
 		StructName:  "Migration_" + version,
+		Up:          strings.TrimRight(up, "\n"),
+		Down:        strings.TrimRight(down, "\n"),
 	}
 
 	tmpl, err := template.New("migration").Parse(migrationTemplate)
@@ -0,0 +1,27 @@
+package migration
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGeneratorCreateWithBody(t *testing.T) {
+	gen := &Generator{OutputPath: t.TempDir()}
+	up := "\tif err := DoSomething(ctx); err != nil {\n\t\treturn err\n\t}\n"
+	path, _, err := gen.CreateWithBody("add indexes", up, "")
+	if err != nil {
+		t.Fatalf("CreateWithBody returned error: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read generated file: %v", err)
+	}
+	src := string(raw)
+	if !strings.Contains(src, up+"\treturn nil") {
+		t.Fatalf("expected Up body before return, got:\n%s", src)
+	}
+	if !strings.Contains(src, "// Example rollback:") {
+		t.Fatal("expected default Down example when no body is given")
+	}
+}
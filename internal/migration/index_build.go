@@ -0,0 +1,226 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultBuildProgressInterval = 2 * time.Second
+	tempIndexSuffix              = "__rolling"
+)
+
+var (
+	ErrUnhideIndexFailed = errors.New("unhide index failed")
+	ErrIndexNameRequired = errors.New("index name is required to replace an index")
+	ErrReplaceUniqueTTL  = errors.New("unique and TTL indexes cannot be replaced in place; recreate them in a migration")
+)
+
+// IndexBuildProgress is a snapshot of an in-progress index build from $currentOp.
+type IndexBuildProgress struct {
+	Namespace string
+	OpID      string
+	Message   string
+	Done      int64
+	Total     int64
+}
+
+// Percent returns the build completion percentage, or 0 when unknown.
+func (p IndexBuildProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Done) / float64(p.Total) * 100
+}
+
+// WithHiddenBuild creates indexes hidden from the query planner and unhides
+// them once every build has committed, so plans only switch to a fully built index.
+func WithHiddenBuild() IndexCreateOption {
+	return func(cfg *indexCreateConfig) {
+		cfg.hidden = true
+	}
+}
+
+// WithCommitQuorum sets the createIndexes commitQuorum ("majority",
+// "votingMembers" or a replica set tag name).
+func WithCommitQuorum(quorum string) IndexCreateOption {
+	return func(cfg *indexCreateConfig) {
+		cfg.commitQuorum = func(opts *options.CreateIndexesOptionsBuilder) {
+			opts.SetCommitQuorumString(quorum)
+		}
+	}
+}
+
+// WithCommitQuorumMembers sets commitQuorum to a number of data-bearing members.
+func WithCommitQuorumMembers(members int32) IndexCreateOption {
+	return func(cfg *indexCreateConfig) {
+		cfg.commitQuorum = func(opts *options.CreateIndexesOptionsBuilder) {
+			opts.SetCommitQuorumInt(members)
+		}
+	}
+}
+
+// WithBuildProgress polls $currentOp while indexes build and reports progress.
+// A non-positive interval uses the default of two seconds.
+func WithBuildProgress(interval time.Duration, fn func(IndexBuildProgress)) IndexCreateOption {
+	return func(cfg *indexCreateConfig) {
+		if interval <= 0 {
+			interval = defaultBuildProgressInterval
+		}
+		cfg.progressInterval = interval
+		cfg.progress = fn
+	}
+}
+
+// ReplaceIndex changes an index definition without leaving queries uncovered.
+// It builds a temporary index ("<name>__rolling", keyed with a trailing _id so
+// it never conflicts), drops the old index, builds the new definition under
+// the original name and finally drops the temporary index.
+//
+// Unique and TTL indexes are refused with ErrReplaceUniqueTTL: the temporary
+// index cannot enforce uniqueness or expiry, so duplicates written during the
+// swap would make the final build fail and leave no index at all.
+func ReplaceIndex(ctx context.Context, coll *mongo.Collection, idx *IndexBuilder, opts ...IndexCreateOption) error {
+	if idx == nil {
+		return ErrIndexMustDefineKey
+	}
+	model := idx.Model()
+	values, err := buildIndexOptions(model.Options)
+	if err != nil {
+		return err
+	}
+	if values.Name == nil || *values.Name == "" {
+		return ErrIndexNameRequired
+	}
+	if (values.Unique != nil && *values.Unique) || values.ExpireAfterSeconds != nil {
+		return ErrReplaceUniqueTTL
+	}
+	cfg := newIndexCreateConfig(opts)
+	name := *values.Name
+
+	temp, err := temporaryIndex(model, values, name+tempIndexSuffix)
+	if err != nil {
+		return err
+	}
+	if err := CreateIndexesWithOptions(ctx, coll, opts, temp); err != nil {
+		return err
+	}
+	if err := DropIndexes(ctx, coll, cfg.finalName(name)); err != nil {
+		return err
+	}
+	if err := CreateIndexesWithOptions(ctx, coll, opts, idx); err != nil {
+		return err
+	}
+	return DropIndexes(ctx, coll, cfg.finalName(name+tempIndexSuffix))
+}
+
+// temporaryIndex copies the index with a trailing _id key. It only serves
+// reads during the swap.
+func temporaryIndex(model mongo.IndexModel, values *options.IndexOptions, name string) (*IndexBuilder, error) {
+	keys, ok := model.Keys.(bson.D)
+	if !ok || len(keys) == 0 {
+		return nil, ErrIndexMustDefineKey
+	}
+
+	temp := &IndexBuilder{model: mongo.IndexModel{Keys: bson.D{}}}
+	hasID := false
+	for _, key := range keys {
+		temp.Key(key.Key, key.Value)
+		hasID = hasID || key.Key == "_id"
+	}
+	if !hasID {
+		temp.Key("_id", 1)
+	}
+	temp.Name(name)
+	if values.Sparse != nil && *values.Sparse {
+		temp.Sparse()
+	}
+	if values.PartialFilterExpression != nil {
+		temp.Partial(values.PartialFilterExpression)
+	}
+	return temp, nil
+}
+
+func unhideIndexes(ctx context.Context, coll *mongo.Collection, models []mongo.IndexModel) error {
+	for _, model := range models {
+		values, err := buildIndexOptions(model.Options)
+		if err != nil {
+			return err
+		}
+		cmd := bson.D{
+			{Key: "collMod", Value: coll.Name()},
+			{Key: "index", Value: bson.D{{Key: "name", Value: *values.Name}, {Key: "hidden", Value: false}}},
+		}
+		if err := coll.Database().RunCommand(ctx, cmd).Err(); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrUnhideIndexFailed, *values.Name, err)
+		}
+	}
+	return nil
+}
+
+// watchIndexBuilds reports build progress until ctx is cancelled.
+func watchIndexBuilds(ctx context.Context, coll *mongo.Collection, interval time.Duration, fn func(IndexBuildProgress)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			builds, err := currentIndexBuilds(ctx, coll)
+			if err != nil {
+				continue
+			}
+			for _, build := range builds {
+				fn(build)
+			}
+		}
+	}
+}
+
+func currentIndexBuilds(ctx context.Context, coll *mongo.Collection) ([]IndexBuildProgress, error) {
+	ns := coll.Database().Name() + "." + coll.Name()
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$currentOp", Value: bson.M{"allUsers": true, "idleConnections": false}}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"ns":  bson.Regex{Pattern: "^" + regexp.QuoteMeta(ns) + "$"},
+			"msg": bson.Regex{Pattern: "^Index Build"},
+		}}},
+	}
+	cur, err := coll.Database().Client().Database("admin").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []IndexBuildProgress
+	for cur.Next(ctx) {
+		var op struct {
+			OpID     any    `bson:"opid"`
+			Msg      string `bson:"msg"`
+			Progress struct {
+				Done  int64 `bson:"done"`
+				Total int64 `bson:"total"`
+			} `bson:"progress"`
+		}
+		if err := cur.Decode(&op); err != nil {
+			return nil, err
+		}
+		out = append(out, IndexBuildProgress{
+			Namespace: ns,
+			OpID:      fmt.Sprintf("%v", op.OpID),
+			Message:   op.Msg,
+			Done:      op.Progress.Done,
+			Total:     op.Progress.Total,
+		})
+	}
+	return out, cur.Err()
+}
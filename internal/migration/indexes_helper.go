@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -34,9 +36,13 @@ type IndexBuilder struct {
 type IndexCreateOption func(*indexCreateConfig)
 
 type indexCreateConfig struct {
-	nameOverride func(string) string
-	namePrefix   string
-	nameSuffix   string
+	nameOverride     func(string) string
+	namePrefix       string
+	nameSuffix       string
+	hidden           bool
+	commitQuorum     func(*options.CreateIndexesOptionsBuilder)
+	progress         func(IndexBuildProgress)
+	progressInterval time.Duration
 }
 
 func WithIndexNamePrefix(prefix string) IndexCreateOption {
@@ -99,6 +105,12 @@ func (b *IndexBuilder) TTL(seconds int32) *IndexBuilder {
 	return b
 }
 
+func (b *IndexBuilder) Hidden() *IndexBuilder {
+	opts := b.ensureOptions()
+	opts.SetHidden(true)
+	return b
+}
+
 func (b *IndexBuilder) Partial(expr interface{}) *IndexBuilder {
 	opts := b.ensureOptions()
 	opts.SetPartialFilterExpression(expr)
@@ -123,20 +135,14 @@ func CreateIndexesWithOptions(
 		return nil
 	}
 
-	cfg := &indexCreateConfig{}
-	for _, opt := range opts {
-		if opt != nil {
-			opt(cfg)
-		}
-	}
-
+	cfg := newIndexCreateConfig(opts)
 	models := make([]mongo.IndexModel, 0, len(indexes))
 	for _, idx := range indexes {
 		if idx == nil {
 			continue
 		}
-		model := idx.Model()
-		if err := ensureIndexName(&model, cfg); err != nil {
+		model, err := indexModel(idx, cfg)
+		if err != nil {
 			return err
 		}
 		models = append(models, model)
@@ -146,10 +152,24 @@ func CreateIndexesWithOptions(
 		return nil
 	}
 
-	_, err := coll.Indexes().CreateMany(ctx, models)
+	createOpts := options.CreateIndexes()
+	if cfg.commitQuorum != nil {
+		cfg.commitQuorum(createOpts)
+	}
+
+	if cfg.progress != nil {
+		watchCtx, stopWatching := context.WithCancel(ctx)
+		defer stopWatching()
+		go watchIndexBuilds(watchCtx, coll, cfg.progressInterval, cfg.progress)
+	}
+
+	_, err := coll.Indexes().CreateMany(ctx, models, createOpts)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrCreateIndexesFailed, err)
 	}
+	if cfg.hidden {
+		return unhideIndexes(ctx, coll, models)
+	}
 	return nil
 }
 
@@ -168,6 +188,23 @@ func DropIndexes(ctx context.Context, coll *mongo.Collection, names ...string) e
 	return nil
 }
 
+// indexModel returns the model to create for idx. Naming and hiding add
+// option setters, so they are applied to a copy and the caller's builder can
+// be reused.
+func indexModel(idx *IndexBuilder, cfg *indexCreateConfig) (mongo.IndexModel, error) {
+	model := idx.Model()
+	if model.Options != nil {
+		model.Options = &options.IndexOptionsBuilder{Opts: slices.Clone(model.Options.Opts)}
+	}
+	if err := ensureIndexName(&model, cfg); err != nil {
+		return mongo.IndexModel{}, err
+	}
+	if cfg.hidden {
+		model.Options.SetHidden(true)
+	}
+	return model, nil
+}
+
 func ensureIndexName(model *mongo.IndexModel, cfg *indexCreateConfig) error {
 	keys, ok := model.Keys.(bson.D)
 	if !ok || len(keys) == 0 {
@@ -181,14 +218,7 @@ func ensureIndexName(model *mongo.IndexModel, cfg *indexCreateConfig) error {
 	}
 
 	if values.Name != nil && *values.Name != "" {
-		name := *values.Name
-		if cfg != nil && cfg.nameOverride != nil {
-			name = cfg.nameOverride(name)
-		}
-		if cfg != nil {
-			name = cfg.namePrefix + name + cfg.nameSuffix
-		}
-		opts.SetName(name)
+		opts.SetName(cfg.finalName(*values.Name))
 		return nil
 	}
 
@@ -198,14 +228,29 @@ func ensureIndexName(model *mongo.IndexModel, cfg *indexCreateConfig) error {
 	if optsSuffix != "" {
 		name = base + "_" + optsSuffix
 	}
-	if cfg != nil {
-		if cfg.nameOverride != nil {
-			name = cfg.nameOverride(name)
+	opts.SetName(cfg.finalName(name))
+	return nil
+}
+
+func newIndexCreateConfig(opts []IndexCreateOption) *indexCreateConfig {
+	cfg := &indexCreateConfig{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
 		}
-		name = cfg.namePrefix + name + cfg.nameSuffix
 	}
-	opts.SetName(name)
-	return nil
+	return cfg
+}
+
+// finalName applies the configured override, prefix and suffix to an index name.
+func (cfg *indexCreateConfig) finalName(name string) string {
+	if cfg == nil {
+		return name
+	}
+	if cfg.nameOverride != nil {
+		name = cfg.nameOverride(name)
+	}
+	return cfg.namePrefix + name + cfg.nameSuffix
 }
 
 func buildIndexBaseName(keys bson.D) string {
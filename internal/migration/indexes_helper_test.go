@@ -1,6 +1,8 @@
 package migration
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -45,3 +47,64 @@ func TestBuildIndexOptionsSuffix(t *testing.T) {
 		t.Fatalf("unexpected suffix %s", got)
 	}
 }
+
+func TestTemporaryIndexAppendsIDAndDropsUniqueness(t *testing.T) {
+	model := mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}}
+	values, err := buildIndexOptions(options.Index().SetUnique(true).SetSparse(true))
+	if err != nil {
+		t.Fatalf("buildIndexOptions returned error: %v", err)
+	}
+
+	temp, err := temporaryIndex(model, values, "email_1__rolling")
+	if err != nil {
+		t.Fatalf("temporaryIndex returned error: %v", err)
+	}
+	keys := temp.model.Keys.(bson.D)
+	if len(keys) != 2 || keys[1].Key != "_id" {
+		t.Fatalf("expected trailing _id key, got %v", keys)
+	}
+	applied, err := buildIndexOptions(temp.model.Options)
+	if err != nil {
+		t.Fatalf("buildIndexOptions returned error: %v", err)
+	}
+	if applied.Unique != nil && *applied.Unique {
+		t.Fatal("temporary index must not be unique")
+	}
+	if applied.Sparse == nil || !*applied.Sparse {
+		t.Fatal("expected sparse to be preserved")
+	}
+	if applied.Name == nil || *applied.Name != "email_1__rolling" {
+		t.Fatalf("unexpected name %v", applied.Name)
+	}
+}
+
+func TestIndexModelLeavesBuilderUnchanged(t *testing.T) {
+	idx := Index().Key("email", 1).Unique()
+	before := len(idx.model.Options.Opts)
+
+	model, err := indexModel(idx, &indexCreateConfig{hidden: true, namePrefix: "pfx_"})
+	if err != nil {
+		t.Fatalf("indexModel returned error: %v", err)
+	}
+	applied, err := buildIndexOptions(model.Options)
+	if err != nil {
+		t.Fatalf("buildIndexOptions returned error: %v", err)
+	}
+	if applied.Hidden == nil || !*applied.Hidden || applied.Name == nil {
+		t.Fatalf("expected hidden, named model, got %+v", applied)
+	}
+	if got := len(idx.model.Options.Opts); got != before {
+		t.Fatalf("builder gained %d option setters", got-before)
+	}
+}
+
+func TestReplaceIndexRefusesUniqueAndTTL(t *testing.T) {
+	for _, idx := range []*IndexBuilder{
+		Index().Key("email", 1).Name("email_1").Unique(),
+		Index().Key("created_at", 1).Name("created_at_1").TTL(3600),
+	} {
+		if err := ReplaceIndex(context.Background(), nil, idx); !errors.Is(err, ErrReplaceUniqueTTL) {
+			t.Fatalf("expected ErrReplaceUniqueTTL, got %v", err)
+		}
+	}
+}
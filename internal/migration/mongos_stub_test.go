@@ -0,0 +1,193 @@
+package migration
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	opReply = 1
+	opQuery = 2004
+	opMsg   = 2013
+)
+
+// mongosStub is a local stand-in for mongos: it speaks enough of the wire
+// protocol for the driver to connect, records every command, and answers
+// sharding commands with the checks mongos makes.
+type mongosStub struct {
+	addr string
+
+	mu       sync.Mutex
+	commands []bson.D
+	zones    map[string]int
+}
+
+func newMongosStub(t *testing.T) *mongosStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &mongosStub{addr: ln.Addr().String(), zones: map[string]int{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// Commands returns the names of the sharding commands received, in order.
+func (s *mongosStub) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, cmd := range s.commands {
+		switch cmd[0].Key {
+		case "shardCollection", "updateZoneKeyRange", "split", "reshardCollection":
+			names = append(names, cmd[0].Key)
+		}
+	}
+	return names
+}
+
+func (s *mongosStub) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var header [16]byte
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			return
+		}
+		size := int(binary.LittleEndian.Uint32(header[0:]))
+		requestID := binary.LittleEndian.Uint32(header[4:])
+		opCode := binary.LittleEndian.Uint32(header[12:])
+		body := make([]byte, size-16)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+
+		var reply []byte
+		switch opCode {
+		case opQuery:
+			// flags, then the cstring namespace, skip and limit, then the query.
+			rest := body[4:]
+			for len(rest) > 0 && rest[0] != 0 {
+				rest = rest[1:]
+			}
+			cmd, err := readDoc(rest[9:])
+			if err != nil {
+				return
+			}
+			doc := s.handle(cmd)
+			reply = binary.LittleEndian.AppendUint32(nil, 0)
+			reply = binary.LittleEndian.AppendUint64(reply, 0)
+			reply = binary.LittleEndian.AppendUint32(reply, 0)
+			reply = binary.LittleEndian.AppendUint32(reply, 1)
+			reply = append(reply, doc...)
+			reply = frame(requestID, opReply, reply)
+		case opMsg:
+			// flagBits, then a kind 0 section holding the command.
+			if body[4] != 0 {
+				return
+			}
+			cmd, err := readDoc(body[5:])
+			if err != nil {
+				return
+			}
+			reply = binary.LittleEndian.AppendUint32(nil, 0)
+			reply = append(reply, 0)
+			reply = append(reply, s.handle(cmd)...)
+			reply = frame(requestID, opMsg, reply)
+		default:
+			return
+		}
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *mongosStub) handle(cmd bson.D) []byte {
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	s.mu.Unlock()
+
+	reply := bson.D{{Key: "ok", Value: 1.0}}
+	switch cmd[0].Key {
+	case "hello", "isMaster", "ismaster":
+		reply = bson.D{
+			{Key: "ok", Value: 1.0},
+			{Key: "isWritablePrimary", Value: true},
+			{Key: "helloOk", Value: true},
+			{Key: "msg", Value: "isdbgrid"},
+			{Key: "minWireVersion", Value: int32(0)},
+			{Key: "maxWireVersion", Value: int32(21)},
+			{Key: "maxBsonObjectSize", Value: int32(16 * 1024 * 1024)},
+			{Key: "maxMessageSizeBytes", Value: int32(48 * 1000 * 1000)},
+			{Key: "maxWriteBatchSize", Value: int32(100000)},
+			{Key: "logicalSessionTimeoutMinutes", Value: int32(30)},
+			{Key: "localTime", Value: time.Now()},
+		}
+	case "updateZoneKeyRange":
+		s.mu.Lock()
+		s.zones[cmd[0].Value.(string)]++
+		s.mu.Unlock()
+	case "shardCollection":
+		s.mu.Lock()
+		zones := s.zones[cmd[0].Value.(string)]
+		s.mu.Unlock()
+		for _, e := range cmd {
+			if e.Key == "presplitHashedZones" && e.Value == true && zones == 0 {
+				reply = bson.D{
+					{Key: "ok", Value: 0.0},
+					{Key: "code", Value: int32(72)},
+					{Key: "codeName", Value: "InvalidOptions"},
+					{Key: "errmsg", Value: "presplitHashedZones requires zones to be defined for the namespace"},
+				}
+			}
+		}
+	}
+	raw, err := bson.Marshal(reply)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+func readDoc(b []byte) (bson.D, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short document")
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	if n > len(b) {
+		return nil, errors.New("short document")
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(b[:n], &doc); err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return doc, nil
+}
+
+func frame(responseTo uint32, opCode uint32, body []byte) []byte {
+	out := binary.LittleEndian.AppendUint32(nil, uint32(16+len(body)))
+	out = binary.LittleEndian.AppendUint32(out, responseTo+1)
+	out = binary.LittleEndian.AppendUint32(out, responseTo)
+	out = binary.LittleEndian.AppendUint32(out, opCode)
+	return append(out, body...)
+}
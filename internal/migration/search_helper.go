@@ -0,0 +1,113 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const defaultSearchIndexPoll = 5 * time.Second
+
+var ErrSearchIndexFailed = errors.New("search index build failed")
+
+// SearchIndexStatus is the state reported by $listSearchIndexes.
+type SearchIndexStatus struct {
+	Name      string `bson:"name"`
+	Type      string `bson:"type"`
+	Status    string `bson:"status"`
+	Queryable bool   `bson:"queryable"`
+}
+
+// CreateSearchIndex submits a search or vector search index build. Builds run
+// asynchronously; use WaitForSearchIndex when later steps need to query it.
+func CreateSearchIndex(ctx context.Context, coll *mongo.Collection, spec schema.SearchIndexSpec) error {
+	model := mongo.SearchIndexModel{
+		Definition: spec.Definition,
+		Options:    options.SearchIndexes().SetName(spec.Name).SetType(spec.IndexType()),
+	}
+	if _, err := coll.SearchIndexes().CreateOne(ctx, model); err != nil {
+		return fmt.Errorf("create search index %s: %w", spec.Name, err)
+	}
+	return nil
+}
+
+// UpdateSearchIndex replaces the definition. The old index keeps serving
+// queries until the new definition is built.
+func UpdateSearchIndex(ctx context.Context, coll *mongo.Collection, spec schema.SearchIndexSpec) error {
+	if err := coll.SearchIndexes().UpdateOne(ctx, spec.Name, spec.Definition); err != nil {
+		return fmt.Errorf("update search index %s: %w", spec.Name, err)
+	}
+	return nil
+}
+
+func DropSearchIndex(ctx context.Context, coll *mongo.Collection, name string) error {
+	if err := coll.SearchIndexes().DropOne(ctx, name); err != nil {
+		return fmt.Errorf("drop search index %s: %w", name, err)
+	}
+	return nil
+}
+
+// WaitForSearchIndex polls until the index is queryable, fails, or ctx ends.
+// A poll interval of zero uses five seconds.
+func WaitForSearchIndex(ctx context.Context, coll *mongo.Collection, name string, poll time.Duration) error {
+	if poll <= 0 {
+		poll = defaultSearchIndexPoll
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		status, err := searchIndexStatus(ctx, coll, name)
+		if err != nil {
+			return err
+		}
+		if status.Status == "FAILED" {
+			return fmt.Errorf("%w: %s", ErrSearchIndexFailed, name)
+		}
+		if status.Queryable && status.Status == "READY" {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("search index %s not queryable (status %s): %w", name, status.Status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func searchIndexStatus(ctx context.Context, coll *mongo.Collection, name string) (SearchIndexStatus, error) {
+	cur, err := coll.SearchIndexes().List(ctx, options.SearchIndexes().SetName(name))
+	if err != nil {
+		return SearchIndexStatus{}, err
+	}
+	var rows []SearchIndexStatus
+	if err := cur.All(ctx, &rows); err != nil {
+		return SearchIndexStatus{}, err
+	}
+	for _, row := range rows {
+		if row.Name == name {
+			return row, nil
+		}
+	}
+	// A freshly submitted index can take a moment to be listed.
+	return SearchIndexStatus{Name: name, Status: "PENDING"}, nil
+}
+
+// ListSearchIndexStatuses returns every search index on coll with its status.
+func ListSearchIndexStatuses(ctx context.Context, coll *mongo.Collection) ([]SearchIndexStatus, error) {
+	cur, err := coll.SearchIndexes().List(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	var rows []SearchIndexStatus
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
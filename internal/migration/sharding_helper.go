@@ -0,0 +1,95 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ShardCollection assigns zone ranges, shards db.<spec.Collection> on
+// spec.Key and presplits chunks. Zones come first: a hashed key with zones is
+// sharded with presplitHashedZones, which needs the ranges to exist already.
+// Sharding commands run against the admin database, so db must come from a
+// client connected to mongos.
+func ShardCollection(ctx context.Context, db *mongo.Database, spec schema.ShardKeySpec) error {
+	admin := db.Client().Database("admin")
+	ns := db.Name() + "." + spec.Collection
+	if err := UpdateZones(ctx, db, spec.Collection, spec.Zones); err != nil {
+		return err
+	}
+	if err := admin.RunCommand(ctx, shardCollectionCommand(ns, spec)).Err(); err != nil {
+		return fmt.Errorf("shardCollection %s: %w", ns, err)
+	}
+	for _, point := range spec.PresplitPoints {
+		cmd := bson.D{{Key: "split", Value: ns}, {Key: "middle", Value: point}}
+		if err := admin.RunCommand(ctx, cmd).Err(); err != nil {
+			return fmt.Errorf("split %s at %v: %w", ns, point, err)
+		}
+	}
+	return nil
+}
+
+// ReshardCollection changes the shard key of an already sharded collection.
+// Resharding copies every document; expect it to take as long as an initial sync
+// of the collection and to need free space for a second copy.
+func ReshardCollection(ctx context.Context, db *mongo.Database, spec schema.ShardKeySpec) error {
+	ns := db.Name() + "." + spec.Collection
+	if err := db.Client().Database("admin").RunCommand(ctx, reshardCollectionCommand(ns, spec)).Err(); err != nil {
+		return fmt.Errorf("reshardCollection %s: %w", ns, err)
+	}
+	return nil
+}
+
+// UpdateZones assigns each zone's key range. Shards must already be tagged
+// with the zone names (sh.addShardToZone or AddShardToZone).
+func UpdateZones(ctx context.Context, db *mongo.Database, collection string, zones []schema.ZoneSpec) error {
+	admin := db.Client().Database("admin")
+	ns := db.Name() + "." + collection
+	for _, z := range zones {
+		cmd := bson.D{
+			{Key: "updateZoneKeyRange", Value: ns},
+			{Key: "min", Value: z.Min},
+			{Key: "max", Value: z.Max},
+			{Key: "zone", Value: z.Name},
+		}
+		if err := admin.RunCommand(ctx, cmd).Err(); err != nil {
+			return fmt.Errorf("updateZoneKeyRange %s zone %s: %w", ns, z.Name, err)
+		}
+	}
+	return nil
+}
+
+func AddShardToZone(ctx context.Context, client *mongo.Client, shard, zone string) error {
+	cmd := bson.D{{Key: "addShardToZone", Value: shard}, {Key: "zone", Value: zone}}
+	return client.Database("admin").RunCommand(ctx, cmd).Err()
+}
+
+func shardCollectionCommand(ns string, spec schema.ShardKeySpec) bson.D {
+	cmd := bson.D{{Key: "shardCollection", Value: ns}, {Key: "key", Value: spec.Key}}
+	if spec.Unique {
+		cmd = append(cmd, bson.E{Key: "unique", Value: true})
+	}
+	// Hashed keys with zones must create chunks per zone up front.
+	if spec.Hashed() && len(spec.Zones) > 0 {
+		cmd = append(cmd, bson.E{Key: "presplitHashedZones", Value: true})
+	}
+	return cmd
+}
+
+func reshardCollectionCommand(ns string, spec schema.ShardKeySpec) bson.D {
+	cmd := bson.D{{Key: "reshardCollection", Value: ns}, {Key: "key", Value: spec.Key}}
+	if spec.Unique {
+		cmd = append(cmd, bson.E{Key: "unique", Value: true})
+	}
+	if len(spec.Zones) > 0 {
+		zones := make(bson.A, 0, len(spec.Zones))
+		for _, z := range spec.Zones {
+			zones = append(zones, bson.D{{Key: "zone", Value: z.Name}, {Key: "min", Value: z.Min}, {Key: "max", Value: z.Max}})
+		}
+		cmd = append(cmd, bson.E{Key: "zones", Value: zones})
+	}
+	return cmd
+}
@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestShardingCommands(t *testing.T) {
+	spec := schema.ShardKeySpec{
+		Collection: "events",
+		Key:        bson.D{{Key: "tenant", Value: int32(1)}, {Key: "_id", Value: "hashed"}},
+		Zones: []schema.ZoneSpec{{
+			Name: "eu",
+			Min:  bson.D{{Key: "tenant", Value: "eu"}, {Key: "_id", Value: bson.MinKey{}}},
+			Max:  bson.D{{Key: "tenant", Value: "ev"}, {Key: "_id", Value: bson.MinKey{}}},
+		}},
+	}
+
+	shard := shardCollectionCommand("app.events", spec)
+	if shard[0].Key != "shardCollection" || shard[0].Value != "app.events" {
+		t.Fatalf("shardCollection must be the first field, got %v", shard)
+	}
+	if shard[len(shard)-1].Key != "presplitHashedZones" {
+		t.Fatalf("expected presplitHashedZones for hashed key with zones, got %v", shard)
+	}
+
+	reshard := reshardCollectionCommand("app.events", spec)
+	zones, ok := reshard[len(reshard)-1].Value.(bson.A)
+	if reshard[0].Key != "reshardCollection" || !ok || len(zones) != 1 {
+		t.Fatalf("unexpected reshardCollection command %v", reshard)
+	}
+}
+
+func TestShardCollectionAgainstMongosStub(t *testing.T) {
+	stub := newMongosStub(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://" + stub.addr).
+		SetServerSelectionTimeout(5 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	spec := schema.ShardKeySpec{
+		Collection: "events",
+		Key:        bson.D{{Key: "tenant", Value: int32(1)}, {Key: "_id", Value: "hashed"}},
+		Zones: []schema.ZoneSpec{{
+			Name: "eu",
+			Min:  bson.D{{Key: "tenant", Value: "eu"}, {Key: "_id", Value: bson.MinKey{}}},
+			Max:  bson.D{{Key: "tenant", Value: "ev"}, {Key: "_id", Value: bson.MinKey{}}},
+		}},
+		PresplitPoints: []bson.D{{{Key: "tenant", Value: "us"}, {Key: "_id", Value: bson.MinKey{}}}},
+	}
+	if err := ShardCollection(ctx, client.Database("app"), spec); err != nil {
+		t.Fatalf("ShardCollection: %v", err)
+	}
+	want := []string{"updateZoneKeyRange", "shardCollection", "split"}
+	if got := stub.Commands(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands = %v, want %v", got, want)
+	}
+}
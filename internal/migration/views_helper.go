@@ -0,0 +1,65 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrCreateViewFailed = errors.New("create view failed")
+	ErrModifyViewFailed = errors.New("modify view failed")
+	ErrDropViewFailed   = errors.New("drop view failed")
+)
+
+// CreateView creates a standard view over source.
+func CreateView(ctx context.Context, db *mongo.Database, name, source string, pipeline mongo.Pipeline,
+	collation *options.Collation) error {
+	opts := options.CreateView()
+	if collation != nil {
+		opts.SetCollation(collation)
+	}
+	if err := db.CreateView(ctx, name, source, pipeline, opts); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrCreateViewFailed, name, err)
+	}
+	return nil
+}
+
+// ModifyView replaces the source and pipeline of an existing view via collMod.
+func ModifyView(ctx context.Context, db *mongo.Database, name, source string, pipeline mongo.Pipeline) error {
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	cmd := bson.D{
+		{Key: "collMod", Value: name},
+		{Key: "viewOn", Value: source},
+		{Key: "pipeline", Value: pipeline},
+	}
+	if err := db.RunCommand(ctx, cmd).Err(); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrModifyViewFailed, name, err)
+	}
+	return nil
+}
+
+// DropView drops a view. Dropping a view never removes data from its source.
+func DropView(ctx context.Context, db *mongo.Database, name string) error {
+	if err := db.Collection(name).Drop(ctx); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrDropViewFailed, name, err)
+	}
+	return nil
+}
+
+// RefreshMaterializedView re-runs a registered materialized view's pipeline.
+// Call it from a migration's Up to (re)build the view after a deploy.
+func RefreshMaterializedView(ctx context.Context, db *mongo.Database, name string) error {
+	spec, ok := schema.MaterializedView(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", schema.ErrMaterializedViewNotReg, name)
+	}
+	return spec.Refresh(ctx, db)
+}
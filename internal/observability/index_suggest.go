@@ -0,0 +1,391 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	SuggestSourceProfile   = "profile"
+	SuggestSourceCurrentOp = "current-op"
+
+	defaultSuggestSample   = 1000
+	defaultSuggestMinRatio = 10
+)
+
+var ErrUnknownSuggestSource = errors.New("unknown query sample source")
+
+type SuggestOptions struct {
+	Collection string
+	// Source is SuggestSourceProfile (system.profile) or SuggestSourceCurrentOp.
+	Source string
+	// Sample caps how many profiler entries or operations are read.
+	Sample int
+	// MinRatio flags shapes whose docsExamined/nReturned reaches this value.
+	MinRatio float64
+}
+
+type IndexSuggestion struct {
+	Collection   string           `json:"collection"`
+	Shape        string           `json:"shape"`
+	Count        int              `json:"count"`
+	CollScans    int              `json:"collscans"`
+	DocsExamined int64            `json:"docs_examined"`
+	Returned     int64            `json:"returned"`
+	Ratio        float64          `json:"ratio"`
+	MaxMillis    int64            `json:"max_millis"`
+	Index        schema.IndexSpec `json:"index"`
+}
+
+// queryShape is a filter reduced to its field names, ordered by the
+// equality-sort-range rule so the proposed index serves all three.
+type queryShape struct {
+	equality []string
+	sort     bson.D
+	ranges   []string
+}
+
+type sampledQuery struct {
+	collection   string
+	shape        queryShape
+	collScan     bool
+	docsExamined int64
+	returned     int64
+	millis       int64
+}
+
+// SuggestIndexes samples slow queries, groups them by query shape and proposes
+// an index for shapes that scan the collection or examine many more documents
+// than they return. Shapes already served by an existing index prefix are skipped.
+func SuggestIndexes(ctx context.Context, db *mongo.Database, opts SuggestOptions) ([]IndexSuggestion, error) {
+	if opts.Sample <= 0 {
+		opts.Sample = defaultSuggestSample
+	}
+	if opts.MinRatio <= 0 {
+		opts.MinRatio = defaultSuggestMinRatio
+	}
+
+	var (
+		samples []sampledQuery
+		err     error
+	)
+	switch opts.Source {
+	case "", SuggestSourceProfile:
+		samples, err = sampleProfiler(ctx, db, opts)
+	case SuggestSourceCurrentOp:
+		samples, err = sampleCurrentOp(ctx, db, opts)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSuggestSource, opts.Source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := groupSuggestions(samples, opts.MinRatio)
+	existing := map[string][]bson.D{}
+	out := suggestions[:0]
+	for _, s := range suggestions {
+		keys, ok := existing[s.Collection]
+		if !ok {
+			keys, err = indexKeys(ctx, db.Collection(s.Collection))
+			if err != nil {
+				return nil, err
+			}
+			existing[s.Collection] = keys
+		}
+		if !coveredByIndex(s.Index.Keys, keys) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func sampleProfiler(ctx context.Context, db *mongo.Database, opts SuggestOptions) ([]sampledQuery, error) {
+	filter := bson.D{{Key: "op", Value: bson.D{{Key: "$in", Value: bson.A{"query", "command"}}}}}
+	if opts.Collection != "" {
+		filter = append(filter, bson.E{Key: "ns", Value: db.Name() + "." + opts.Collection})
+	}
+	findOpts := options.Find().SetSort(bson.D{{Key: "ts", Value: -1}}).SetLimit(int64(opts.Sample))
+	cur, err := db.Collection("system.profile").Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	var rows []bson.M
+	if err := cur.All(ctx, &rows); err != nil {
+		_ = cur.Close(ctx)
+		return nil, err
+	}
+	_ = cur.Close(ctx)
+
+	out := make([]sampledQuery, 0, len(rows))
+	for _, row := range rows {
+		if q, ok := sampleFromOperation(db.Name(), row); ok {
+			q.docsExamined = int64(number(row["docsExamined"]))
+			q.returned = int64(number(row["nreturned"]))
+			q.millis = int64(number(row["millis"]))
+			out = append(out, q)
+		}
+	}
+	return out, nil
+}
+
+func sampleCurrentOp(ctx context.Context, db *mongo.Database, opts SuggestOptions) ([]sampledQuery, error) {
+	match := bson.D{{Key: "planSummary", Value: bson.D{{Key: "$exists", Value: true}}}}
+	if opts.Collection != "" {
+		match = append(match, bson.E{Key: "ns", Value: db.Name() + "." + opts.Collection})
+	}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$currentOp", Value: bson.M{"allUsers": true, "idleConnections": false}}},
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$limit", Value: opts.Sample}},
+	}
+	cur, err := db.Client().Database("admin").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []bson.M
+	if err := cur.All(ctx, &rows); err != nil {
+		_ = cur.Close(ctx)
+		return nil, err
+	}
+	_ = cur.Close(ctx)
+
+	out := make([]sampledQuery, 0, len(rows))
+	for _, row := range rows {
+		if q, ok := sampleFromOperation(db.Name(), row); ok {
+			q.millis = int64(number(row["microsecs_running"]) / 1000)
+			out = append(out, q)
+		}
+	}
+	return out, nil
+}
+
+// sampleFromOperation extracts the collection and query shape from a profiler
+// entry or $currentOp document. Operations without a filter are ignored.
+func sampleFromOperation(dbName string, row bson.M) (sampledQuery, bool) {
+	ns, _ := row["ns"].(string)
+	collection, ok := strings.CutPrefix(ns, dbName+".")
+	if !ok || collection == "" || strings.HasPrefix(collection, "system.") || strings.HasPrefix(collection, "$cmd") {
+		return sampledQuery{}, false
+	}
+	command, _ := asMap(row["command"])
+	filter, sortSpec := commandFilter(command)
+	shape := shapeOf(filter, sortSpec)
+	if len(shape.equality)+len(shape.sort)+len(shape.ranges) == 0 {
+		return sampledQuery{}, false
+	}
+	planSummary, _ := row["planSummary"].(string)
+	return sampledQuery{
+		collection: collection,
+		shape:      shape,
+		collScan:   strings.HasPrefix(planSummary, "COLLSCAN"),
+	}, true
+}
+
+func commandFilter(command bson.M) (bson.M, bson.D) {
+	sortSpec, _ := command["sort"].(bson.D)
+	for _, key := range []string{"filter", "query", "q"} {
+		if filter, ok := asMap(command[key]); ok {
+			return filter, sortSpec
+		}
+	}
+	if pipeline, ok := command["pipeline"].(bson.A); ok && len(pipeline) > 0 {
+		var filter bson.M
+		for _, stage := range pipeline {
+			st, ok := asMap(stage)
+			if !ok {
+				break
+			}
+			if m, ok := asMap(st["$match"]); ok && filter == nil {
+				filter = m
+				continue
+			}
+			if s, ok := st["$sort"].(bson.D); ok && filter != nil {
+				sortSpec = s
+			}
+			break
+		}
+		return filter, sortSpec
+	}
+	return nil, sortSpec
+}
+
+var rangeOperators = map[string]bool{
+	"$gt": true, "$gte": true, "$lt": true, "$lte": true, "$ne": true, "$nin": true,
+	"$exists": true, "$regex": true, "$type": true,
+}
+
+func shapeOf(filter bson.M, sortSpec bson.D) queryShape {
+	var shape queryShape
+	seen := map[string]bool{}
+	var visit func(bson.M)
+	visit = func(f bson.M) {
+		for field, value := range f {
+			if field == "$and" {
+				if clauses, ok := value.(bson.A); ok {
+					for _, c := range clauses {
+						if m, ok := asMap(c); ok {
+							visit(m)
+						}
+					}
+				}
+				continue
+			}
+			if strings.HasPrefix(field, "$") || seen[field] {
+				continue
+			}
+			seen[field] = true
+			if isRangePredicate(value) {
+				shape.ranges = append(shape.ranges, field)
+			} else {
+				shape.equality = append(shape.equality, field)
+			}
+		}
+	}
+	visit(filter)
+	for _, e := range sortSpec {
+		if !seen[e.Key] {
+			shape.sort = append(shape.sort, e)
+		}
+	}
+	sort.Strings(shape.equality)
+	sort.Strings(shape.ranges)
+	return shape
+}
+
+func isRangePredicate(value any) bool {
+	m, ok := asMap(value)
+	if !ok {
+		return false
+	}
+	for op := range m {
+		if rangeOperators[op] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s queryShape) keys() bson.D {
+	keys := bson.D{}
+	for _, f := range s.equality {
+		keys = append(keys, bson.E{Key: f, Value: int32(1)})
+	}
+	for _, e := range s.sort {
+		dir := int32(1)
+		if number(e.Value) < 0 {
+			dir = -1
+		}
+		keys = append(keys, bson.E{Key: e.Key, Value: dir})
+	}
+	for _, f := range s.ranges {
+		keys = append(keys, bson.E{Key: f, Value: int32(1)})
+	}
+	return keys
+}
+
+func (s queryShape) String() string {
+	var parts []string
+	if len(s.equality) > 0 {
+		parts = append(parts, "eq("+strings.Join(s.equality, ",")+")")
+	}
+	if len(s.sort) > 0 {
+		parts = append(parts, "sort"+formatIndexKeys(s.sort))
+	}
+	if len(s.ranges) > 0 {
+		parts = append(parts, "range("+strings.Join(s.ranges, ",")+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+func groupSuggestions(samples []sampledQuery, minRatio float64) []IndexSuggestion {
+	groups := map[string]*IndexSuggestion{}
+	var order []string
+	for _, q := range samples {
+		shape := q.shape.String()
+		id := q.collection + " " + shape
+		g, ok := groups[id]
+		if !ok {
+			keys := q.shape.keys()
+			g = &IndexSuggestion{
+				Collection: q.collection,
+				Shape:      shape,
+				Index: schema.IndexSpec{
+					Collection: q.collection,
+					Name:       suggestedIndexName(keys),
+					Keys:       keys,
+				},
+			}
+			groups[id] = g
+			order = append(order, id)
+		}
+		g.Count++
+		if q.collScan {
+			g.CollScans++
+		}
+		g.DocsExamined += q.docsExamined
+		g.Returned += q.returned
+		g.MaxMillis = max(g.MaxMillis, q.millis)
+	}
+
+	out := make([]IndexSuggestion, 0, len(groups))
+	for _, id := range order {
+		g := groups[id]
+		g.Ratio = float64(g.DocsExamined) / float64(max(g.Returned, 1))
+		if g.CollScans > 0 || g.Ratio >= minRatio {
+			out = append(out, *g)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].DocsExamined != out[j].DocsExamined {
+			return out[i].DocsExamined > out[j].DocsExamined
+		}
+		return out[i].Count > out[j].Count
+	})
+	return out
+}
+
+func suggestedIndexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		parts = append(parts, k.Key, fmt.Sprintf("%v", k.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+func indexKeys(ctx context.Context, coll *mongo.Collection) ([]bson.D, error) {
+	cur, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Key bson.D `bson:"key"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		_ = cur.Close(ctx)
+		return nil, err
+	}
+	_ = cur.Close(ctx)
+	out := make([]bson.D, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row.Key)
+	}
+	return out, nil
+}
+
+func coveredByIndex(keys bson.D, existing []bson.D) bool {
+	for _, idx := range existing {
+		if keysStartWith(idx, keys) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package observability
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSampleFromOperationOrdersKeysByEqualitySortRange(t *testing.T) {
+	row := bson.M{
+		"ns":          "app.orders",
+		"planSummary": "COLLSCAN",
+		"command": bson.D{
+			{Key: "find", Value: "orders"},
+			{Key: "filter", Value: bson.D{
+				{Key: "created", Value: bson.D{{Key: "$gte", Value: int32(5)}}},
+				{Key: "status", Value: "paid"},
+			}},
+			{Key: "sort", Value: bson.D{{Key: "total", Value: int32(-1)}}},
+		},
+	}
+
+	q, ok := sampleFromOperation("app", row)
+	if !ok {
+		t.Fatal("expected operation to be sampled")
+	}
+	if q.collection != "orders" || !q.collScan {
+		t.Fatalf("unexpected sample %+v", q)
+	}
+	got := formatIndexKeys(q.shape.keys())
+	if got != "[status:1 total:-1 created:1]" {
+		t.Fatalf("unexpected keys %s", got)
+	}
+}
+
+func TestGroupSuggestionsFiltersEfficientShapes(t *testing.T) {
+	scan := queryShape{equality: []string{"status"}}
+	efficient := queryShape{equality: []string{"email"}}
+	samples := []sampledQuery{
+		{collection: "orders", shape: scan, docsExamined: 1000, returned: 10},
+		{collection: "orders", shape: scan, docsExamined: 500, returned: 5},
+		{collection: "users", shape: efficient, docsExamined: 2, returned: 2},
+	}
+
+	got := groupSuggestions(samples, 10)
+	if len(got) != 1 {
+		t.Fatalf("expected one suggestion, got %+v", got)
+	}
+	if got[0].Count != 2 || got[0].Ratio != 100 || got[0].Index.Name != "status_1" {
+		t.Fatalf("unexpected suggestion %+v", got[0])
+	}
+	if !coveredByIndex(got[0].Index.Keys, []bson.D{{{Key: "status", Value: int32(1)}, {Key: "x", Value: int32(1)}}}) {
+		t.Fatal("expected wider index with the same prefix to cover the suggestion")
+	}
+}
@@ -0,0 +1,189 @@
+package observability
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const idIndexName = "_id_"
+
+type IndexUsage struct {
+	Collection    string     `json:"collection"`
+	Name          string     `json:"name"`
+	Keys          string     `json:"keys"`
+	Ops           int64      `json:"ops"`
+	Since         *time.Time `json:"since,omitempty"`
+	SizeBytes     int64      `json:"size_bytes"`
+	Unused        bool       `json:"unused"`
+	RedundantWith string     `json:"redundant_with,omitempty"`
+
+	key     bson.D
+	unique  bool
+	partial bool
+	ttl     bool
+}
+
+// Reclaimable reports whether dropping the index is a candidate for saving space.
+func (u IndexUsage) Reclaimable() bool {
+	return u.Name != idIndexName && (u.Unused || u.RedundantWith != "")
+}
+
+type IndexUsageReport struct {
+	Indexes          []IndexUsage `json:"indexes"`
+	Unused           int          `json:"unused"`
+	Redundant        int          `json:"redundant"`
+	ReclaimableBytes int64        `json:"reclaimable_bytes"`
+}
+
+type indexStatsRow struct {
+	Name     string `bson:"name"`
+	Key      bson.D `bson:"key"`
+	Accesses struct {
+		Ops   int64     `bson:"ops"`
+		Since time.Time `bson:"since"`
+	} `bson:"accesses"`
+	Spec bson.M `bson:"spec"`
+}
+
+// AnalyzeIndexUsage combines $indexStats with collStats.indexSizes. Usage counters
+// are per mongod and reset on restart, so "unused" means unused since Since.
+func AnalyzeIndexUsage(ctx context.Context, db *mongo.Database, collection string) (IndexUsageReport, error) {
+	names, err := db.ListCollectionNames(ctx, bson.D{{Key: "type", Value: "collection"}})
+	if err != nil {
+		return IndexUsageReport{}, err
+	}
+	sort.Strings(names)
+
+	var all []IndexUsage
+	for _, name := range names {
+		if collection != "" && collection != name {
+			continue
+		}
+		usage, err := collectionIndexUsage(ctx, db, name)
+		if err != nil {
+			return IndexUsageReport{}, err
+		}
+		all = append(all, usage...)
+	}
+	return buildIndexUsageReport(all), nil
+}
+
+func collectionIndexUsage(ctx context.Context, db *mongo.Database, name string) ([]IndexUsage, error) {
+	cur, err := db.Collection(name).Aggregate(ctx, mongo.Pipeline{bson.D{{Key: "$indexStats", Value: bson.D{}}}})
+	if err != nil {
+		return nil, err
+	}
+	var rows []indexStatsRow
+	if err := cur.All(ctx, &rows); err != nil {
+		_ = cur.Close(ctx)
+		return nil, err
+	}
+	_ = cur.Close(ctx)
+
+	var stats bson.M
+	if err := db.RunCommand(ctx, bson.D{{Key: "collStats", Value: name}}).Decode(&stats); err != nil {
+		return nil, err
+	}
+	sizes, _ := asMap(stats["indexSizes"])
+
+	out := make([]IndexUsage, 0, len(rows))
+	for _, row := range rows {
+		u := IndexUsage{
+			Collection: name,
+			Name:       row.Name,
+			Keys:       formatIndexKeys(row.Key),
+			Ops:        row.Accesses.Ops,
+			SizeBytes:  int64(number(sizes[row.Name])),
+			key:        row.Key,
+			unique:     asBool(row.Spec["unique"]),
+			partial:    row.Spec["partialFilterExpression"] != nil || asBool(row.Spec["sparse"]),
+			ttl:        row.Spec["expireAfterSeconds"] != nil,
+		}
+		if !row.Accesses.Since.IsZero() {
+			since := row.Accesses.Since
+			u.Since = &since
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+func buildIndexUsageReport(indexes []IndexUsage) IndexUsageReport {
+	markRedundantIndexes(indexes)
+	report := IndexUsageReport{Indexes: indexes}
+	for i := range report.Indexes {
+		u := &report.Indexes[i]
+		// TTL deletes and unique checks never show up as accesses, and those
+		// indexes are needed whether or not queries use them.
+		u.Unused = u.Name != idIndexName && u.Ops == 0 && !u.ttl && !u.unique
+		if u.Unused {
+			report.Unused++
+		}
+		if u.RedundantWith != "" {
+			report.Redundant++
+		}
+		if u.Reclaimable() {
+			report.ReclaimableBytes += u.SizeBytes
+		}
+	}
+	sort.SliceStable(report.Indexes, func(i, j int) bool {
+		if report.Indexes[i].Collection != report.Indexes[j].Collection {
+			return report.Indexes[i].Collection < report.Indexes[j].Collection
+		}
+		return report.Indexes[i].Name < report.Indexes[j].Name
+	})
+	return report
+}
+
+// markRedundantIndexes flags indexes whose keys are a leading prefix of another
+// index on the same collection. Unique and partial indexes are never flagged:
+// they enforce constraints or cover different documents than the wider index.
+func markRedundantIndexes(indexes []IndexUsage) {
+	for i := range indexes {
+		a := &indexes[i]
+		if a.Name == idIndexName || a.unique || a.partial {
+			continue
+		}
+		for j := range indexes {
+			b := indexes[j]
+			if i == j || a.Collection != b.Collection || b.partial {
+				continue
+			}
+			if isKeyPrefix(a.key, b.key) {
+				a.RedundantWith = b.Name
+				break
+			}
+		}
+	}
+}
+
+func isKeyPrefix(prefix, keys bson.D) bool {
+	return len(prefix) < len(keys) && keysStartWith(keys, prefix)
+}
+
+func keysStartWith(keys, prefix bson.D) bool {
+	if len(prefix) == 0 || len(prefix) > len(keys) {
+		return false
+	}
+	for i, k := range prefix {
+		if k.Key != keys[i].Key || !sameKeyDirection(k.Value, keys[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameKeyDirection(a, b any) bool {
+	if s, ok := a.(string); ok {
+		other, ok := b.(string)
+		return ok && s == other
+	}
+	if _, ok := b.(string); ok {
+		return false
+	}
+	return number(a) == number(b)
+}
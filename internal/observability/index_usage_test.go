@@ -0,0 +1,67 @@
+package observability
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestBuildIndexUsageReportFlagsUnusedAndRedundant(t *testing.T) {
+	indexes := []IndexUsage{
+		{Collection: "orders", Name: "_id_", Ops: 0, SizeBytes: 100, key: bson.D{{Key: "_id", Value: int32(1)}}},
+		{Collection: "orders", Name: "status_1", Ops: 5, SizeBytes: 40, key: bson.D{{Key: "status", Value: int32(1)}}},
+		{
+			Collection: "orders",
+			Name:       "status_1_created_1",
+			Ops:        12,
+			SizeBytes:  80,
+			key:        bson.D{{Key: "status", Value: int32(1)}, {Key: "created", Value: int32(1)}},
+		},
+		{
+			Collection: "orders",
+			Name:       "email_1",
+			Ops:        0,
+			SizeBytes:  30,
+			unique:     true,
+			key:        bson.D{{Key: "email", Value: int32(1)}},
+		},
+		{
+			Collection: "orders",
+			Name:       "email_1_name_1",
+			Ops:        3,
+			SizeBytes:  50,
+			key:        bson.D{{Key: "email", Value: int32(1)}, {Key: "name", Value: int32(1)}},
+		},
+		{Collection: "users", Name: "status_-1", Ops: 1, SizeBytes: 10, key: bson.D{{Key: "status", Value: int32(-1)}}},
+		{Collection: "users", Name: "seen_1", Ops: 0, SizeBytes: 20, ttl: true, key: bson.D{{Key: "seen", Value: int32(1)}}},
+		{Collection: "users", Name: "tmp_1", Ops: 0, SizeBytes: 30, key: bson.D{{Key: "tmp", Value: int32(1)}}},
+	}
+
+	report := buildIndexUsageReport(indexes)
+
+	byName := map[string]IndexUsage{}
+	for _, u := range report.Indexes {
+		byName[u.Collection+"."+u.Name] = u
+	}
+	if got := byName["orders.status_1"].RedundantWith; got != "status_1_created_1" {
+		t.Fatalf("expected status_1 to be redundant with status_1_created_1, got %q", got)
+	}
+	if byName["orders.email_1"].RedundantWith != "" {
+		t.Fatal("unique index must not be flagged as redundant")
+	}
+	if byName["orders.email_1"].Unused || byName["users.seen_1"].Unused || byName["orders._id_"].Unused {
+		t.Fatalf("unique, TTL and _id indexes must not be reported unused: %+v", report.Indexes)
+	}
+	if !byName["users.tmp_1"].Unused {
+		t.Fatalf("unexpected unused flags: %+v", report.Indexes)
+	}
+	if byName["users.status_-1"].RedundantWith != "" {
+		t.Fatal("indexes on other collections must not make an index redundant")
+	}
+	if report.Unused != 1 || report.Redundant != 1 {
+		t.Fatalf("unexpected counts unused=%d redundant=%d", report.Unused, report.Redundant)
+	}
+	if report.ReclaimableBytes != 70 {
+		t.Fatalf("expected 70 reclaimable bytes, got %d", report.ReclaimableBytes)
+	}
+}
@@ -21,8 +21,9 @@ type HealthReport struct {
 }
 
 type CollectionInfo struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	ViewOn string `json:"view_on,omitempty"`
 }
 
 type IndexInfo struct {
@@ -129,10 +130,14 @@ func ListCollections(ctx context.Context, db *mongo.Database) ([]CollectionInfo,
 		if err := c.Decode(&row); err != nil {
 			return nil, err
 		}
-		out = append(out, CollectionInfo{
+		info := CollectionInfo{
 			Name: fmt.Sprintf("%v", row["name"]),
 			Type: fmt.Sprintf("%v", row["type"]),
-		})
+		}
+		if opts, ok := asMap(row["options"]); ok {
+			info.ViewOn, _ = opts["viewOn"].(string)
+		}
+		out = append(out, info)
 	}
 	return out, c.Err()
 }
@@ -311,6 +316,13 @@ func number(v interface{}) float64 {
 }
 
 func formatIndexKeys(keys any) string {
+	if d, ok := keys.(bson.D); ok && len(d) > 0 {
+		parts := make([]string, 0, len(d))
+		for _, e := range d {
+			parts = append(parts, fmt.Sprintf("%s:%v", e.Key, e.Value))
+		}
+		return fmt.Sprintf("%s", parts)
+	}
 	m, ok := asMap(keys)
 	if !ok || len(m) == 0 {
 		return ""
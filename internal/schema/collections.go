@@ -4,25 +4,104 @@ import (
 	"errors"
 	"sort"
 	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 var (
 	ErrCollectionNameRequired = errors.New("collection name is required")
 )
 
+// CollectionOptions declares creation options for a collection. Nil or empty
+// fields are left unmanaged so diffs only report options a project declares.
+type CollectionOptions struct {
+	Capped             bool               `json:"capped,omitempty" yaml:"capped"`
+	SizeBytes          int64              `json:"size_bytes,omitempty" yaml:"sizeBytes"`
+	MaxDocuments       int64              `json:"max_documents,omitempty" yaml:"maxDocuments"`
+	TimeSeries         *TimeSeriesOptions `json:"timeseries,omitempty" yaml:"timeseries"`
+	ClusteredIndex     bool               `json:"clustered_index,omitempty" yaml:"clusteredIndex"`
+	PreAndPostImages   *bool              `json:"pre_and_post_images,omitempty" yaml:"changeStreamPreAndPostImages"`
+	Collation          bson.M             `json:"collation,omitempty" yaml:"collation"`
+	ExpireAfterSeconds *int64             `json:"expire_after_seconds,omitempty" yaml:"expireAfterSeconds"`
+}
+
+type TimeSeriesOptions struct {
+	TimeField   string `json:"time_field" yaml:"timeField"`
+	MetaField   string `json:"meta_field,omitempty" yaml:"metaField"`
+	Granularity string `json:"granularity,omitempty" yaml:"granularity"`
+}
+
+// IsZero reports whether no option is declared.
+func (o CollectionOptions) IsZero() bool {
+	return !o.Capped && o.SizeBytes == 0 && o.MaxDocuments == 0 && o.TimeSeries == nil &&
+		!o.ClusteredIndex && o.PreAndPostImages == nil && len(o.Collation) == 0 &&
+		o.ExpireAfterSeconds == nil
+}
+
+// CreateCommand renders the create command for a collection with these options.
+func (o CollectionOptions) CreateCommand(name string) bson.D {
+	cmd := bson.D{{Key: "create", Value: name}}
+	if o.Capped {
+		cmd = append(cmd, bson.E{Key: "capped", Value: true})
+		if o.SizeBytes > 0 {
+			cmd = append(cmd, bson.E{Key: "size", Value: o.SizeBytes})
+		}
+		if o.MaxDocuments > 0 {
+			cmd = append(cmd, bson.E{Key: "max", Value: o.MaxDocuments})
+		}
+	}
+	if ts := o.TimeSeries; ts != nil {
+		tsDoc := bson.D{{Key: "timeField", Value: ts.TimeField}}
+		if ts.MetaField != "" {
+			tsDoc = append(tsDoc, bson.E{Key: "metaField", Value: ts.MetaField})
+		}
+		if ts.Granularity != "" {
+			tsDoc = append(tsDoc, bson.E{Key: "granularity", Value: ts.Granularity})
+		}
+		cmd = append(cmd, bson.E{Key: "timeseries", Value: tsDoc})
+	}
+	if o.ClusteredIndex {
+		cmd = append(cmd, bson.E{Key: "clusteredIndex", Value: bson.D{
+			{Key: "key", Value: bson.D{{Key: "_id", Value: 1}}},
+			{Key: "unique", Value: true},
+		}})
+	}
+	if o.PreAndPostImages != nil {
+		cmd = append(cmd, bson.E{Key: "changeStreamPreAndPostImages", Value: bson.D{
+			{Key: "enabled", Value: *o.PreAndPostImages},
+		}})
+	}
+	if len(o.Collation) > 0 {
+		cmd = append(cmd, bson.E{Key: "collation", Value: o.Collation})
+	}
+	if o.ExpireAfterSeconds != nil {
+		cmd = append(cmd, bson.E{Key: "expireAfterSeconds", Value: *o.ExpireAfterSeconds})
+	}
+	return cmd
+}
+
 var (
 	collectionsMu sync.RWMutex
-	collections   = make(map[string]struct{})
+	collections   = make(map[string]CollectionOptions)
 )
 
 func RegisterCollection(name string) error {
+	return RegisterCollectionWithOptions(name, CollectionOptions{})
+}
+
+// RegisterCollectionWithOptions tracks a collection together with its declared
+// options. Registering a name again without options keeps earlier options.
+func RegisterCollectionWithOptions(name string, opts CollectionOptions) error {
 	if name == "" {
 		return ErrCollectionNameRequired
 	}
 
 	collectionsMu.Lock()
 	defer collectionsMu.Unlock()
-	collections[name] = struct{}{}
+	if existing, ok := collections[name]; ok && opts.IsZero() {
+		opts = existing
+	}
+	collections[name] = opts
 	return nil
 }
 
@@ -34,6 +113,12 @@ func MustRegisterCollections(names ...string) {
 	}
 }
 
+func MustRegisterCollectionWithOptions(name string, opts CollectionOptions) {
+	if err := RegisterCollectionWithOptions(name, opts); err != nil {
+		panic(err)
+	}
+}
+
 func Collections() []string {
 	collectionsMu.RLock()
 	defer collectionsMu.RUnlock()
@@ -45,3 +130,12 @@ func Collections() []string {
 	sort.Strings(out)
 	return out
 }
+
+// CollectionOptionsFor returns the options declared for a registered collection.
+func CollectionOptionsFor(name string) (CollectionOptions, bool) {
+	collectionsMu.RLock()
+	defer collectionsMu.RUnlock()
+
+	opts, ok := collections[name]
+	return opts, ok
+}
@@ -0,0 +1,235 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/migration"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	ApplyStatusApplied = "applied"
+	ApplyStatusPlanned = "planned"
+	ApplyStatusSkipped = "skipped"
+)
+
+// ApplyOptions controls how Apply converges the live database.
+type ApplyOptions struct {
+	DryRun bool
+	// IndexOptions are passed to index builds, e.g. migration.WithHiddenBuild.
+	IndexOptions []migration.IndexCreateOption
+}
+
+// ApplyResult records what Apply did with a single diff.
+type ApplyResult struct {
+	Diff
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+type applyFunc func(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, opts ApplyOptions) error
+
+// Apply converges the live database towards target for the diffs that can be
+// applied safely: creating collections and indexes, rebuilding changed indexes
+// without a coverage gap, setting validators and changing mutable collection
+// options via collMod. Drops and immutable options are skipped so they go
+// through a reviewed migration, and diffs covered by an ignore or ownership
+// rule are skipped even when passed in explicitly.
+func Apply(ctx context.Context, db *mongo.Database, target SchemaSpec, diffs []Diff, opts ApplyOptions) ([]ApplyResult, error) {
+	results := make([]ApplyResult, 0, len(diffs))
+	rules := RegisteredIgnoreRules()
+	for _, d := range diffs {
+		if rule, ignored := rules.Rule(d); ignored {
+			results = append(results, ApplyResult{Diff: d, Status: ApplyStatusSkipped, Note: ignoreNote(rule)})
+			continue
+		}
+		fn, note := applierFor(target, d)
+		if fn == nil {
+			results = append(results, ApplyResult{Diff: d, Status: ApplyStatusSkipped, Note: note})
+			continue
+		}
+		if opts.DryRun {
+			results = append(results, ApplyResult{Diff: d, Status: ApplyStatusPlanned})
+			continue
+		}
+		if err := fn(ctx, db, target, d, opts); err != nil {
+			return results, fmt.Errorf("apply %s %s: %w", d.Action, d.Target, err)
+		}
+		results = append(results, ApplyResult{Diff: d, Status: ApplyStatusApplied})
+	}
+	return results, nil
+}
+
+func ignoreNote(rule IgnoreRule) string {
+	note := "ignored by rule " + rule.Pattern
+	if rule.Owner != "" {
+		note = "owned by " + rule.Owner
+	}
+	if rule.Reason != "" {
+		note += ": " + rule.Reason
+	}
+	return note
+}
+
+func applierFor(target SchemaSpec, d Diff) (applyFunc, string) {
+	switch d.Action {
+	case "AddCollection":
+		return applyAddCollection, ""
+	case "AddIndex":
+		return applyAddIndex, ""
+	case "UpdateIndex":
+		if idx, ok := findTargetIndex(target, d.Target); ok && (idx.Unique || idx.ExpireAfterSeconds != nil) {
+			return nil, "unique or TTL index; drop and recreate it in a migration"
+		}
+		return applyUpdateIndex, ""
+	case "AddValidator", "UpdateValidator":
+		return applyValidator, ""
+	case "AddView":
+		return applyAddView, ""
+	case "UpdateView":
+		return applyUpdateView, ""
+	case "AddMaterializedView":
+		return applyRefreshMaterializedView, ""
+	case "UpdateCollectionOption":
+		if _, name, ok := splitOptionTarget(target, d.Target); ok && mutableOptions[name] {
+			return applyCollectionOption, ""
+		}
+		return nil, "immutable option; recreate the collection in a migration"
+	case "AddSearchIndex":
+		return applyAddSearchIndex, ""
+	case "UpdateSearchIndex":
+		return applyUpdateSearchIndex, ""
+	case "ShardCollection", "ReshardCollection", "UpdateZones":
+		return nil, "sharding changes are not reversible; use migration.ShardCollection in a migration"
+	default:
+		return nil, "destructive change; write a migration"
+	}
+}
+
+func applyAddCollection(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, _ ApplyOptions) error {
+	return db.RunCommand(ctx, target.Options[d.Target].CreateCommand(d.Target)).Err()
+}
+
+func applyAddIndex(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, opts ApplyOptions) error {
+	idx, ok := findTargetIndex(target, d.Target)
+	if !ok {
+		return fmt.Errorf("index %s not found in target schema", d.Target)
+	}
+	return migration.CreateIndexesWithOptions(ctx, db.Collection(idx.Collection), opts.IndexOptions, indexBuilder(idx))
+}
+
+// applyUpdateIndex swaps the index through a temporary copy so queries stay
+// covered, instead of dropping it and rebuilding from scratch.
+func applyUpdateIndex(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, opts ApplyOptions) error {
+	idx, ok := findTargetIndex(target, d.Target)
+	if !ok {
+		return fmt.Errorf("index %s not found in target schema", d.Target)
+	}
+	return migration.ReplaceIndex(ctx, db.Collection(idx.Collection), indexBuilder(idx), opts.IndexOptions...)
+}
+
+func findTargetIndex(target SchemaSpec, full string) (IndexSpec, bool) {
+	for coll, indexes := range target.Indexes {
+		for name, idx := range indexes {
+			if coll+"."+name == full {
+				return idx, true
+			}
+		}
+	}
+	return IndexSpec{}, false
+}
+
+func indexBuilder(idx IndexSpec) *migration.IndexBuilder {
+	b := migration.Index().Name(idx.Name)
+	for _, key := range idx.Keys {
+		b.Key(key.Key, key.Value)
+	}
+	if idx.Unique {
+		b.Unique()
+	}
+	if idx.Sparse {
+		b.Sparse()
+	}
+	if idx.ExpireAfterSeconds != nil {
+		b.TTL(*idx.ExpireAfterSeconds)
+	}
+	if len(idx.PartialFilter) > 0 {
+		b.Partial(idx.PartialFilter)
+	}
+	return b
+}
+
+func applyValidator(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, _ ApplyOptions) error {
+	v := target.Validators[d.Target]
+	cmd := bson.D{
+		{Key: "collMod", Value: d.Target},
+		{Key: "validator", Value: v.Schema},
+	}
+	if v.Level != "" {
+		cmd = append(cmd, bson.E{Key: "validationLevel", Value: v.Level})
+	}
+	return db.RunCommand(ctx, cmd).Err()
+}
+
+func applyCollectionOption(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, _ ApplyOptions) error {
+	coll, name, _ := splitOptionTarget(target, d.Target)
+	fields, ok := collModForOption(target.Options[coll], name)
+	if !ok {
+		return fmt.Errorf("option %s cannot be changed with collMod", name)
+	}
+	cmd := append(bson.D{{Key: "collMod", Value: coll}}, fields...)
+	return db.RunCommand(ctx, cmd).Err()
+}
+
+func applyAddView(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, _ ApplyOptions) error {
+	return db.RunCommand(ctx, createViewCommand(target.Views[d.Target])).Err()
+}
+
+// applyUpdateView modifies the view in place; collation cannot be changed with
+// collMod, so the view is dropped and recreated. Views hold no data.
+func applyUpdateView(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, _ ApplyOptions) error {
+	view := target.Views[d.Target]
+	live, err := InspectLive(ctx, db)
+	if err != nil {
+		return err
+	}
+	if canonicalJSON(live.Views[d.Target].Collation) != canonicalJSON(view.Collation) {
+		if err := db.Collection(view.Name).Drop(ctx); err != nil {
+			return err
+		}
+		return db.RunCommand(ctx, createViewCommand(view)).Err()
+	}
+
+	pipeline := view.Pipeline
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: view.Name},
+		{Key: "viewOn", Value: view.Source},
+		{Key: "pipeline", Value: pipeline},
+	}).Err()
+}
+
+func applyRefreshMaterializedView(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, _ ApplyOptions) error {
+	return target.MaterializedViews[d.Target].Refresh(ctx, db)
+}
+
+// splitOptionTarget resolves "<collection>.<option>" against the declared
+// collections, since both collection and option names may contain dots. The
+// longest matching collection wins, so "a.b.capped" belongs to "a.b", not "a".
+func splitOptionTarget(target SchemaSpec, full string) (string, string, bool) {
+	best := ""
+	for coll := range target.Options {
+		if strings.HasPrefix(full, coll+".") && len(coll) > len(best) {
+			best = coll
+		}
+	}
+	if best == "" {
+		return "", "", false
+	}
+	return best, strings.TrimPrefix(full, best+"."), true
+}
@@ -0,0 +1,19 @@
+package diff
+
+import "testing"
+
+func TestSplitOptionTargetPrefersLongestCollection(t *testing.T) {
+	target := SchemaSpec{Options: map[string]CollectionOptions{"a": {}, "a.b": {}}}
+	for range 20 {
+		coll, name, ok := splitOptionTarget(target, "a.b.capped")
+		if !ok || coll != "a.b" || name != "capped" {
+			t.Fatalf("splitOptionTarget = %q, %q, %v; want a.b, capped", coll, name, ok)
+		}
+	}
+	if coll, name, _ := splitOptionTarget(target, "a.validator"); coll != "a" || name != "validator" {
+		t.Fatalf("splitOptionTarget = %q, %q; want a, validator", coll, name)
+	}
+	if _, _, ok := splitOptionTarget(target, "c.capped"); ok {
+		t.Fatal("undeclared collection should not resolve")
+	}
+}
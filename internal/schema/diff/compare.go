@@ -5,9 +5,24 @@ import (
 	"sort"
 )
 
+// Compare returns every difference between live and target.
 func Compare(live, target SchemaSpec) []Diff {
+	return compareAll(live, target)
+}
+
+// CompareWithIgnored is Compare with the diffs covered by rules split off, so
+// callers can report them. Pass RegisteredIgnoreRules to honour the rules
+// from schema files and config.
+func CompareWithIgnored(live, target SchemaSpec, rules IgnoreRules) (diffs, ignored []Diff) {
+	return rules.Filter(compareAll(live, target))
+}
+
+func compareAll(live, target SchemaSpec) []Diff {
 	var diffs []Diff
 	for _, coll := range unionKeys(live.Collections, target.Collections) {
+		if _, materialized := target.MaterializedViews[coll]; materialized {
+			continue
+		}
 		_, liveOK := live.Collections[coll]
 		_, targetOK := target.Collections[coll]
 		switch {
@@ -18,7 +33,7 @@ func Compare(live, target SchemaSpec) []Diff {
 				Target:    coll,
 				Current:   "missing",
 				Proposed:  "tracked in registry",
-				Risk:      "LOW",
+				Risk:      RiskLow,
 			})
 		case liveOK && !targetOK:
 			diffs = append(diffs, Diff{
@@ -27,8 +42,10 @@ func Compare(live, target SchemaSpec) []Diff {
 				Target:    coll,
 				Current:   "present in live database",
 				Proposed:  "missing from registry",
-				Risk:      "LOW",
+				Risk:      RiskLow,
 			})
+		case liveOK && targetOK:
+			diffs = append(diffs, compareOptions(coll, live.Options[coll], target.Options[coll])...)
 		}
 	}
 
@@ -57,7 +74,7 @@ func Compare(live, target SchemaSpec) []Diff {
 					Target:    fmt.Sprintf("%s.%s", coll, name),
 					Current:   describeIndex(liveIdx),
 					Proposed:  "removed",
-					Risk:      "CRITICAL",
+					Risk:      RiskCritical,
 				})
 			case liveOK && targetOK:
 				if indexSignature(liveIdx) != indexSignature(targetIdx) {
@@ -67,7 +84,7 @@ func Compare(live, target SchemaSpec) []Diff {
 						Target:    fmt.Sprintf("%s.%s", coll, name),
 						Current:   describeIndex(liveIdx),
 						Proposed:  describeIndex(targetIdx),
-						Risk:      "MEDIUM",
+						Risk:      RiskMedium,
 					})
 				}
 			}
@@ -77,6 +94,7 @@ func Compare(live, target SchemaSpec) []Diff {
 	for _, coll := range unionKeys(live.Validators, target.Validators) {
 		liveVal, liveOK := live.Validators[coll]
 		targetVal, targetOK := target.Validators[coll]
+		impact, impactKnown := target.ValidatorImpact[coll]
 
 		switch {
 		case !liveOK && targetOK:
@@ -86,7 +104,7 @@ func Compare(live, target SchemaSpec) []Diff {
 				Target:    coll,
 				Current:   "missing",
 				Proposed:  validatorSummary(targetVal),
-				Risk:      "MEDIUM",
+				Risk:      validatorChangeRisk(impact, impactKnown),
 			})
 		case liveOK && !targetOK:
 			diffs = append(diffs, Diff{
@@ -95,7 +113,7 @@ func Compare(live, target SchemaSpec) []Diff {
 				Target:    coll,
 				Current:   validatorSummary(liveVal),
 				Proposed:  "removed",
-				Risk:      "HIGH",
+				Risk:      RiskHigh,
 			})
 		case liveOK && targetOK:
 			if validatorSignature(liveVal) != validatorSignature(targetVal) {
@@ -105,12 +123,16 @@ func Compare(live, target SchemaSpec) []Diff {
 					Target:    coll,
 					Current:   validatorSummary(liveVal),
 					Proposed:  validatorSummary(targetVal),
-					Risk:      "MEDIUM",
+					Risk:      validatorChangeRisk(impact, impactKnown),
 				})
 			}
 		}
 	}
 
+	diffs = append(diffs, compareViews(live, target)...)
+	diffs = append(diffs, compareShardKeys(live, target)...)
+	diffs = append(diffs, compareSearchIndexes(live, target)...)
+
 	sort.Slice(diffs, func(i, j int) bool {
 		if diffs[i].Component != diffs[j].Component {
 			return diffs[i].Component < diffs[j].Component
@@ -121,6 +143,15 @@ func Compare(live, target SchemaSpec) []Diff {
 	return diffs
 }
 
+func sortedKeys[T any](m map[string]T) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
 func unionKeys[T any](a, b map[string]T) []string {
 	keys := make(map[string]struct{}, len(a)+len(b))
 	for k := range a {
@@ -153,7 +184,7 @@ func validatorSignature(v ValidatorSpec) string {
 
 func indexAddRisk(idx IndexSpec) string {
 	if idx.Unique {
-		return "MEDIUM"
+		return RiskMedium
 	}
-	return "LOW"
+	return RiskLow
 }
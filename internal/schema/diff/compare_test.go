@@ -1,9 +1,12 @@
 package diff
 
 import (
+	"context"
 	"testing"
 
+	"github.com/drewjocham/mongork/internal/schema"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 func TestCompareIndexesAndValidators(t *testing.T) {
@@ -46,6 +49,89 @@ func TestCompareIndexesAndValidators(t *testing.T) {
 	assertHasDiff(t, diffs, "validator", "DropValidator", "users")
 }
 
+func TestCompareValidatorRiskUsesImpact(t *testing.T) {
+	validator := ValidatorSpec{
+		Collection: "orders",
+		Schema:     bson.M{"$jsonSchema": bson.M{"required": bson.A{"total"}}},
+		Level:      "strict",
+	}
+
+	tests := []struct {
+		name   string
+		impact *ValidatorImpact
+		want   string
+	}{
+		{name: "unknown", want: "MEDIUM"},
+		{name: "clean", impact: &ValidatorImpact{Scanned: 100}, want: "LOW"},
+		{name: "few violations", impact: &ValidatorImpact{Scanned: 100, Violations: 3}, want: "HIGH"},
+		{name: "many violations", impact: &ValidatorImpact{Scanned: 100, Violations: 40}, want: "CRITICAL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := NewSchemaSpec()
+			live.Collections["orders"] = struct{}{}
+			target := NewSchemaSpec()
+			target.Collections["orders"] = struct{}{}
+			target.Validators["orders"] = validator
+			if tt.impact != nil {
+				target.ValidatorImpact["orders"] = *tt.impact
+			}
+
+			diffs := Compare(live, target)
+			if len(diffs) != 1 || diffs[0].Action != "AddValidator" {
+				t.Fatalf("expected a single AddValidator diff, got %+v", diffs)
+			}
+			if diffs[0].Risk != tt.want {
+				t.Fatalf("expected risk %s, got %s", tt.want, diffs[0].Risk)
+			}
+		})
+	}
+}
+
+func TestCompareCollectionOptions(t *testing.T) {
+	ttl := int64(3600)
+	liveTTL := int64(60)
+	enabled := true
+
+	live := NewSchemaSpec()
+	live.Collections["events"] = struct{}{}
+	live.Options["events"] = CollectionOptions{
+		TimeSeries:         &schema.TimeSeriesOptions{TimeField: "ts", Granularity: "seconds"},
+		ExpireAfterSeconds: &liveTTL,
+	}
+
+	target := NewSchemaSpec()
+	target.Collections["events"] = struct{}{}
+	target.Options["events"] = CollectionOptions{
+		TimeSeries:         &schema.TimeSeriesOptions{TimeField: "timestamp", Granularity: "seconds"},
+		PreAndPostImages:   &enabled,
+		ExpireAfterSeconds: &ttl,
+	}
+
+	diffs := Compare(live, target)
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 diffs, got %d: %+v", len(diffs), diffs)
+	}
+	assertHasDiff(t, diffs, "collection", "UpdateCollectionOption", "events.changeStreamPreAndPostImages")
+	assertHasDiff(t, diffs, "collection", "UpdateCollectionOption", "events.expireAfterSeconds")
+	assertHasDiff(t, diffs, "collection", "UpdateCollectionOption", "events.timeseries.timeField")
+
+	results, err := Apply(context.Background(), nil, target, diffs, ApplyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Apply dry-run returned error: %v", err)
+	}
+	statuses := make(map[string]string, len(results))
+	for _, r := range results {
+		statuses[r.Target] = r.Status
+	}
+	if statuses["events.expireAfterSeconds"] != ApplyStatusPlanned ||
+		statuses["events.changeStreamPreAndPostImages"] != ApplyStatusPlanned ||
+		statuses["events.timeseries.timeField"] != ApplyStatusSkipped {
+		t.Fatalf("unexpected apply plan: %+v", results)
+	}
+}
+
 func assertHasDiff(t *testing.T, diffs []Diff, component, action, target string) {
 	t.Helper()
 	for _, d := range diffs {
@@ -55,3 +141,250 @@ func assertHasDiff(t *testing.T, diffs []Diff, component, action, target string)
 	}
 	t.Fatalf("missing diff %s %s %s", component, action, target)
 }
+
+func TestCompareViews(t *testing.T) {
+	activeStage := bson.D{{Key: "$match", Value: bson.D{{Key: "active", Value: true}}}}
+
+	live := NewSchemaSpec()
+	live.Collections["users"] = struct{}{}
+	live.Views["active_users"] = ViewSpec{
+		Name:     "active_users",
+		Source:   "users",
+		Pipeline: mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "active", Value: false}}}}},
+	}
+	live.Views["legacy_view"] = ViewSpec{Name: "legacy_view", Source: "users"}
+
+	target := NewSchemaSpec()
+	target.Collections["users"] = struct{}{}
+	target.Views["active_users"] = ViewSpec{Name: "active_users", Source: "users", Pipeline: mongo.Pipeline{activeStage}}
+	target.Views["user_emails"] = ViewSpec{Name: "user_emails", Source: "users"}
+	target.MaterializedViews["daily_signups"] = MaterializedViewSpec{Name: "daily_signups", Source: "users"}
+
+	diffs := Compare(live, target)
+	if len(diffs) != 4 {
+		t.Fatalf("expected 4 diffs, got %d: %+v", len(diffs), diffs)
+	}
+	assertHasDiff(t, diffs, "view", "UpdateView", "active_users")
+	assertHasDiff(t, diffs, "view", "TrackView", "legacy_view")
+	assertHasDiff(t, diffs, "view", "AddView", "user_emails")
+	assertHasDiff(t, diffs, "view", "AddMaterializedView", "daily_signups")
+
+	live.Collections["daily_signups"] = struct{}{}
+	live.Views["active_users"] = target.Views["active_users"]
+	delete(live.Views, "legacy_view")
+	live.Views["user_emails"] = target.Views["user_emails"]
+	if diffs := Compare(live, target); len(diffs) != 0 {
+		t.Fatalf("expected no drift once views match, got %+v", diffs)
+	}
+}
+
+func TestParseListCollectionsDecodesNestedDocuments(t *testing.T) {
+	raw, err := bson.Marshal(bson.D{
+		{Key: "name", Value: "users"},
+		{Key: "options", Value: bson.D{
+			{Key: "validator", Value: bson.D{{Key: "$jsonSchema", Value: bson.D{{Key: "bsonType", Value: "object"}}}}},
+			{Key: "validationLevel", Value: "strict"},
+			{Key: "changeStreamPreAndPostImages", Value: bson.D{{Key: "enabled", Value: true}}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	validator, level := parseValidator(doc)
+	if level != "strict" || validator == nil {
+		t.Fatalf("expected strict validator, got %v %s", validator, level)
+	}
+	if _, ok := validator["$jsonSchema"].(bson.M); !ok {
+		t.Fatalf("expected nested schema to be normalised to bson.M, got %T", validator["$jsonSchema"])
+	}
+
+	opts := parseCollectionOptions(doc)
+	if opts.PreAndPostImages == nil || !*opts.PreAndPostImages {
+		t.Fatalf("expected pre/post images enabled, got %+v", opts)
+	}
+}
+
+func TestCompareShardKeys(t *testing.T) {
+	key := bson.D{{Key: "tenant", Value: int32(1)}}
+	eu := schema.ZoneSpec{
+		Name: "eu",
+		Min:  bson.D{{Key: "tenant", Value: bson.MinKey{}}},
+		Max:  bson.D{{Key: "tenant", Value: bson.MaxKey{}}},
+	}
+
+	live := NewSchemaSpec()
+	live.ShardKeys["orders"] = ShardKeySpec{Collection: "orders", Key: key}
+	live.ShardKeys["events"] = ShardKeySpec{Collection: "events", Key: key}
+	target := NewSchemaSpec()
+	target.ShardKeys["orders"] = ShardKeySpec{Collection: "orders", Key: key, Zones: []ZoneSpec{eu}}
+	target.ShardKeys["events"] = ShardKeySpec{Collection: "events", Key: bson.D{{Key: "_id", Value: "hashed"}}}
+	target.ShardKeys["users"] = ShardKeySpec{Collection: "users", Key: key}
+
+	got := map[string]Diff{}
+	for _, d := range compareShardKeys(live, target) {
+		got[d.Target] = d
+	}
+	if got["users"].Action != "ShardCollection" || got["users"].Current != "unsharded" {
+		t.Fatalf("expected unsharded users collection to be flagged, got %+v", got["users"])
+	}
+	if got["events"].Action != "ReshardCollection" || got["events"].Risk != "HIGH" {
+		t.Fatalf("expected mismatched key to need resharding, got %+v", got["events"])
+	}
+	if got["orders"].Action != "UpdateZones" || got["orders"].Proposed != "eu[tenant:MinKey → tenant:MaxKey)" {
+		t.Fatalf("expected zone change on orders, got %+v", got["orders"])
+	}
+
+	live.ShardKeysUnknown = true
+	if diffs := compareShardKeys(live, target); len(diffs) != 0 {
+		t.Fatalf("expected no shard key diffs when live metadata is unreadable, got %+v", diffs)
+	}
+	if !isUnauthorized(mongo.CommandError{Code: codeUnauthorized}) || isUnauthorized(mongo.CommandError{Code: 59}) {
+		t.Fatal("expected only code 13 to count as unauthorized")
+	}
+}
+
+func TestCompareSearchIndexes(t *testing.T) {
+	live := NewSchemaSpec()
+	live.SearchIndexes["products"] = map[string]SearchIndexSpec{
+		"default": {Collection: "products", Name: "default", Type: "search", Definition: bson.M{
+			"mappings": bson.D{{Key: "dynamic", Value: true}, {Key: "fields", Value: bson.M{}}},
+			"analyzer": "lucene.standard",
+		}},
+		"embedding_idx": {Collection: "products", Name: "embedding_idx", Type: "vectorSearch", Definition: bson.M{
+			"fields": bson.A{bson.D{{Key: "type", Value: "vector"}, {Key: "numDimensions", Value: int64(768)}}},
+		}},
+		"legacy": {Collection: "products", Name: "legacy", Definition: bson.M{"mappings": bson.M{"dynamic": true}}},
+	}
+	target := NewSchemaSpec()
+	target.SearchIndexes["products"] = map[string]SearchIndexSpec{
+		"default": {Collection: "products", Name: "default", Definition: bson.M{"mappings": bson.M{"dynamic": true}}},
+		"embedding_idx": {Collection: "products", Name: "embedding_idx", Type: "vectorSearch", Definition: bson.M{
+			"fields": bson.A{bson.M{"type": "vector", "numDimensions": int32(1536)}},
+		}},
+		"title_idx": {Collection: "products", Name: "title_idx", Definition: bson.M{"mappings": bson.M{"dynamic": false}}},
+	}
+
+	got := map[string]Diff{}
+	for _, d := range compareSearchIndexes(live, target) {
+		got[d.Target] = d
+	}
+	if _, ok := got["products.default"]; ok {
+		t.Fatalf("server defaults should not produce a diff, got %+v", got["products.default"])
+	}
+	if got["products.embedding_idx"].Action != "UpdateSearchIndex" {
+		t.Fatalf("expected changed dimensions to update the index, got %+v", got["products.embedding_idx"])
+	}
+	if got["products.title_idx"].Action != "AddSearchIndex" || got["products.legacy"].Action != "DropSearchIndex" {
+		t.Fatalf("unexpected diffs %+v", got)
+	}
+}
+
+func TestCompareHonorsOwnershipRules(t *testing.T) {
+	billing := schema.IgnoreRule{Pattern: "billing_*", Owner: "billing-service"}
+
+	live := NewSchemaSpec()
+	for _, coll := range []string{"billing_invoices", "system.profile", "orders"} {
+		live.Collections[coll] = struct{}{}
+	}
+	live.Indexes["billing_invoices"] = map[string]IndexSpec{
+		"idx_due": {Collection: "billing_invoices", Name: "idx_due", Keys: bson.D{{Key: "due", Value: int32(1)}}},
+	}
+	target := NewSchemaSpec()
+
+	diffs, ignored := CompareWithIgnored(live, target, IgnoreRules{billing, {Pattern: "system.*", Owner: "mongodb"}})
+	if len(diffs) != 1 || diffs[0].Target != "orders" {
+		t.Fatalf("expected only orders to be reported, got %+v", diffs)
+	}
+	if len(ignored) != 3 {
+		t.Fatalf("expected owned collection, its index and system.profile to be ignored, got %+v", ignored)
+	}
+
+	// Apply consults the registry so explicitly passed diffs are still skipped.
+	schema.MustRegisterIgnore(billing)
+	t.Cleanup(func() { schema.UnregisterIgnore(billing) })
+	drop := Diff{Component: "index", Action: "DropIndex", Target: "billing_invoices.idx_due"}
+	results, err := Apply(context.Background(), nil, target, []Diff{drop}, ApplyOptions{})
+	if err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if results[0].Status != ApplyStatusSkipped || results[0].Note != "owned by billing-service" {
+		t.Fatalf("expected owned index to be skipped, got %+v", results[0])
+	}
+}
+
+func TestSearchUnsupportedOnlyDropsMissingSearch(t *testing.T) {
+	for _, code := range []int32{codeCommandNotFound, codeSearchNotEnabled, codeUnrecognizedPipeStage} {
+		if err := searchUnsupported(mongo.CommandError{Code: code}); err != nil {
+			t.Fatalf("code %d should mean search is unsupported, got %v", code, err)
+		}
+	}
+	unauthorized := mongo.CommandError{Code: codeUnauthorized}
+	if err := searchUnsupported(unauthorized); err == nil {
+		t.Fatal("expected unauthorized error to be returned")
+	}
+	if err := searchUnsupported(context.DeadlineExceeded); err == nil {
+		t.Fatal("expected deadline error to be returned")
+	}
+}
+
+func TestIgnoreRulesMatchCollectionsWhole(t *testing.T) {
+	rules := IgnoreRules{{Pattern: "orders"}}
+	cases := []struct {
+		diff Diff
+		want bool
+	}{
+		{Diff{Component: "collection", Action: "TrackCollection", Target: "orders"}, true},
+		{Diff{Component: "collection", Action: "TrackCollection", Target: "orders.archive"}, false},
+		{Diff{Component: "validator", Action: "UpdateValidator", Target: "orders.archive"}, false},
+		{Diff{Component: "index", Action: "DropIndex", Target: "orders.idx_ts"}, true},
+		{Diff{Component: "collection", Action: "UpdateCollectionOption", Target: "orders.capped"}, true},
+	}
+	for _, c := range cases {
+		if got := rules.Match(c.diff); got != c.want {
+			t.Errorf("Match(%s %s) = %v, want %v", c.diff.Component, c.diff.Target, got, c.want)
+		}
+	}
+}
+
+func TestPipelineStringIsStable(t *testing.T) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"a": 1, "b": 2, "c": bson.M{"$in": bson.A{1, 2}}, "d": true}}},
+		{{Key: "$sort", Value: bson.D{{Key: "z", Value: 1}, {Key: "a", Value: -1}}}},
+	}
+	want := pipelineString(pipeline)
+	for range 50 {
+		if got := pipelineString(pipeline); got != want {
+			t.Fatalf("pipelineString changed between calls: %s vs %s", got, want)
+		}
+	}
+	reordered := mongo.Pipeline{pipeline[0], {{Key: "$sort", Value: bson.D{{Key: "a", Value: -1}, {Key: "z", Value: 1}}}}}
+	if pipelineString(reordered) == want {
+		t.Fatal("expected $sort key order to be part of the signature")
+	}
+}
+
+func TestSchemaSpecEmptyCoversEverySection(t *testing.T) {
+	if !NewSchemaSpec().Empty() {
+		t.Fatal("a new spec should be empty")
+	}
+	fill := []func(*SchemaSpec){
+		func(s *SchemaSpec) { s.Collections["users"] = struct{}{} },
+		func(s *SchemaSpec) { s.Options["users"] = CollectionOptions{} },
+		func(s *SchemaSpec) { s.Views["v"] = ViewSpec{Name: "v"} },
+		func(s *SchemaSpec) { s.MaterializedViews["m"] = MaterializedViewSpec{Name: "m"} },
+		func(s *SchemaSpec) { s.ShardKeys["users"] = ShardKeySpec{} },
+		func(s *SchemaSpec) { s.SearchIndexes["users"] = map[string]SearchIndexSpec{} },
+	}
+	for i, f := range fill {
+		spec := NewSchemaSpec()
+		f(&spec)
+		if spec.Empty() {
+			t.Errorf("case %d: expected a spec with content to be non-empty", i)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/schema"
+)
+
+type IgnoreRule = schema.IgnoreRule
+
+// IgnoreRules lists intentional differences and objects owned by other
+// services that should not count as drift.
+type IgnoreRules []IgnoreRule
+
+// RegisteredIgnoreRules returns the rules from schema files, config and
+// schema.RegisterIgnore, including the built-in system.* rule.
+func RegisteredIgnoreRules() IgnoreRules {
+	return IgnoreRules(schema.IgnoreRules())
+}
+
+// ParseIgnore reads one glob per line. Blank lines and lines starting with #
+// are skipped. A "component:" prefix limits the rule, e.g.
+//
+//	audit_*
+//	index:users.tmp_*
+//	validator:legacy_orders
+//
+// Globs use path.Match syntax and are matched against the diff target. Index
+// and collection option targets are also tried with every dotted prefix, so
+// "audit_*" also covers "audit_log.idx_ts".
+func ParseIgnore(r io.Reader) (IgnoreRules, error) {
+	var rules IgnoreRules
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule := IgnoreRule{Pattern: text}
+		if component, pattern, ok := strings.Cut(text, ":"); ok {
+			rule = IgnoreRule{Component: strings.TrimSpace(component), Pattern: strings.TrimSpace(pattern)}
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+func LoadIgnoreFile(filename string) (IgnoreRules, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules, err := ParseIgnore(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return rules, nil
+}
+
+// Match reports whether any rule covers d.
+func (r IgnoreRules) Match(d Diff) bool {
+	_, ok := r.Rule(d)
+	return ok
+}
+
+// Rule returns the first rule covering d.
+func (r IgnoreRules) Rule(d Diff) (IgnoreRule, bool) {
+	if d.Action != "UpdateCollectionOption" {
+		return r.RuleFor(d.Component, d.Target)
+	}
+	// Option targets are "<collection>.<option>", so a rule for the
+	// collection covers its options.
+	for _, rule := range r {
+		if rule.MatchesNested(d.Component, d.Target) {
+			return rule, true
+		}
+	}
+	return IgnoreRule{}, false
+}
+
+// RuleFor returns the first rule covering target of the given component.
+func (r IgnoreRules) RuleFor(component, target string) (IgnoreRule, bool) {
+	for _, rule := range r {
+		if rule.Matches(component, target) {
+			return rule, true
+		}
+	}
+	return IgnoreRule{}, false
+}
+
+// Filter splits diffs into the ones still reported and the ones ignored.
+func (r IgnoreRules) Filter(diffs []Diff) (kept, ignored []Diff) {
+	for _, d := range diffs {
+		if r.Match(d) {
+			ignored = append(ignored, d)
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept, ignored
+}
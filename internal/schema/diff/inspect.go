@@ -3,6 +3,7 @@ package diff
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"sort"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -29,7 +30,14 @@ func InspectLive(ctx context.Context, db *mongo.Database) (SchemaSpec, error) {
 		if !ok {
 			continue
 		}
+		if collType, _ := doc["type"].(string); collType == "view" {
+			spec.Views[collName] = parseView(collName, doc)
+			continue
+		}
 		spec.Collections[collName] = struct{}{}
+		if opts := parseCollectionOptions(doc); !opts.IsZero() {
+			spec.Options[collName] = opts
+		}
 
 		validator, level := parseValidator(doc)
 		if len(validator) > 0 {
@@ -49,16 +57,36 @@ func InspectLive(ctx context.Context, db *mongo.Database) (SchemaSpec, error) {
 		}
 	}
 
-	return spec, cur.Err()
+	if err := cur.Err(); err != nil {
+		return spec, err
+	}
+
+	shardKeys, err := readShardKeys(ctx, db)
+	switch {
+	case isUnauthorized(err):
+		// Reading config.collections needs cluster-wide read access; without
+		// it the rest of the schema can still be compared.
+		slog.Warn("Cannot read sharding metadata; skipping shard key comparison", "error", err)
+		spec.ShardKeysUnknown = true
+	case err != nil:
+		return spec, err
+	}
+	for coll, sk := range shardKeys {
+		spec.ShardKeys[coll] = sk
+	}
+	if err := readSearchIndexes(ctx, db, &spec); err != nil {
+		return spec, err
+	}
+	return spec, nil
 }
 
 func parseValidator(collDoc bson.M) (bson.M, string) {
-	opts, _ := collDoc["options"].(bson.M)
+	opts, _ := toBsonM(collDoc["options"])
 	if opts == nil {
 		return nil, "off"
 	}
 
-	validator, _ := opts["validator"].(bson.M)
+	validator, _ := toBsonM(opts["validator"])
 	level, ok := opts["validationLevel"].(string)
 	if !ok || level == "" {
 		level = "off"
@@ -133,6 +161,43 @@ func toBsonD(value interface{}) bson.D {
 	}
 }
 
+// toBsonM converts a decoded document into bson.M, recursively. The v2 driver
+// decodes nested documents as bson.D even when the outer target is bson.M.
+func toBsonM(value interface{}) (bson.M, bool) {
+	switch v := value.(type) {
+	case bson.M:
+		out := make(bson.M, len(v))
+		for k, item := range v {
+			out[k] = normalizeValue(item)
+		}
+		return out, true
+	case bson.D:
+		out := make(bson.M, len(v))
+		for _, elem := range v {
+			out[elem.Key] = normalizeValue(elem.Value)
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.M, bson.D:
+		m, _ := toBsonM(v)
+		return m
+	case bson.A:
+		out := make(bson.A, len(v))
+		for i, item := range v {
+			out[i] = normalizeValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
 func toInt32(value interface{}) (int32, bool) {
 	switch v := value.(type) {
 	case int32:
@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type CollectionOptions = schema.CollectionOptions
+
+// mutableOptions lists options collMod can change in place; everything else
+// requires recreating the collection.
+var mutableOptions = map[string]bool{
+	"size":                         true,
+	"max":                          true,
+	"timeseries.granularity":       true,
+	"changeStreamPreAndPostImages": true,
+	"expireAfterSeconds":           true,
+}
+
+func parseCollectionOptions(collDoc bson.M) CollectionOptions {
+	var opts CollectionOptions
+	raw, _ := toBsonM(collDoc["options"])
+	if raw == nil {
+		return opts
+	}
+
+	if capped, ok := raw["capped"].(bool); ok && capped {
+		opts.Capped = true
+		opts.SizeBytes = toInt64(raw["size"])
+		opts.MaxDocuments = toInt64(raw["max"])
+	}
+	if ts, ok := toBsonM(raw["timeseries"]); ok {
+		opts.TimeSeries = &schema.TimeSeriesOptions{}
+		opts.TimeSeries.TimeField, _ = ts["timeField"].(string)
+		opts.TimeSeries.MetaField, _ = ts["metaField"].(string)
+		opts.TimeSeries.Granularity, _ = ts["granularity"].(string)
+	}
+	if clustered, ok := raw["clusteredIndex"].(bool); ok {
+		opts.ClusteredIndex = clustered
+	} else if _, ok := toBsonM(raw["clusteredIndex"]); ok {
+		opts.ClusteredIndex = true
+	}
+	if images, ok := toBsonM(raw["changeStreamPreAndPostImages"]); ok {
+		enabled, _ := images["enabled"].(bool)
+		opts.PreAndPostImages = &enabled
+	}
+	if collation, ok := toBsonM(raw["collation"]); ok {
+		opts.Collation = collation
+	}
+	if _, ok := raw["expireAfterSeconds"]; ok {
+		ttl := toInt64(raw["expireAfterSeconds"])
+		opts.ExpireAfterSeconds = &ttl
+	}
+	return opts
+}
+
+// optionFields flattens declared options into comparable strings.
+func optionFields(o CollectionOptions) map[string]string {
+	fields := make(map[string]string)
+	if o.Capped {
+		fields["capped"] = "true"
+		if o.SizeBytes > 0 {
+			fields["size"] = fmt.Sprintf("%d", o.SizeBytes)
+		}
+		if o.MaxDocuments > 0 {
+			fields["max"] = fmt.Sprintf("%d", o.MaxDocuments)
+		}
+	}
+	if ts := o.TimeSeries; ts != nil {
+		fields["timeseries.timeField"] = ts.TimeField
+		if ts.MetaField != "" {
+			fields["timeseries.metaField"] = ts.MetaField
+		}
+		if ts.Granularity != "" {
+			fields["timeseries.granularity"] = ts.Granularity
+		}
+	}
+	if o.ClusteredIndex {
+		fields["clusteredIndex"] = "true"
+	}
+	if o.PreAndPostImages != nil {
+		fields["changeStreamPreAndPostImages"] = fmt.Sprintf("%t", *o.PreAndPostImages)
+	}
+	for k, v := range o.Collation {
+		fields["collation."+k] = fmt.Sprintf("%v", v)
+	}
+	if o.ExpireAfterSeconds != nil {
+		fields["expireAfterSeconds"] = fmt.Sprintf("%d", *o.ExpireAfterSeconds)
+	}
+	return fields
+}
+
+// compareOptions reports every option declared in target whose live value differs.
+func compareOptions(coll string, live, target CollectionOptions) []Diff {
+	liveFields := optionFields(live)
+	targetFields := optionFields(target)
+
+	names := make([]string, 0, len(targetFields))
+	for name := range targetFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var diffs []Diff
+	for _, name := range names {
+		current, ok := liveFields[name]
+		if ok && current == targetFields[name] {
+			continue
+		}
+		if !ok {
+			current = "unset"
+		}
+		risk := RiskHigh
+		if mutableOptions[name] {
+			risk = RiskLow
+		}
+		diffs = append(diffs, Diff{
+			Component: "collection",
+			Action:    "UpdateCollectionOption",
+			Target:    fmt.Sprintf("%s.%s", coll, name),
+			Current:   current,
+			Proposed:  targetFields[name],
+			Risk:      risk,
+		})
+	}
+	return diffs
+}
+
+// collModForOption builds the collMod fields that change a single mutable option.
+func collModForOption(opts CollectionOptions, name string) (bson.D, bool) {
+	switch name {
+	case "size":
+		return bson.D{{Key: "cappedSize", Value: opts.SizeBytes}}, true
+	case "max":
+		return bson.D{{Key: "cappedMax", Value: opts.MaxDocuments}}, true
+	case "timeseries.granularity":
+		return bson.D{{Key: "timeseries", Value: bson.D{{Key: "granularity", Value: opts.TimeSeries.Granularity}}}}, true
+	case "changeStreamPreAndPostImages":
+		return bson.D{{Key: "changeStreamPreAndPostImages", Value: bson.D{
+			{Key: "enabled", Value: *opts.PreAndPostImages},
+		}}}, true
+	case "expireAfterSeconds":
+		return bson.D{{Key: "expireAfterSeconds", Value: *opts.ExpireAfterSeconds}}, true
+	default:
+		return nil, false
+	}
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
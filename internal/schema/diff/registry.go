@@ -9,6 +9,9 @@ func FromRegistry() SchemaSpec {
 	spec := NewSchemaSpec()
 	for _, collection := range schema.Collections() {
 		spec.Collections[collection] = struct{}{}
+		if opts, ok := schema.CollectionOptionsFor(collection); ok && !opts.IsZero() {
+			spec.Options[collection] = opts
+		}
 	}
 
 	for _, idx := range schema.Indexes() {
@@ -27,6 +30,26 @@ func FromRegistry() SchemaSpec {
 		}
 	}
 
+	for _, v := range schema.Views() {
+		spec.Views[v.Name] = v
+	}
+	for _, mv := range schema.MaterializedViews() {
+		spec.MaterializedViews[mv.Name] = mv
+	}
+
+	for _, si := range schema.SearchIndexes() {
+		spec.Collections[si.Collection] = struct{}{}
+		if spec.SearchIndexes[si.Collection] == nil {
+			spec.SearchIndexes[si.Collection] = make(map[string]SearchIndexSpec)
+		}
+		spec.SearchIndexes[si.Collection][si.Name] = si
+	}
+
+	for _, sk := range schema.ShardKeys() {
+		spec.Collections[sk.Collection] = struct{}{}
+		spec.ShardKeys[sk.Collection] = sk
+	}
+
 	for _, v := range schema.Validators() {
 		spec.Collections[v.Collection] = struct{}{}
 		spec.Validators[v.Collection] = ValidatorSpec{
@@ -0,0 +1,220 @@
+package diff
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit renders one test case per diff, grouped into a suite per
+// component. Diffs at or above failOn are failures; the rest are skipped so
+// they stay visible without failing the build. An empty failOn fails every diff.
+func WriteJUnit(w io.Writer, diffs []Diff, failOn string) error {
+	byComponent := make(map[string][]Diff)
+	for _, d := range diffs {
+		byComponent[d.Component] = append(byComponent[d.Component], d)
+	}
+
+	report := junitSuites{Name: "schema drift", Tests: len(diffs)}
+	for _, component := range sortedKeys(byComponent) {
+		suite := junitSuite{Name: component}
+		for _, d := range byComponent[component] {
+			tc := junitCase{Name: d.Action + " " + d.Target, Classname: "schema." + component}
+			msg := &junitMessage{
+				Message: fmt.Sprintf("%s risk: %s", d.Risk, d.Action),
+				Type:    d.Risk,
+				Text:    fmt.Sprintf("current: %s\nproposed: %s", d.Current, d.Proposed),
+			}
+			if failOn == "" || d.AtLeast(failOn) {
+				tc.Failure = msg
+				suite.Failures++
+			} else {
+				tc.Skipped = msg
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Tests = len(suite.Cases)
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string    `json:"id"`
+	ShortDescription sarifText `json:"shortDescription"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifText       `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIF renders diffs as a SARIF 2.1.0 log for code scanning UIs. Rules
+// are the diff actions; HIGH and CRITICAL map to error, MEDIUM to warning and
+// everything else to note.
+func WriteSARIF(w io.Writer, diffs []Diff) error {
+	rules := make(map[string]sarifRule)
+	results := make([]sarifResult, 0, len(diffs))
+	for _, d := range diffs {
+		ruleID := d.Component + "/" + d.Action
+		rules[ruleID] = sarifRule{ID: ruleID, ShortDescription: sarifText{Text: d.Action + " (" + d.Component + ")"}}
+		results = append(results, sarifResult{
+			RuleID:  ruleID,
+			Level:   sarifLevel(d.Risk),
+			Message: sarifText{Text: fmt.Sprintf("%s %s: %s -> %s", d.Action, d.Target, d.Current, d.Proposed)},
+			Locations: []sarifLocation{{LogicalLocations: []sarifLogicalLocation{{
+				Name:               d.Target,
+				FullyQualifiedName: d.Component + ":" + d.Target,
+				Kind:               "member",
+			}}}},
+			Properties: map[string]any{"risk": d.Risk},
+		})
+	}
+
+	driver := sarifDriver{Name: "mongork", Rules: make([]sarifRule, 0, len(rules))}
+	for _, id := range sortedKeys(rules) {
+		driver.Rules = append(driver.Rules, rules[id])
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
+
+func sarifLevel(risk string) string {
+	switch risk {
+	case RiskHigh, RiskCritical:
+		return "error"
+	case RiskMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// WriteMarkdown renders a summary line and a table, suitable for a pull
+// request comment or a CI job summary.
+func WriteMarkdown(w io.Writer, diffs []Diff) error {
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintln(w, "### Schema drift\n\nNo schema drift detected.")
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, d := range diffs {
+		counts[d.Risk]++
+	}
+	risks := make([]string, 0, len(counts))
+	for risk := range counts {
+		risks = append(risks, risk)
+	}
+	sort.Slice(risks, func(i, j int) bool { return riskRank[risks[i]] > riskRank[risks[j]] })
+	summary := make([]string, 0, len(risks))
+	for _, risk := range risks {
+		summary = append(summary, fmt.Sprintf("%d %s", counts[risk], risk))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Schema drift\n\n%d difference(s): %s\n\n", len(diffs), join(summary, ", "))
+	b.WriteString("| Component | Action | Target | Current | Proposed | Risk |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, d := range diffs {
+		fmt.Fprintf(&b, "| %s | %s | `%s` | %s | %s | %s |\n",
+			markdownCell(d.Component), markdownCell(d.Action), markdownCell(d.Target),
+			markdownCell(d.Current), markdownCell(d.Proposed), markdownCell(d.Risk))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
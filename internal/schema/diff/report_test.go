@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var reportDiffs = []Diff{
+	{
+		Component: "index", Action: "AddIndex", Target: "users.idx_email",
+		Current: "missing", Proposed: "keys={email:1}", Risk: "LOW",
+	},
+	{
+		Component: "index", Action: "DropIndex", Target: "audit_log.idx_ts",
+		Current: "keys={ts:1}", Proposed: "removed", Risk: "HIGH",
+	},
+	{Component: "validator", Action: "UpdateValidator", Target: "orders", Current: "a|b", Proposed: "c", Risk: "MEDIUM"},
+}
+
+func TestIgnoreRulesFilter(t *testing.T) {
+	rules, err := ParseIgnore(strings.NewReader("# intentional\naudit_*\n\nvalidator:orders\nindex:orders\n"))
+	if err != nil {
+		t.Fatalf("ParseIgnore returned error: %v", err)
+	}
+	kept, ignored := rules.Filter(reportDiffs)
+	if len(ignored) != 2 || len(kept) != 1 || kept[0].Target != "users.idx_email" {
+		t.Fatalf("unexpected filter result kept=%+v ignored=%+v", kept, ignored)
+	}
+
+	if _, err := ParseIgnore(strings.NewReader("users.[\n")); err == nil {
+		t.Fatal("expected error for malformed glob")
+	}
+}
+
+func TestFilterRisk(t *testing.T) {
+	if got := FilterRisk(reportDiffs, RiskMedium); len(got) != 2 {
+		t.Fatalf("expected MEDIUM and HIGH diffs, got %+v", got)
+	}
+	if _, err := ParseRisk("severe"); err == nil {
+		t.Fatal("expected error for unknown risk")
+	}
+	if risk, _ := ParseRisk(" high "); risk != RiskHigh {
+		t.Fatalf("expected HIGH, got %q", risk)
+	}
+}
+
+func TestWriteJUnitSkipsDiffsBelowThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, reportDiffs, RiskMedium); err != nil {
+		t.Fatalf("WriteJUnit returned error: %v", err)
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid junit xml: %v\n%s", err, buf.String())
+	}
+	if report.Tests != 3 || report.Failures != 2 {
+		t.Fatalf("expected 3 tests and 2 failures, got %d/%d", report.Tests, report.Failures)
+	}
+	if report.Suites[0].Name != "index" || report.Suites[0].Skipped != 1 {
+		t.Fatalf("expected LOW index diff to be skipped, got %+v", report.Suites[0])
+	}
+}
+
+func TestWriteSARIFLevels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, reportDiffs); err != nil {
+		t.Fatalf("WriteSARIF returned error: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid sarif json: %v", err)
+	}
+	levels := map[string]string{}
+	for _, r := range log.Runs[0].Results {
+		levels[r.RuleID] = r.Level
+	}
+	if levels["index/DropIndex"] != "error" || levels["validator/UpdateValidator"] != "warning" ||
+		levels["index/AddIndex"] != "note" {
+		t.Fatalf("unexpected levels %v", levels)
+	}
+	if len(log.Runs[0].Tool.Driver.Rules) != 3 {
+		t.Fatalf("expected one rule per action, got %+v", log.Runs[0].Tool.Driver.Rules)
+	}
+}
+
+func TestWriteMarkdownEscapesPipes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, reportDiffs); err != nil {
+		t.Fatalf("WriteMarkdown returned error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "3 difference(s): 1 HIGH, 1 MEDIUM, 1 LOW") {
+		t.Fatalf("missing summary line:\n%s", out)
+	}
+	if !strings.Contains(out, `| a\|b |`) {
+		t.Fatalf("expected pipe to be escaped:\n%s", out)
+	}
+}
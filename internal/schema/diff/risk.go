@@ -0,0 +1,49 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	RiskLow      = "LOW"
+	RiskMedium   = "MEDIUM"
+	RiskHigh     = "HIGH"
+	RiskCritical = "CRITICAL"
+)
+
+var ErrUnknownRisk = errors.New("unknown risk level (use low, medium, high or critical)")
+
+var riskRank = map[string]int{
+	RiskLow:      1,
+	RiskMedium:   2,
+	RiskHigh:     3,
+	RiskCritical: 4,
+}
+
+// ParseRisk normalizes a user supplied risk level such as "high".
+func ParseRisk(s string) (string, error) {
+	risk := strings.ToUpper(strings.TrimSpace(s))
+	if _, ok := riskRank[risk]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownRisk, s)
+	}
+	return risk, nil
+}
+
+// AtLeast reports whether the diff's risk is threshold or higher. Unknown
+// risks rank below LOW.
+func (d Diff) AtLeast(threshold string) bool {
+	return riskRank[d.Risk] >= riskRank[threshold]
+}
+
+// FilterRisk returns the diffs whose risk is threshold or higher.
+func FilterRisk(diffs []Diff, threshold string) []Diff {
+	var out []Diff
+	for _, d := range diffs {
+		if d.AtLeast(threshold) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
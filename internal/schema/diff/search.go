@@ -0,0 +1,203 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/drewjocham/mongork/internal/migration"
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type SearchIndexSpec = schema.SearchIndexSpec
+
+// Server error codes meaning the deployment cannot list search indexes at all.
+const (
+	codeCommandNotFound       = 59
+	codeCommandNotSupported   = 115
+	codeSearchNotEnabled      = 31082
+	codeUnrecognizedPipeStage = 40324
+)
+
+// readSearchIndexes lists search and vector search indexes on every collection
+// into spec. Only Atlas and the Atlas local image run mongot; elsewhere
+// $listSearchIndexes is not supported and the result is empty, so the first
+// such error stops further probing. Any other error is returned.
+func readSearchIndexes(ctx context.Context, db *mongo.Database, spec *SchemaSpec) error {
+	for _, coll := range sortedKeys(spec.Collections) {
+		cur, err := db.Collection(coll).SearchIndexes().List(ctx, nil)
+		if err != nil {
+			return searchUnsupported(err)
+		}
+		var rows []struct {
+			Name             string `bson:"name"`
+			Type             string `bson:"type"`
+			LatestDefinition bson.M `bson:"latestDefinition"`
+		}
+		if err := cur.All(ctx, &rows); err != nil {
+			return searchUnsupported(err)
+		}
+		for _, row := range rows {
+			definition, _ := toBsonM(row.LatestDefinition)
+			if spec.SearchIndexes[coll] == nil {
+				spec.SearchIndexes[coll] = make(map[string]SearchIndexSpec)
+			}
+			spec.SearchIndexes[coll][row.Name] = SearchIndexSpec{
+				Collection: coll,
+				Name:       row.Name,
+				Type:       row.Type,
+				Definition: definition,
+			}
+		}
+	}
+	return nil
+}
+
+// searchUnsupported drops errors that only mean there is no search support.
+func searchUnsupported(err error) error {
+	var se mongo.ServerError
+	if errors.As(err, &se) && (se.HasErrorCode(codeCommandNotFound) ||
+		se.HasErrorCode(codeCommandNotSupported) ||
+		se.HasErrorCode(codeSearchNotEnabled) ||
+		se.HasErrorCode(codeUnrecognizedPipeStage)) {
+		return nil
+	}
+	return err
+}
+
+// compareSearchIndexes matches search indexes by collection and name. The
+// server fills in analyzer and mapping defaults, so a live definition only
+// differs when it lacks or changes something the declaration sets.
+func compareSearchIndexes(live, target SchemaSpec) []Diff {
+	var diffs []Diff
+	for _, coll := range unionKeys(live.SearchIndexes, target.SearchIndexes) {
+		liveIdx := live.SearchIndexes[coll]
+		targetIdx := target.SearchIndexes[coll]
+		for _, name := range unionKeys(liveIdx, targetIdx) {
+			l, liveOK := liveIdx[name]
+			t, targetOK := targetIdx[name]
+			full := coll + "." + name
+			switch {
+			case !liveOK && targetOK:
+				diffs = append(diffs, Diff{
+					Component: "search_index",
+					Action:    "AddSearchIndex",
+					Target:    full,
+					Current:   "missing",
+					Proposed:  searchIndexSummary(t),
+					Risk:      RiskLow,
+				})
+			case liveOK && !targetOK:
+				diffs = append(diffs, Diff{
+					Component: "search_index",
+					Action:    "DropSearchIndex",
+					Target:    full,
+					Current:   searchIndexSummary(l),
+					Proposed:  "removed",
+					Risk:      RiskHigh,
+				})
+			case liveOK && targetOK:
+				if l.IndexType() != t.IndexType() || !containsValue(l.Definition, t.Definition) {
+					diffs = append(diffs, Diff{
+						Component: "search_index",
+						Action:    "UpdateSearchIndex",
+						Target:    full,
+						Current:   searchIndexSummary(l),
+						Proposed:  searchIndexSummary(t),
+						Risk:      RiskMedium,
+					})
+				}
+			}
+		}
+	}
+	return diffs
+}
+
+// containsValue reports whether every field set in want is present with the
+// same value in got. Arrays must match element by element.
+func containsValue(got, want any) bool {
+	if wm, ok := toBsonM(want); ok {
+		gm, ok := toBsonM(got)
+		if !ok {
+			return false
+		}
+		for k, wv := range wm {
+			gv, present := gm[k]
+			if !present || !containsValue(gv, wv) {
+				return false
+			}
+		}
+		return true
+	}
+	wa, wantArray := normalizeValue(want).(bson.A)
+	ga, gotArray := normalizeValue(got).(bson.A)
+	if wantArray || gotArray {
+		if !wantArray || !gotArray || len(wa) != len(ga) {
+			return false
+		}
+		for i := range wa {
+			if !containsValue(ga[i], wa[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if reflect.DeepEqual(got, want) {
+		return true
+	}
+	// YAML and the server disagree on integer widths; compare numbers by value.
+	gf, gNum := toFloat(got)
+	wf, wNum := toFloat(want)
+	return gNum && wNum && gf == wf
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func searchIndexSummary(spec SearchIndexSpec) string {
+	return fmt.Sprintf("type=%s def=%s", spec.IndexType(), canonicalJSON(spec.Definition))
+}
+
+func findTargetSearchIndex(target SchemaSpec, full string) (SearchIndexSpec, bool) {
+	for coll, indexes := range target.SearchIndexes {
+		for name, idx := range indexes {
+			if coll+"."+name == full {
+				return idx, true
+			}
+		}
+	}
+	return SearchIndexSpec{}, false
+}
+
+// applyAddSearchIndex submits the build and returns; search indexes become
+// queryable asynchronously (see migration.WaitForSearchIndex).
+func applyAddSearchIndex(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, _ ApplyOptions) error {
+	idx, ok := findTargetSearchIndex(target, d.Target)
+	if !ok {
+		return fmt.Errorf("search index %s not found in target schema", d.Target)
+	}
+	return migration.CreateSearchIndex(ctx, db.Collection(idx.Collection), idx)
+}
+
+func applyUpdateSearchIndex(ctx context.Context, db *mongo.Database, target SchemaSpec, d Diff, _ ApplyOptions) error {
+	idx, ok := findTargetSearchIndex(target, d.Target)
+	if !ok {
+		return fmt.Errorf("search index %s not found in target schema", d.Target)
+	}
+	return migration.UpdateSearchIndex(ctx, db.Collection(idx.Collection), idx)
+}
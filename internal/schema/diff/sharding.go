@@ -0,0 +1,191 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type (
+	ShardKeySpec = schema.ShardKeySpec
+	ZoneSpec     = schema.ZoneSpec
+)
+
+// readShardKeys returns the sharded collections of db from config.collections
+// and their zone ranges from config.tags. Outside a mongos there is no
+// sharding metadata and the result is empty.
+func readShardKeys(ctx context.Context, db *mongo.Database) (map[string]ShardKeySpec, error) {
+	var hello bson.M
+	if err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return nil, err
+	}
+	if msg, _ := hello["msg"].(string); msg != "isdbgrid" {
+		return nil, nil
+	}
+
+	config := db.Client().Database("config")
+	prefix := db.Name() + "."
+	nsFilter := bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(prefix)}}
+
+	cur, err := config.Collection("collections").Find(ctx, bson.D{
+		{Key: "_id", Value: nsFilter},
+		{Key: "dropped", Value: bson.D{{Key: "$ne", Value: true}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var colls []struct {
+		NS     string `bson:"_id"`
+		Key    bson.D `bson:"key"`
+		Unique bool   `bson:"unique"`
+	}
+	if err := cur.All(ctx, &colls); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]ShardKeySpec, len(colls))
+	for _, c := range colls {
+		name := strings.TrimPrefix(c.NS, prefix)
+		out[name] = ShardKeySpec{Collection: name, Key: c.Key, Unique: c.Unique}
+	}
+
+	cur, err = config.Collection("tags").Find(ctx, bson.D{{Key: "ns", Value: nsFilter}},
+		options.Find().SetSort(bson.D{{Key: "ns", Value: 1}, {Key: "min", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var tags []struct {
+		NS  string `bson:"ns"`
+		Tag string `bson:"tag"`
+		Min bson.D `bson:"min"`
+		Max bson.D `bson:"max"`
+	}
+	if err := cur.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		name := strings.TrimPrefix(t.NS, prefix)
+		spec, ok := out[name]
+		if !ok {
+			continue
+		}
+		spec.Zones = append(spec.Zones, ZoneSpec{Name: t.Tag, Min: t.Min, Max: t.Max})
+		out[name] = spec
+	}
+	return out, nil
+}
+
+// codeUnauthorized is returned when the user may not read config.collections
+// or config.tags.
+const codeUnauthorized = 13
+
+func isUnauthorized(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorCode(codeUnauthorized)
+}
+
+// compareShardKeys flags collections that should be sharded but are not, and
+// sharded collections whose key or zones differ from the declaration.
+// Presplit points only apply when sharding and are not compared.
+func compareShardKeys(live, target SchemaSpec) []Diff {
+	if live.ShardKeysUnknown || target.ShardKeysUnknown {
+		return nil
+	}
+	var diffs []Diff
+	for _, coll := range unionKeys(live.ShardKeys, target.ShardKeys) {
+		liveKey, liveOK := live.ShardKeys[coll]
+		targetKey, targetOK := target.ShardKeys[coll]
+		switch {
+		case !liveOK && targetOK:
+			diffs = append(diffs, Diff{
+				Component: "sharding",
+				Action:    "ShardCollection",
+				Target:    coll,
+				Current:   "unsharded",
+				Proposed:  shardKeySummary(targetKey),
+				Risk:      RiskMedium,
+			})
+		case liveOK && !targetOK:
+			diffs = append(diffs, Diff{
+				Component: "sharding",
+				Action:    "TrackShardKey",
+				Target:    coll,
+				Current:   shardKeySummary(liveKey),
+				Proposed:  "missing from registry",
+				Risk:      RiskLow,
+			})
+		case liveOK && targetOK:
+			if shardKeySignature(liveKey) != shardKeySignature(targetKey) {
+				diffs = append(diffs, Diff{
+					Component: "sharding",
+					Action:    "ReshardCollection",
+					Target:    coll,
+					Current:   shardKeySummary(liveKey),
+					Proposed:  shardKeySummary(targetKey),
+					Risk:      RiskHigh,
+				})
+			} else if zonesSignature(liveKey.Zones) != zonesSignature(targetKey.Zones) {
+				diffs = append(diffs, Diff{
+					Component: "sharding",
+					Action:    "UpdateZones",
+					Target:    coll,
+					Current:   zonesSignature(liveKey.Zones),
+					Proposed:  zonesSignature(targetKey.Zones),
+					Risk:      RiskMedium,
+				})
+			}
+		}
+	}
+	return diffs
+}
+
+func shardKeySignature(spec ShardKeySpec) string {
+	return fmt.Sprintf("k=%s|u=%t", formatBsonD(spec.Key), spec.Unique)
+}
+
+func zonesSignature(zones []ZoneSpec) string {
+	if len(zones) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(zones))
+	for _, z := range zones {
+		parts = append(parts, fmt.Sprintf("%s[%s → %s)", z.Name, formatBound(z.Min), formatBound(z.Max)))
+	}
+	sort.Strings(parts)
+	return join(parts, "; ")
+}
+
+// formatBound spells out MinKey/MaxKey, which otherwise both print as "{}".
+func formatBound(doc bson.D) string {
+	parts := make([]string, 0, len(doc))
+	for _, e := range doc {
+		value := fmt.Sprintf("%v", e.Value)
+		switch e.Value.(type) {
+		case bson.MinKey:
+			value = "MinKey"
+		case bson.MaxKey:
+			value = "MaxKey"
+		}
+		parts = append(parts, e.Key+":"+value)
+	}
+	return join(parts, ", ")
+}
+
+func shardKeySummary(spec ShardKeySpec) string {
+	summary := fmt.Sprintf("key={%s}", formatBsonD(spec.Key))
+	if spec.Unique {
+		summary += " unique"
+	}
+	if len(spec.Zones) > 0 {
+		summary += " zones=" + zonesSignature(spec.Zones)
+	}
+	return summary
+}
@@ -0,0 +1,280 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// SnapshotFormat is bumped whenever the serialized snapshot layout changes.
+const SnapshotFormat = 1
+
+const snapshotTimeLayout = "20060102_150405"
+
+var (
+	ErrSnapshotNotFound      = errors.New("schema snapshot not found")
+	ErrSnapshotFormat        = errors.New("unsupported schema snapshot format")
+	ErrSnapshotStoreRequired = errors.New("schema snapshot store not configured")
+)
+
+// Snapshot is a SchemaSpec frozen at a point in time. Maps are flattened into
+// sorted slices so the serialized form is stable and reviewable in git.
+type Snapshot struct {
+	Name              string                       `bson:"_id"`
+	Format            int                          `bson:"format"`
+	Database          string                       `bson:"database"`
+	CreatedAt         time.Time                    `bson:"createdAt"`
+	MigrationVersion  string                       `bson:"migrationVersion,omitempty"`
+	Collections       []string                     `bson:"collections"`
+	Options           map[string]CollectionOptions `bson:"options,omitempty"`
+	Indexes           []IndexSpec                  `bson:"indexes"`
+	Validators        []ValidatorSpec              `bson:"validators"`
+	Views             []ViewSpec                   `bson:"views,omitempty"`
+	MaterializedViews []MaterializedViewSpec       `bson:"materializedViews,omitempty"`
+	ShardKeys         []ShardKeySpec               `bson:"shardKeys,omitempty"`
+	SearchIndexes     []SearchIndexSpec            `bson:"searchIndexes,omitempty"`
+}
+
+// NewSnapshot captures spec. migrationVersion is the last applied migration,
+// if known, so snapshots can be matched to a point in the migration history.
+func NewSnapshot(spec SchemaSpec, database, migrationVersion string, at time.Time) Snapshot {
+	at = at.UTC()
+	snap := Snapshot{
+		Name:             at.Format(snapshotTimeLayout),
+		Format:           SnapshotFormat,
+		Database:         database,
+		CreatedAt:        at,
+		MigrationVersion: migrationVersion,
+		Collections:      sortedKeys(spec.Collections),
+		Options:          spec.Options,
+		Indexes:          []IndexSpec{},
+		Validators:       []ValidatorSpec{},
+	}
+	if migrationVersion != "" {
+		snap.Name += "_" + migrationVersion
+	}
+	for _, coll := range sortedKeys(spec.Indexes) {
+		for _, name := range sortedKeys(spec.Indexes[coll]) {
+			snap.Indexes = append(snap.Indexes, spec.Indexes[coll][name])
+		}
+	}
+	for _, coll := range sortedKeys(spec.Validators) {
+		snap.Validators = append(snap.Validators, spec.Validators[coll])
+	}
+	for _, name := range sortedKeys(spec.Views) {
+		snap.Views = append(snap.Views, spec.Views[name])
+	}
+	for _, name := range sortedKeys(spec.MaterializedViews) {
+		snap.MaterializedViews = append(snap.MaterializedViews, spec.MaterializedViews[name])
+	}
+	for _, coll := range sortedKeys(spec.ShardKeys) {
+		snap.ShardKeys = append(snap.ShardKeys, spec.ShardKeys[coll])
+	}
+	for _, coll := range sortedKeys(spec.SearchIndexes) {
+		for _, name := range sortedKeys(spec.SearchIndexes[coll]) {
+			snap.SearchIndexes = append(snap.SearchIndexes, spec.SearchIndexes[coll][name])
+		}
+	}
+	return snap
+}
+
+// Spec rebuilds the SchemaSpec so two snapshots, or a snapshot and the live
+// database, can be passed to Compare.
+func (s Snapshot) Spec() SchemaSpec {
+	spec := NewSchemaSpec()
+	for _, coll := range s.Collections {
+		spec.Collections[coll] = struct{}{}
+	}
+	for coll, opts := range s.Options {
+		if m, ok := toBsonM(opts.Collation); ok {
+			opts.Collation = m
+		}
+		spec.Options[coll] = opts
+	}
+	for _, idx := range s.Indexes {
+		if spec.Indexes[idx.Collection] == nil {
+			spec.Indexes[idx.Collection] = make(map[string]IndexSpec)
+		}
+		spec.Indexes[idx.Collection][idx.Name] = idx
+	}
+	for _, v := range s.Validators {
+		// Decoding leaves nested documents as bson.D; normalize like InspectLive
+		// so validator signatures match.
+		if m, ok := toBsonM(v.Schema); ok {
+			v.Schema = m
+		}
+		spec.Validators[v.Collection] = v
+	}
+	for _, v := range s.Views {
+		if m, ok := toBsonM(v.Collation); ok {
+			v.Collation = m
+		}
+		spec.Views[v.Name] = v
+	}
+	for _, mv := range s.MaterializedViews {
+		spec.MaterializedViews[mv.Name] = mv
+	}
+	for _, sk := range s.ShardKeys {
+		spec.ShardKeys[sk.Collection] = sk
+	}
+	for _, si := range s.SearchIndexes {
+		if m, ok := toBsonM(si.Definition); ok {
+			si.Definition = m
+		}
+		if spec.SearchIndexes[si.Collection] == nil {
+			spec.SearchIndexes[si.Collection] = make(map[string]SearchIndexSpec)
+		}
+		spec.SearchIndexes[si.Collection][si.Name] = si
+	}
+	return spec
+}
+
+// MarshalSnapshot encodes a snapshot as indented relaxed Extended JSON, which
+// keeps BSON types such as int32 keys and dates intact across a round trip.
+func MarshalSnapshot(s Snapshot) ([]byte, error) {
+	return bson.MarshalExtJSONIndent(s, false, false, "", "  ")
+}
+
+func UnmarshalSnapshot(data []byte) (Snapshot, error) {
+	var s Snapshot
+	if err := bson.UnmarshalExtJSON(data, false, &s); err != nil {
+		return Snapshot{}, err
+	}
+	if s.Format != SnapshotFormat {
+		return Snapshot{}, fmt.Errorf("%w: %d", ErrSnapshotFormat, s.Format)
+	}
+	return s, nil
+}
+
+// ReadSnapshotFile loads a snapshot written by FileSnapshotStore or MarshalSnapshot.
+func ReadSnapshotFile(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Snapshot{}, fmt.Errorf("%w: %s", ErrSnapshotNotFound, path)
+		}
+		return Snapshot{}, err
+	}
+	s, err := UnmarshalSnapshot(data)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// SnapshotStore persists snapshots by name. Names sort chronologically.
+type SnapshotStore interface {
+	Save(ctx context.Context, s Snapshot) (string, error)
+	Load(ctx context.Context, name string) (Snapshot, error)
+	List(ctx context.Context) ([]string, error)
+}
+
+// LatestSnapshot loads the most recent snapshot in store.
+func LatestSnapshot(ctx context.Context, store SnapshotStore) (Snapshot, error) {
+	names, err := store.List(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(names) == 0 {
+		return Snapshot{}, fmt.Errorf("%w: store is empty", ErrSnapshotNotFound)
+	}
+	return store.Load(ctx, names[len(names)-1])
+}
+
+// FileSnapshotStore keeps one <name>.json file per snapshot in Dir.
+type FileSnapshotStore struct {
+	Dir string
+}
+
+func (f FileSnapshotStore) Save(_ context.Context, s Snapshot) (string, error) {
+	data, err := MarshalSnapshot(s)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(f.Dir, s.Name+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (f FileSnapshotStore) Load(_ context.Context, name string) (Snapshot, error) {
+	return ReadSnapshotFile(filepath.Join(f.Dir, strings.TrimSuffix(name, ".json")+".json"))
+}
+
+func (f FileSnapshotStore) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(f.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".json" {
+			names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// MongoSnapshotStore keeps snapshots as documents keyed by name.
+type MongoSnapshotStore struct {
+	Collection *mongo.Collection
+}
+
+func (m MongoSnapshotStore) Save(ctx context.Context, s Snapshot) (string, error) {
+	_, err := m.Collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: s.Name}}, s, options.Replace().SetUpsert(true))
+	if err != nil {
+		return "", err
+	}
+	return m.Collection.Name() + "/" + s.Name, nil
+}
+
+func (m MongoSnapshotStore) Load(ctx context.Context, name string) (Snapshot, error) {
+	var s Snapshot
+	err := m.Collection.FindOne(ctx, bson.D{{Key: "_id", Value: name}}).Decode(&s)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Snapshot{}, fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if s.Format != SnapshotFormat {
+		return Snapshot{}, fmt.Errorf("%w: %d", ErrSnapshotFormat, s.Format)
+	}
+	return s, nil
+}
+
+func (m MongoSnapshotStore) List(ctx context.Context) ([]string, error) {
+	cur, err := m.Collection.Find(ctx, bson.D{},
+		options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}).SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Name string `bson:"_id"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(rows))
+	for _, r := range rows {
+		names = append(names, r.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,54 @@
+package diff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSnapshotRoundTripHasNoDiff(t *testing.T) {
+	ttl := int32(3600)
+	spec := NewSchemaSpec()
+	spec.Collections["users"] = struct{}{}
+	spec.Indexes["users"] = map[string]IndexSpec{
+		"email_1": {
+			Collection: "users",
+			Name:       "email_1",
+			Keys:       bson.D{{Key: "email", Value: int32(1)}},
+			Unique:     true,
+		},
+		"seen_1": {
+			Collection:         "users",
+			Name:               "seen_1",
+			Keys:               bson.D{{Key: "seen", Value: int32(1)}},
+			ExpireAfterSeconds: &ttl,
+		},
+	}
+	spec.Validators["users"] = ValidatorSpec{
+		Collection: "users",
+		Level:      "strict",
+		Schema: bson.M{"$jsonSchema": bson.M{
+			"bsonType": "object",
+			"required": bson.A{"email"},
+		}},
+	}
+
+	store := FileSnapshotStore{Dir: t.TempDir()}
+	snap := NewSnapshot(spec, "app", "20240101_000000", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if _, err := store.Save(context.Background(), snap); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	loaded, err := LatestSnapshot(context.Background(), store)
+	if err != nil {
+		t.Fatalf("LatestSnapshot returned error: %v", err)
+	}
+	if loaded.Name != "20240102_030405_20240101_000000" || loaded.Database != "app" {
+		t.Fatalf("unexpected snapshot metadata %+v", loaded)
+	}
+	if diffs := Compare(spec, loaded.Spec()); len(diffs) != 0 {
+		t.Fatalf("expected no diffs after round trip, got %+v", diffs)
+	}
+}
@@ -5,25 +5,35 @@ import (
 )
 
 type IndexSpec struct {
-	Collection         string
-	Name               string
-	Keys               bson.D
-	Unique             bool
-	Sparse             bool
-	PartialFilter      bson.D
-	ExpireAfterSeconds *int32
+	Collection         string `bson:"collection"`
+	Name               string `bson:"name"`
+	Keys               bson.D `bson:"keys"`
+	Unique             bool   `bson:"unique,omitempty"`
+	Sparse             bool   `bson:"sparse,omitempty"`
+	PartialFilter      bson.D `bson:"partialFilter,omitempty"`
+	ExpireAfterSeconds *int32 `bson:"expireAfterSeconds,omitempty"`
 }
 
 type ValidatorSpec struct {
-	Collection string
-	Schema     bson.M
-	Level      string
+	Collection string `bson:"collection"`
+	Schema     bson.M `bson:"schema"`
+	Level      string `bson:"level,omitempty"`
 }
 
 type SchemaSpec struct {
-	Collections map[string]struct{}
-	Indexes     map[string]map[string]IndexSpec
-	Validators  map[string]ValidatorSpec
+	Collections       map[string]struct{}
+	Options           map[string]CollectionOptions
+	Indexes           map[string]map[string]IndexSpec
+	Validators        map[string]ValidatorSpec
+	ValidatorImpact   map[string]ValidatorImpact
+	Views             map[string]ViewSpec
+	MaterializedViews map[string]MaterializedViewSpec
+	ShardKeys         map[string]ShardKeySpec
+	SearchIndexes     map[string]map[string]SearchIndexSpec
+
+	// ShardKeysUnknown is set when the sharding metadata could not be read,
+	// so shard keys are left out of the comparison.
+	ShardKeysUnknown bool
 }
 
 type Diff struct {
@@ -37,8 +47,21 @@ type Diff struct {
 
 func NewSchemaSpec() SchemaSpec {
 	return SchemaSpec{
-		Collections: make(map[string]struct{}),
-		Indexes:     make(map[string]map[string]IndexSpec),
-		Validators:  make(map[string]ValidatorSpec),
+		Collections:       make(map[string]struct{}),
+		Options:           make(map[string]CollectionOptions),
+		Indexes:           make(map[string]map[string]IndexSpec),
+		Validators:        make(map[string]ValidatorSpec),
+		ValidatorImpact:   make(map[string]ValidatorImpact),
+		Views:             make(map[string]ViewSpec),
+		MaterializedViews: make(map[string]MaterializedViewSpec),
+		ShardKeys:         make(map[string]ShardKeySpec),
+		SearchIndexes:     make(map[string]map[string]SearchIndexSpec),
 	}
 }
+
+// Empty reports whether the spec describes nothing at all.
+func (s SchemaSpec) Empty() bool {
+	return len(s.Collections) == 0 && len(s.Options) == 0 && len(s.Indexes) == 0 &&
+		len(s.Validators) == 0 && len(s.Views) == 0 && len(s.MaterializedViews) == 0 &&
+		len(s.ShardKeys) == 0 && len(s.SearchIndexes) == 0
+}
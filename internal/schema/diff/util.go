@@ -48,12 +48,32 @@ func canonicalJSON(doc bson.M) string {
 		if i > 0 {
 			buf.WriteString(",")
 		}
-		buf.WriteString(fmt.Sprintf("%s:%v", key, doc[key]))
+		buf.WriteString(fmt.Sprintf("%s:%s", key, canonicalValue(doc[key])))
 	}
 	buf.WriteString("}")
 	return buf.String()
 }
 
+// canonicalValue formats nested documents with sorted keys; bson.M's own
+// String method follows map iteration order and is not stable.
+func canonicalValue(v any) string {
+	switch t := v.(type) {
+	case bson.M:
+		return canonicalJSON(t)
+	case bson.D:
+		m, _ := toBsonM(t)
+		return canonicalJSON(m)
+	case bson.A:
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			parts = append(parts, canonicalValue(item))
+		}
+		return "[" + join(parts, ",") + "]"
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
 func join(parts []string, sep string) string {
 	if len(parts) == 0 {
 		return ""
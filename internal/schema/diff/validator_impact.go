@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const defaultViolationSample = 10
+
+var ErrValidatorSchemaRequired = errors.New("validator schema is required")
+
+// ValidatorImpact reports how many existing documents would fail a validator.
+type ValidatorImpact struct {
+	Collection string   `json:"collection"`
+	Scanned    int64    `json:"scanned"`
+	Violations int64    `json:"violations"`
+	SampleIDs  []string `json:"sample_ids,omitempty"`
+}
+
+// CheckValidator runs the validator as a $nor query against live documents and
+// counts the documents that would be rejected once the validator is enforced.
+func CheckValidator(ctx context.Context, db *mongo.Database, spec ValidatorSpec, sample int) (ValidatorImpact, error) {
+	impact := ValidatorImpact{Collection: spec.Collection}
+	if len(spec.Schema) == 0 {
+		return impact, fmt.Errorf("%w: %s", ErrValidatorSchemaRequired, spec.Collection)
+	}
+	if sample <= 0 {
+		sample = defaultViolationSample
+	}
+
+	coll := db.Collection(spec.Collection)
+	total, err := coll.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return impact, err
+	}
+	impact.Scanned = total
+
+	filter := violationFilter(spec.Schema)
+	violations, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return impact, err
+	}
+	impact.Violations = violations
+	if violations == 0 {
+		return impact, nil
+	}
+
+	findOpts := options.Find().
+		SetProjection(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(sample))
+	cur, err := coll.Find(ctx, filter, findOpts)
+	if err != nil {
+		return impact, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var doc bson.M
+		if err := cur.Decode(&doc); err != nil {
+			return impact, err
+		}
+		impact.SampleIDs = append(impact.SampleIDs, formatID(doc["_id"]))
+	}
+	return impact, cur.Err()
+}
+
+// CheckValidators fills target.ValidatorImpact for every target validator
+// whose collection already exists in the live database.
+func CheckValidators(ctx context.Context, db *mongo.Database, live, target SchemaSpec, sample int) error {
+	for _, coll := range sortedKeys(target.Validators) {
+		if _, exists := live.Collections[coll]; !exists {
+			continue
+		}
+		impact, err := CheckValidator(ctx, db, target.Validators[coll], sample)
+		if err != nil {
+			return fmt.Errorf("check validator %s: %w", coll, err)
+		}
+		target.ValidatorImpact[coll] = impact
+	}
+	return nil
+}
+
+func violationFilter(validator bson.M) bson.M {
+	return bson.M{"$nor": bson.A{validator}}
+}
+
+func formatID(id any) string {
+	if oid, ok := id.(bson.ObjectID); ok {
+		return oid.Hex()
+	}
+	return fmt.Sprintf("%v", id)
+}
+
+func validatorChangeRisk(impact ValidatorImpact, known bool) string {
+	switch {
+	case !known:
+		return RiskMedium
+	case impact.Violations == 0:
+		return RiskLow
+	case impact.Scanned > 0 && impact.Violations*10 >= impact.Scanned:
+		return RiskCritical
+	default:
+		return RiskHigh
+	}
+}
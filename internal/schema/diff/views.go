@@ -0,0 +1,151 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/drewjocham/mongork/internal/schema"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type (
+	ViewSpec             = schema.ViewSpec
+	MaterializedViewSpec = schema.MaterializedViewSpec
+)
+
+func parseView(name string, collDoc bson.M) ViewSpec {
+	view := ViewSpec{Name: name}
+	opts, _ := toBsonM(collDoc["options"])
+	if opts == nil {
+		return view
+	}
+	view.Source, _ = opts["viewOn"].(string)
+	if stages, ok := opts["pipeline"].(bson.A); ok {
+		view.Pipeline = make(mongo.Pipeline, 0, len(stages))
+		for _, stage := range stages {
+			view.Pipeline = append(view.Pipeline, toBsonD(stage))
+		}
+	}
+	if collation, ok := toBsonM(opts["collation"]); ok {
+		view.Collation = collation
+	}
+	return view
+}
+
+func compareViews(live, target SchemaSpec) []Diff {
+	var diffs []Diff
+	for _, name := range unionKeys(live.Views, target.Views) {
+		liveView, liveOK := live.Views[name]
+		targetView, targetOK := target.Views[name]
+		switch {
+		case !liveOK && targetOK:
+			diffs = append(diffs, Diff{
+				Component: "view",
+				Action:    "AddView",
+				Target:    name,
+				Current:   "missing",
+				Proposed:  viewSummary(targetView),
+				Risk:      RiskLow,
+			})
+		case liveOK && !targetOK:
+			diffs = append(diffs, Diff{
+				Component: "view",
+				Action:    "TrackView",
+				Target:    name,
+				Current:   viewSummary(liveView),
+				Proposed:  "missing from registry",
+				Risk:      RiskLow,
+			})
+		case liveOK && targetOK:
+			if viewSignature(liveView) != viewSignature(targetView) {
+				diffs = append(diffs, Diff{
+					Component: "view",
+					Action:    "UpdateView",
+					Target:    name,
+					Current:   viewSummary(liveView),
+					Proposed:  viewSummary(targetView),
+					Risk:      RiskMedium,
+				})
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(target.MaterializedViews) {
+		if _, exists := live.Collections[name]; exists {
+			continue
+		}
+		mv := target.MaterializedViews[name]
+		diffs = append(diffs, Diff{
+			Component: "view",
+			Action:    "AddMaterializedView",
+			Target:    name,
+			Current:   "missing",
+			Proposed:  fmt.Sprintf("materialized from %s (%d stages)", mv.Source, len(mv.Pipeline)),
+			Risk:      RiskLow,
+		})
+	}
+	return diffs
+}
+
+func viewSignature(v ViewSpec) string {
+	return fmt.Sprintf("%s|%s|%s", v.Source, pipelineString(v.Pipeline), canonicalJSON(v.Collation))
+}
+
+func viewSummary(v ViewSpec) string {
+	summary := fmt.Sprintf("on %s pipeline=%s", v.Source, pipelineString(v.Pipeline))
+	if len(v.Collation) > 0 {
+		summary += " collation=" + canonicalJSON(v.Collation)
+	}
+	return summary
+}
+
+// pipelineString renders stages stably: bson.D keeps its order, since key
+// order is meaningful in stages like $sort, and bson.M is sorted by key.
+func pipelineString(pipeline mongo.Pipeline) string {
+	stages := make([]string, 0, len(pipeline))
+	for _, stage := range pipeline {
+		stages = append(stages, orderedValue(stage))
+	}
+	return "[" + join(stages, ",") + "]"
+}
+
+func orderedValue(v any) string {
+	switch t := v.(type) {
+	case bson.D:
+		parts := make([]string, 0, len(t))
+		for _, e := range t {
+			parts = append(parts, e.Key+":"+orderedValue(e.Value))
+		}
+		return "{" + join(parts, ",") + "}"
+	case bson.M:
+		parts := make([]string, 0, len(t))
+		for _, k := range sortedKeys(t) {
+			parts = append(parts, k+":"+orderedValue(t[k]))
+		}
+		return "{" + join(parts, ",") + "}"
+	case bson.A:
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			parts = append(parts, orderedValue(item))
+		}
+		return "[" + join(parts, ",") + "]"
+	default:
+		return canonicalValue(t)
+	}
+}
+
+func createViewCommand(v ViewSpec) bson.D {
+	pipeline := v.Pipeline
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	cmd := bson.D{
+		{Key: "create", Value: v.Name},
+		{Key: "viewOn", Value: v.Source},
+		{Key: "pipeline", Value: pipeline},
+	}
+	if len(v.Collation) > 0 {
+		cmd = append(cmd, bson.E{Key: "collation", Value: v.Collation})
+	}
+	return cmd
+}
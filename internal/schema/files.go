@@ -0,0 +1,488 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrSchemaFileInvalid = errors.New("invalid schema file")
+	ErrPipelineStage     = errors.New("invalid pipeline stage")
+)
+
+// Definitions is the registry content described by one or more schema files.
+type Definitions struct {
+	Collections       []string
+	CollectionOptions map[string]CollectionOptions
+	Indexes           []IndexSpec
+	Validators        []ValidatorSpec
+	Views             []ViewSpec
+	MaterializedViews []MaterializedViewSpec
+	ShardKeys         []ShardKeySpec
+	SearchIndexes     []SearchIndexSpec
+	Ignore            []IgnoreRule
+}
+
+type schemaFile struct {
+	Collections       []collectionFile `yaml:"collections"`
+	Views             []viewFile       `yaml:"views"`
+	MaterializedViews []viewFile       `yaml:"materializedViews"`
+	Ignore            []ignoreFile     `yaml:"ignore"`
+}
+
+type ignoreFile struct {
+	Pattern   string `yaml:"pattern"`
+	Component string `yaml:"component"`
+	Owner     string `yaml:"owner"`
+	Reason    string `yaml:"reason"`
+}
+
+type viewFile struct {
+	Name           string         `yaml:"name"`
+	Source         string         `yaml:"viewOn"`
+	Pipeline       []yaml.Node    `yaml:"pipeline"`
+	Collation      map[string]any `yaml:"collation"`
+	MergeOn        []string       `yaml:"mergeOn"`
+	WhenMatched    string         `yaml:"whenMatched"`
+	WhenNotMatched string         `yaml:"whenNotMatched"`
+}
+
+type collectionFile struct {
+	Name      string             `yaml:"name"`
+	Options   *CollectionOptions `yaml:"options"`
+	Indexes   []indexFile        `yaml:"indexes"`
+	Validator *validatorFile     `yaml:"validator"`
+	ShardKey  *shardKeyFile      `yaml:"shardKey"`
+	Search    []searchIndexFile  `yaml:"searchIndexes"`
+	Owner     string             `yaml:"owner"`
+}
+
+type searchIndexFile struct {
+	Name       string         `yaml:"name"`
+	Type       string         `yaml:"type"`
+	Definition map[string]any `yaml:"definition"`
+}
+
+type shardKeyFile struct {
+	Keys     []map[string]any `yaml:"keys"`
+	Unique   bool             `yaml:"unique"`
+	Zones    []zoneFile       `yaml:"zones"`
+	Presplit []map[string]any `yaml:"presplit"`
+}
+
+type zoneFile struct {
+	Name string         `yaml:"name"`
+	Min  map[string]any `yaml:"min"`
+	Max  map[string]any `yaml:"max"`
+}
+
+type indexFile struct {
+	Name               string           `yaml:"name"`
+	Keys               []map[string]any `yaml:"keys"`
+	Unique             bool             `yaml:"unique"`
+	Sparse             bool             `yaml:"sparse"`
+	PartialFilter      map[string]any   `yaml:"partialFilter"`
+	ExpireAfterSeconds *int32           `yaml:"expireAfterSeconds"`
+}
+
+type validatorFile struct {
+	Description string         `yaml:"description"`
+	Level       string         `yaml:"level"`
+	JSONSchema  map[string]any `yaml:"jsonSchema"`
+}
+
+// ParseDefinitions decodes a YAML or JSON schema document. Index keys are a
+// list of single-entry maps so their order survives decoding:
+//
+//	collections:
+//	  - name: users
+//	    options:
+//	      changeStreamPreAndPostImages: true
+//	    indexes:
+//	      - name: idx_users_email
+//	        keys: [{email: 1}]
+//	        unique: true
+//	    validator:
+//	      level: moderate
+//	      jsonSchema: {bsonType: object, required: [email]}
+//	    shardKey:
+//	      keys: [{tenant: 1}, {_id: hashed}]
+//	      zones: [{name: eu, min: {tenant: "eu"}, max: {tenant: "ev"}}]
+//	    searchIndexes:
+//	      - name: default
+//	        definition: {mappings: {dynamic: true}}
+//	  - name: invoices
+//	    owner: billing-service
+//	ignore:
+//	  - {pattern: "tmp_*", reason: scratch data}
+//	  - {component: index, pattern: "users.analytics_*", owner: analytics}
+func ParseDefinitions(data []byte) (Definitions, error) {
+	var file schemaFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Definitions{}, fmt.Errorf("%w: %w", ErrSchemaFileInvalid, err)
+	}
+
+	var defs Definitions
+	for _, coll := range file.Collections {
+		if coll.Name == "" {
+			return Definitions{}, fmt.Errorf("%w: %w", ErrSchemaFileInvalid, ErrCollectionNameRequired)
+		}
+		// A collection owned by another service is only recorded as ignored.
+		if coll.Owner != "" {
+			defs.Ignore = append(defs.Ignore, IgnoreRule{Pattern: coll.Name, Owner: coll.Owner})
+			continue
+		}
+		defs.Collections = append(defs.Collections, coll.Name)
+		if coll.Options != nil && !coll.Options.IsZero() {
+			opts := *coll.Options
+			if len(opts.Collation) > 0 {
+				opts.Collation, _ = toBsonValue(map[string]any(opts.Collation)).(bson.M)
+			}
+			if defs.CollectionOptions == nil {
+				defs.CollectionOptions = make(map[string]CollectionOptions)
+			}
+			defs.CollectionOptions[coll.Name] = opts
+		}
+
+		for _, idx := range coll.Indexes {
+			keys, err := indexKeysFromFile(idx.Keys)
+			if err != nil {
+				return Definitions{}, fmt.Errorf("%w: %s.%s: %w", ErrSchemaFileInvalid, coll.Name, idx.Name, err)
+			}
+			defs.Indexes = append(defs.Indexes, IndexSpec{
+				Collection:         coll.Name,
+				Name:               idx.Name,
+				Keys:               keys,
+				Unique:             idx.Unique,
+				Sparse:             idx.Sparse,
+				PartialFilter:      sortedBsonD(idx.PartialFilter),
+				ExpireAfterSeconds: idx.ExpireAfterSeconds,
+			})
+		}
+
+		if v := coll.Validator; v != nil {
+			defs.Validators = append(defs.Validators, ValidatorSpec{
+				Collection:  coll.Name,
+				Description: v.Description,
+				Schema:      bson.M{"$jsonSchema": toBsonValue(v.JSONSchema)},
+				Level:       v.Level,
+			})
+		}
+
+		if sk := coll.ShardKey; sk != nil {
+			spec, err := shardKeyFromFile(coll.Name, sk)
+			if err != nil {
+				return Definitions{}, fmt.Errorf("%w: %s: %w", ErrSchemaFileInvalid, coll.Name, err)
+			}
+			defs.ShardKeys = append(defs.ShardKeys, spec)
+		}
+
+		for _, si := range coll.Search {
+			definition, _ := toBsonValue(si.Definition).(bson.M)
+			defs.SearchIndexes = append(defs.SearchIndexes, SearchIndexSpec{
+				Collection: coll.Name,
+				Name:       si.Name,
+				Type:       si.Type,
+				Definition: definition,
+			})
+		}
+	}
+	for _, v := range file.Views {
+		pipeline, err := pipelineFromFile(v.Pipeline)
+		if err != nil {
+			return Definitions{}, fmt.Errorf("%w: view %s: %w", ErrSchemaFileInvalid, v.Name, err)
+		}
+		view := ViewSpec{Name: v.Name, Source: v.Source, Pipeline: pipeline}
+		if len(v.Collation) > 0 {
+			view.Collation, _ = toBsonValue(v.Collation).(bson.M)
+		}
+		defs.Views = append(defs.Views, view)
+	}
+	for _, v := range file.MaterializedViews {
+		pipeline, err := pipelineFromFile(v.Pipeline)
+		if err != nil {
+			return Definitions{}, fmt.Errorf("%w: materialized view %s: %w", ErrSchemaFileInvalid, v.Name, err)
+		}
+		defs.MaterializedViews = append(defs.MaterializedViews, MaterializedViewSpec{
+			Name:           v.Name,
+			Source:         v.Source,
+			Pipeline:       pipeline,
+			MergeOn:        v.MergeOn,
+			WhenMatched:    v.WhenMatched,
+			WhenNotMatched: v.WhenNotMatched,
+		})
+	}
+	for _, ig := range file.Ignore {
+		rule := IgnoreRule{Pattern: ig.Pattern, Component: ig.Component, Owner: ig.Owner, Reason: ig.Reason}
+		if err := rule.Validate(); err != nil {
+			return Definitions{}, fmt.Errorf("%w: %w", ErrSchemaFileInvalid, err)
+		}
+		defs.Ignore = append(defs.Ignore, rule)
+	}
+	return defs, nil
+}
+
+// Register adds every definition to the in-process registries.
+func (d Definitions) Register() error {
+	for _, name := range d.Collections {
+		if err := RegisterCollectionWithOptions(name, d.CollectionOptions[name]); err != nil {
+			return err
+		}
+	}
+	for _, idx := range d.Indexes {
+		if err := Register(idx); err != nil {
+			return err
+		}
+	}
+	for _, v := range d.Validators {
+		if err := RegisterValidator(v); err != nil {
+			return err
+		}
+	}
+	for _, v := range d.Views {
+		if err := RegisterView(v); err != nil {
+			return err
+		}
+	}
+	for _, v := range d.MaterializedViews {
+		if err := RegisterMaterializedView(v); err != nil {
+			return err
+		}
+	}
+	for _, sk := range d.ShardKeys {
+		if err := RegisterShardKey(sk); err != nil {
+			return err
+		}
+	}
+	for _, si := range d.SearchIndexes {
+		if err := RegisterSearchIndex(si); err != nil {
+			return err
+		}
+	}
+	for _, rule := range d.Ignore {
+		if err := RegisterIgnore(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile parses a single schema file and registers its contents.
+func LoadFile(path string) (Definitions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Definitions{}, err
+	}
+	defs, err := ParseDefinitions(data)
+	if err != nil {
+		return Definitions{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := defs.Register(); err != nil {
+		return Definitions{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return defs, nil
+}
+
+// LoadDir registers every *.yaml, *.yml and *.json file in dir, in name order.
+// A missing directory is not an error.
+func LoadDir(dir string) (Definitions, error) {
+	var all Definitions
+	if dir == "" {
+		return all, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return all, nil
+		}
+		return all, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !isSchemaFile(entry.Name()) {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		defs, err := LoadFile(filepath.Join(dir, name))
+		if err != nil {
+			return all, err
+		}
+		all.Collections = append(all.Collections, defs.Collections...)
+		for name, opts := range defs.CollectionOptions {
+			if all.CollectionOptions == nil {
+				all.CollectionOptions = make(map[string]CollectionOptions)
+			}
+			all.CollectionOptions[name] = opts
+		}
+		all.Indexes = append(all.Indexes, defs.Indexes...)
+		all.Validators = append(all.Validators, defs.Validators...)
+		all.Views = append(all.Views, defs.Views...)
+		all.MaterializedViews = append(all.MaterializedViews, defs.MaterializedViews...)
+		all.ShardKeys = append(all.ShardKeys, defs.ShardKeys...)
+		all.SearchIndexes = append(all.SearchIndexes, defs.SearchIndexes...)
+		all.Ignore = append(all.Ignore, defs.Ignore...)
+	}
+	return all, nil
+}
+
+func isSchemaFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func indexKeysFromFile(entries []map[string]any) (bson.D, error) {
+	keys := make(bson.D, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry) != 1 {
+			return nil, fmt.Errorf("each index key must have exactly one field, got %d", len(entry))
+		}
+		for field, order := range entry {
+			keys = append(keys, bson.E{Key: field, Value: toBsonValue(order)})
+		}
+	}
+	return keys, nil
+}
+
+// shardKeyFromFile keeps key order from the list form; zone bounds and split
+// points are ordered like the shard key since MongoDB compares them that way.
+// Fields missing from a bound default to MinKey; "$minKey" and "$maxKey" are
+// accepted as values.
+func shardKeyFromFile(collection string, f *shardKeyFile) (ShardKeySpec, error) {
+	keys, err := indexKeysFromFile(f.Keys)
+	if err != nil {
+		return ShardKeySpec{}, err
+	}
+	spec := ShardKeySpec{Collection: collection, Key: keys, Unique: f.Unique}
+	for _, z := range f.Zones {
+		spec.Zones = append(spec.Zones, ZoneSpec{
+			Name: z.Name,
+			Min:  orderLike(keys, z.Min),
+			Max:  orderLike(keys, z.Max),
+		})
+	}
+	for _, point := range f.Presplit {
+		spec.PresplitPoints = append(spec.PresplitPoints, orderLike(keys, point))
+	}
+	return spec, nil
+}
+
+func orderLike(keys bson.D, values map[string]any) bson.D {
+	out := make(bson.D, 0, len(keys))
+	for _, k := range keys {
+		var value any = bson.MinKey{}
+		switch v := values[k.Key]; v {
+		case nil, "$minKey":
+		case "$maxKey":
+			value = bson.MaxKey{}
+		default:
+			value = toBsonValue(v)
+		}
+		out = append(out, bson.E{Key: k.Key, Value: value})
+	}
+	return out
+}
+
+// pipelineFromFile decodes stages from their YAML nodes so every document,
+// such as a $sort or a compound $group _id, keeps the key order it was
+// written in.
+func pipelineFromFile(stages []yaml.Node) (mongo.Pipeline, error) {
+	pipeline := make(mongo.Pipeline, 0, len(stages))
+	for i := range stages {
+		v, err := orderedBsonValue(&stages[i])
+		if err != nil {
+			return nil, err
+		}
+		stage, ok := v.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("%w: stage %d is not a document", ErrPipelineStage, i)
+		}
+		pipeline = append(pipeline, stage)
+	}
+	return pipeline, nil
+}
+
+// orderedBsonValue is toBsonValue for a YAML node, with mappings as bson.D.
+func orderedBsonValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return orderedBsonValue(node.Alias)
+	case yaml.MappingNode:
+		out := make(bson.D, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v, err := orderedBsonValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, bson.E{Key: node.Content[i].Value, Value: v})
+		}
+		return out, nil
+	case yaml.SequenceNode:
+		out := make(bson.A, 0, len(node.Content))
+		for _, item := range node.Content {
+			v, err := orderedBsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	default:
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return nil, err
+		}
+		return toBsonValue(v), nil
+	}
+}
+
+func sortedBsonD(m map[string]any) bson.D {
+	if len(m) == 0 {
+		return nil
+	}
+	converted, _ := toBsonValue(m).(bson.M)
+	return toBsonD(converted)
+}
+
+// toBsonValue converts decoded YAML/JSON values into their BSON equivalents.
+// Integers become int32 when they fit so specs compare equal to live metadata.
+func toBsonValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(bson.M, len(v))
+		for k, item := range v {
+			out[k] = toBsonValue(item)
+		}
+		return out
+	case []any:
+		out := make(bson.A, len(v))
+		for i, item := range v {
+			out[i] = toBsonValue(item)
+		}
+		return out
+	case int:
+		if v >= -1<<31 && v <= 1<<31-1 {
+			return int32(v)
+		}
+		return int64(v)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestParseDefinitionsYAML(t *testing.T) {
+	data := []byte(`
+collections:
+  - name: users
+    indexes:
+      - name: idx_users_email_created
+        keys: [{email: 1}, {created_at: -1}]
+        unique: true
+        partialFilter: {deleted: false}
+      - name: idx_users_session_ttl
+        keys: [{expires_at: 1}]
+        expireAfterSeconds: 3600
+    validator:
+      level: strict
+      jsonSchema:
+        bsonType: object
+        required: [email]
+  - name: audit_log
+`)
+
+	defs, err := ParseDefinitions(data)
+	if err != nil {
+		t.Fatalf("ParseDefinitions returned error: %v", err)
+	}
+	if len(defs.Collections) != 2 || len(defs.Indexes) != 2 || len(defs.Validators) != 1 {
+		t.Fatalf("unexpected definitions: %+v", defs)
+	}
+
+	idx := defs.Indexes[0]
+	wantKeys := bson.D{{Key: "email", Value: int32(1)}, {Key: "created_at", Value: int32(-1)}}
+	if idx.KeyString() != formatBsonD(wantKeys) || !idx.Unique {
+		t.Fatalf("unexpected index: %+v", idx)
+	}
+	if idx.PartialFilterString() != "deleted:false" {
+		t.Fatalf("unexpected partial filter: %s", idx.PartialFilterString())
+	}
+	if ttl := defs.Indexes[1].ExpireAfterSeconds; ttl == nil || *ttl != 3600 {
+		t.Fatalf("unexpected ttl: %v", ttl)
+	}
+
+	jsonSchema, ok := defs.Validators[0].Schema["$jsonSchema"].(bson.M)
+	if !ok || jsonSchema["bsonType"] != "object" {
+		t.Fatalf("unexpected validator schema: %+v", defs.Validators[0].Schema)
+	}
+}
+
+func TestParseDefinitionsJSON(t *testing.T) {
+	data := []byte(`{"collections":[{"name":"orders","indexes":[{"name":"idx_orders_customer","keys":[{"customer_id":1}]}]}]}`)
+
+	defs, err := ParseDefinitions(data)
+	if err != nil {
+		t.Fatalf("ParseDefinitions returned error: %v", err)
+	}
+	if len(defs.Indexes) != 1 || defs.Indexes[0].Collection != "orders" {
+		t.Fatalf("unexpected definitions: %+v", defs)
+	}
+}
+
+func TestParseDefinitionsRejectsMultiFieldKey(t *testing.T) {
+	data := []byte(`collections: [{name: users, indexes: [{name: bad, keys: [{a: 1, b: 1}]}]}]`)
+	if _, err := ParseDefinitions(data); err == nil {
+		t.Fatal("expected error for multi-field key entry")
+	}
+}
+
+func TestParseDefinitionsShardKey(t *testing.T) {
+	data := []byte(`
+collections:
+  - name: events
+    shardKey:
+      keys: [{tenant: 1}, {created: 1}]
+      zones:
+        - {name: eu, min: {tenant: "eu"}, max: {tenant: "eu", created: $maxKey}}
+      presplit: [{tenant: "m"}]
+`)
+
+	defs, err := ParseDefinitions(data)
+	if err != nil {
+		t.Fatalf("ParseDefinitions returned error: %v", err)
+	}
+	if len(defs.ShardKeys) != 1 {
+		t.Fatalf("expected one shard key, got %+v", defs.ShardKeys)
+	}
+	sk := defs.ShardKeys[0]
+	if formatBsonD(sk.Key) != "tenant:1, created:1" {
+		t.Fatalf("unexpected shard key %v", sk.Key)
+	}
+	zone := sk.Zones[0]
+	if zone.Name != "eu" || zone.Min[1].Value != (bson.MinKey{}) || zone.Max[1].Value != (bson.MaxKey{}) {
+		t.Fatalf("unexpected zone %+v", zone)
+	}
+	if len(sk.PresplitPoints) != 1 || sk.PresplitPoints[0][0].Value != "m" {
+		t.Fatalf("unexpected presplit points %+v", sk.PresplitPoints)
+	}
+}
+
+func TestParseDefinitionsSearchIndexes(t *testing.T) {
+	data := []byte(`
+collections:
+  - name: products
+    searchIndexes:
+      - name: default
+        definition: {mappings: {dynamic: true}}
+      - name: embedding_idx
+        type: vectorSearch
+        definition:
+          fields: [{type: vector, path: embedding, numDimensions: 1536, similarity: cosine}]
+`)
+
+	defs, err := ParseDefinitions(data)
+	if err != nil {
+		t.Fatalf("ParseDefinitions returned error: %v", err)
+	}
+	if len(defs.SearchIndexes) != 2 {
+		t.Fatalf("expected two search indexes, got %+v", defs.SearchIndexes)
+	}
+	if defs.SearchIndexes[0].IndexType() != SearchIndexTypeSearch {
+		t.Fatalf("expected default type search, got %q", defs.SearchIndexes[0].IndexType())
+	}
+	vector := defs.SearchIndexes[1]
+	fields, _ := vector.Definition["fields"].(bson.A)
+	field, _ := fields[0].(bson.M)
+	if vector.Type != SearchIndexTypeVectorSearch || field["numDimensions"] != int32(1536) {
+		t.Fatalf("unexpected vector index %+v", vector)
+	}
+}
+
+func TestParseDefinitionsIgnoreAndOwner(t *testing.T) {
+	data := []byte(`
+collections:
+  - name: invoices
+    owner: billing-service
+    indexes:
+      - name: idx_due
+        keys: [{due: 1}]
+ignore:
+  - {component: index, pattern: "users.tmp_*", reason: scratch}
+`)
+
+	defs, err := ParseDefinitions(data)
+	if err != nil {
+		t.Fatalf("ParseDefinitions returned error: %v", err)
+	}
+	if len(defs.Collections) != 0 || len(defs.Indexes) != 0 {
+		t.Fatalf("owned collection should not be registered, got %+v", defs)
+	}
+	if len(defs.Ignore) != 2 || defs.Ignore[0].Owner != "billing-service" {
+		t.Fatalf("unexpected ignore rules %+v", defs.Ignore)
+	}
+	rule := defs.Ignore[1]
+	if !rule.Matches("index", "users.tmp_backfill") || rule.Matches("validator", "users.tmp_backfill") {
+		t.Fatalf("component-scoped rule matched unexpectedly: %+v", rule)
+	}
+	if !defs.Ignore[0].Matches("index", "invoices.idx_due") {
+		t.Fatal("collection rule should cover the collection's indexes")
+	}
+
+	if _, err := ParseDefinitions([]byte("ignore:\n  - {pattern: \"[\"}\n")); !errors.Is(err, ErrIgnorePatternInvalid) {
+		t.Fatalf("expected ErrIgnorePatternInvalid, got %v", err)
+	}
+}
+
+func TestParseDefinitionsKeepsPipelineKeyOrder(t *testing.T) {
+	data := []byte(`
+views:
+  - name: recent_orders
+    viewOn: orders
+    pipeline:
+      - {$sort: {created_at: -1, _id: 1}}
+      - {$group: {_id: {tenant: "$tenant", day: "$day"}, n: {$sum: 1}}}
+`)
+	defs, err := ParseDefinitions(data)
+	if err != nil {
+		t.Fatalf("ParseDefinitions returned error: %v", err)
+	}
+	pipeline := defs.Views[0].Pipeline
+	sort := pipeline[0][0].Value.(bson.D)
+	if sort[0].Key != "created_at" || sort[1].Key != "_id" || sort[0].Value != int32(-1) {
+		t.Fatalf("expected $sort in file order, got %v", sort)
+	}
+	id := pipeline[1][0].Value.(bson.D)[0].Value.(bson.D)
+	if id[0].Key != "tenant" || id[1].Key != "day" {
+		t.Fatalf("expected the compound _id in file order, got %v", id)
+	}
+
+	_, err = ParseDefinitions([]byte("views: [{name: v, viewOn: o, pipeline: [1]}]"))
+	if !errors.Is(err, ErrPipelineStage) {
+		t.Fatalf("expected ErrPipelineStage, got %v", err)
+	}
+}
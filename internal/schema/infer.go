@@ -0,0 +1,313 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	defaultInferSample = 500
+	arrayItemsSegment  = "[]"
+
+	// unknownBSONType is counted in FieldStats but never written to a
+	// validator, since $jsonSchema rejects type names it does not know.
+	unknownBSONType = "unknown"
+)
+
+var ErrInferCollectionRequired = errors.New("collection is required for schema inference")
+
+// FieldStats summarises one field path observed across sampled documents.
+// Array elements are reported under "<path>.[]".
+type FieldStats struct {
+	Path     string           `json:"path"`
+	Present  int64            `json:"present"`
+	Nulls    int64            `json:"nulls"`
+	Types    map[string]int64 `json:"types"`
+	MinItems *int             `json:"min_items,omitempty"`
+	MaxItems *int             `json:"max_items,omitempty"`
+}
+
+// InferredSchema is the document shape derived from a sample of a collection.
+type InferredSchema struct {
+	Collection string       `json:"collection"`
+	Sampled    int64        `json:"sampled"`
+	Fields     []FieldStats `json:"fields"`
+}
+
+// InferSchema samples documents with $sample and infers their shape.
+func InferSchema(ctx context.Context, db *mongo.Database, collection string, sample int) (InferredSchema, error) {
+	if collection == "" {
+		return InferredSchema{}, ErrInferCollectionRequired
+	}
+	if sample <= 0 {
+		sample = defaultInferSample
+	}
+
+	pipeline := mongo.Pipeline{bson.D{{Key: "$sample", Value: bson.M{"size": sample}}}}
+	cur, err := db.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return InferredSchema{}, err
+	}
+	defer cur.Close(ctx)
+
+	var docs []bson.D
+	if err := cur.All(ctx, &docs); err != nil {
+		return InferredSchema{}, err
+	}
+	return InferFromDocuments(collection, docs), nil
+}
+
+// InferFromDocuments computes field statistics for already loaded documents.
+func InferFromDocuments(collection string, docs []bson.D) InferredSchema {
+	fields := make(map[string]*FieldStats)
+	for _, doc := range docs {
+		walkDocument(fields, "", doc)
+	}
+
+	out := InferredSchema{Collection: collection, Sampled: int64(len(docs))}
+	for _, stats := range fields {
+		out.Fields = append(out.Fields, *stats)
+	}
+	sort.Slice(out.Fields, func(i, j int) bool { return out.Fields[i].Path < out.Fields[j].Path })
+	return out
+}
+
+// Field returns the statistics for path, if it was observed.
+func (s InferredSchema) Field(path string) (FieldStats, bool) {
+	for _, f := range s.Fields {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return FieldStats{}, false
+}
+
+// JSONSchema renders the inferred shape as a $jsonSchema validator document.
+// Fields present and non-null in every sampled document are marked required.
+func (s InferredSchema) JSONSchema() bson.M {
+	return bson.M{"$jsonSchema": s.objectSchema("", s.Sampled)}
+}
+
+// ValidatorSpec wraps JSONSchema in a spec ready for RegisterValidator.
+func (s InferredSchema) ValidatorSpec(level string) ValidatorSpec {
+	if level == "" {
+		level = "moderate"
+	}
+	return ValidatorSpec{
+		Collection:  s.Collection,
+		Description: "inferred from sampled documents",
+		Schema:      s.JSONSchema(),
+		Level:       level,
+	}
+}
+
+// TopLevel returns the direct children of the document root.
+func (s InferredSchema) TopLevel() []FieldStats {
+	return s.children("")
+}
+
+// Required lists the top-level fields that were always present and non-null.
+func (s InferredSchema) Required() []string {
+	var out []string
+	for _, f := range s.TopLevel() {
+		if f.Present == s.Sampled && f.Nulls == 0 {
+			out = append(out, leafName(f.Path))
+		}
+	}
+	return out
+}
+
+// PropertySchema builds the $jsonSchema fragment for a single field path.
+func (s InferredSchema) PropertySchema(f FieldStats) bson.M {
+	prop := bson.M{}
+	types := sortedTypes(f.Types)
+	types = slices.DeleteFunc(types, func(t string) bool { return t == unknownBSONType })
+	switch len(types) {
+	case 0:
+	case 1:
+		prop["bsonType"] = types[0]
+	default:
+		anyTypes := make([]any, len(types))
+		for i, t := range types {
+			anyTypes[i] = t
+		}
+		prop["bsonType"] = anyTypes
+	}
+
+	if f.Types["object"] > 0 {
+		if nested := s.objectSchema(f.Path, f.Types["object"]); len(nested) > 1 {
+			for k, v := range nested {
+				if k != "bsonType" {
+					prop[k] = v
+				}
+			}
+		}
+	}
+	if f.Types["array"] > 0 {
+		if items, ok := s.Field(f.Path + "." + arrayItemsSegment); ok {
+			prop["items"] = s.PropertySchema(items)
+		}
+	}
+	return prop
+}
+
+func (s InferredSchema) objectSchema(prefix string, parentCount int64) bson.M {
+	node := bson.M{"bsonType": "object"}
+	props := bson.M{}
+	var required []string
+	for _, f := range s.children(prefix) {
+		props[leafName(f.Path)] = s.PropertySchema(f)
+		if f.Present == parentCount && f.Nulls == 0 {
+			required = append(required, leafName(f.Path))
+		}
+	}
+	if len(props) > 0 {
+		node["properties"] = props
+	}
+	if len(required) > 0 {
+		node["required"] = required
+	}
+	return node
+}
+
+func (s InferredSchema) children(prefix string) []FieldStats {
+	var out []FieldStats
+	for _, f := range s.Fields {
+		rest := f.Path
+		if prefix != "" {
+			if !strings.HasPrefix(f.Path, prefix+".") {
+				continue
+			}
+			rest = strings.TrimPrefix(f.Path, prefix+".")
+		}
+		if rest == arrayItemsSegment || strings.Contains(rest, ".") {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func walkDocument(fields map[string]*FieldStats, prefix string, doc bson.D) {
+	for _, elem := range doc {
+		walkValue(fields, joinPath(prefix, elem.Key), elem.Value)
+	}
+}
+
+func walkValue(fields map[string]*FieldStats, path string, value any) {
+	stats, ok := fields[path]
+	if !ok {
+		stats = &FieldStats{Path: path, Types: make(map[string]int64)}
+		fields[path] = stats
+	}
+	stats.Present++
+
+	typ := bsonTypeName(value)
+	stats.Types[typ]++
+	switch v := value.(type) {
+	case nil:
+		stats.Nulls++
+	case bson.D:
+		walkDocument(fields, path, v)
+	case bson.M:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		doc := make(bson.D, 0, len(v))
+		for _, k := range keys {
+			doc = append(doc, bson.E{Key: k, Value: v[k]})
+		}
+		walkDocument(fields, path, doc)
+	case bson.A:
+		n := len(v)
+		if stats.MinItems == nil || n < *stats.MinItems {
+			stats.MinItems = &n
+		}
+		if stats.MaxItems == nil || n > *stats.MaxItems {
+			stats.MaxItems = &n
+		}
+		for _, item := range v {
+			walkValue(fields, joinPath(path, arrayItemsSegment), item)
+		}
+	}
+}
+
+func bsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case int32:
+		return "int"
+	case int64:
+		return "long"
+	case float64:
+		return "double"
+	case bool:
+		return "bool"
+	case bson.DateTime:
+		return "date"
+	case bson.ObjectID:
+		return "objectId"
+	case bson.D, bson.M:
+		return "object"
+	case bson.A:
+		return "array"
+	case bson.Decimal128:
+		return "decimal"
+	case bson.Binary:
+		return "binData"
+	case bson.Timestamp:
+		return "timestamp"
+	case bson.Regex:
+		return "regex"
+	case bson.JavaScript:
+		return "javascript"
+	case bson.CodeWithScope:
+		return "javascriptWithScope"
+	case bson.Symbol:
+		return "symbol"
+	case bson.DBPointer:
+		return "dbPointer"
+	case bson.MinKey:
+		return "minKey"
+	case bson.MaxKey:
+		return "maxKey"
+	case bson.Undefined:
+		return "undefined"
+	default:
+		return unknownBSONType
+	}
+}
+
+func sortedTypes(types map[string]int64) []string {
+	out := make([]string, 0, len(types))
+	for t := range types {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func leafName(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
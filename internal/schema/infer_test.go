@@ -0,0 +1,83 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestInferFromDocuments(t *testing.T) {
+	docs := []bson.D{
+		{
+			{Key: "name", Value: "a"},
+			{Key: "age", Value: int32(3)},
+			{Key: "tags", Value: bson.A{"x", "y"}},
+			{Key: "address", Value: bson.D{{Key: "city", Value: "Berlin"}}},
+		},
+		{
+			{Key: "name", Value: "b"},
+			{Key: "age", Value: nil},
+			{Key: "tags", Value: bson.A{}},
+		},
+	}
+
+	inferred := InferFromDocuments("users", docs)
+	if inferred.Sampled != 2 {
+		t.Fatalf("expected 2 sampled documents, got %d", inferred.Sampled)
+	}
+
+	age, ok := inferred.Field("age")
+	if !ok || age.Nulls != 1 || age.Types["int"] != 1 || age.Types["null"] != 1 {
+		t.Fatalf("unexpected age stats: %+v", age)
+	}
+	tags, ok := inferred.Field("tags")
+	if !ok || *tags.MinItems != 0 || *tags.MaxItems != 2 {
+		t.Fatalf("unexpected tags stats: %+v", tags)
+	}
+	if items, ok := inferred.Field("tags.[]"); !ok || items.Types["string"] != 2 {
+		t.Fatalf("unexpected tags items stats: %+v", items)
+	}
+	if city, ok := inferred.Field("address.city"); !ok || city.Present != 1 {
+		t.Fatalf("unexpected address.city stats: %+v", city)
+	}
+
+	if got := inferred.Required(); !reflect.DeepEqual(got, []string{"name", "tags"}) {
+		t.Fatalf("unexpected required fields: %v", got)
+	}
+
+	root := inferred.JSONSchema()["$jsonSchema"].(bson.M)
+	props := root["properties"].(bson.M)
+	address := props["address"].(bson.M)
+	if address["bsonType"] != "object" || address["required"] == nil {
+		t.Fatalf("expected nested address schema, got %+v", address)
+	}
+	tagsProp := props["tags"].(bson.M)
+	if items := tagsProp["items"].(bson.M); items["bsonType"] != "string" {
+		t.Fatalf("expected string items, got %+v", items)
+	}
+}
+
+func TestInferMapsRareTypesAndOmitsUnknown(t *testing.T) {
+	docs := []bson.D{{
+		{Key: "code", Value: bson.JavaScript("return 1")},
+		{Key: "low", Value: bson.MinKey{}},
+		{Key: "meta", Value: bson.M{"source": "import"}},
+		{Key: "odd", Value: struct{}{}},
+	}}
+
+	props := InferFromDocuments("misc", docs).JSONSchema()["$jsonSchema"].(bson.M)["properties"].(bson.M)
+	if got := props["code"].(bson.M)["bsonType"]; got != "javascript" {
+		t.Fatalf("code bsonType = %v", got)
+	}
+	if got := props["low"].(bson.M)["bsonType"]; got != "minKey" {
+		t.Fatalf("low bsonType = %v", got)
+	}
+	meta := props["meta"].(bson.M)
+	if meta["bsonType"] != "object" || meta["properties"] == nil {
+		t.Fatalf("expected bson.M to be inferred as a nested object, got %+v", meta)
+	}
+	if _, ok := props["odd"].(bson.M)["bsonType"]; ok {
+		t.Fatalf("unmapped types must not emit a bsonType: %+v", props["odd"])
+	}
+}
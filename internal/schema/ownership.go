@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrIgnorePatternRequired = errors.New("ignore rule pattern is required")
+	ErrIgnorePatternInvalid  = errors.New("invalid ignore rule pattern")
+)
+
+// IgnoreRule excludes matching collections, indexes and other schema objects
+// from drift detection, the import prompt and schema apply. Owner marks
+// objects managed by another service; rules without an owner are plain
+// intentional differences.
+type IgnoreRule struct {
+	// Component limits the rule to one diff component such as "index" or
+	// "validator". Empty matches every component.
+	Component string `bson:"component,omitempty" json:"component,omitempty"`
+	// Pattern is a path.Match glob over the collection name or
+	// "<collection>.<index>".
+	Pattern string `bson:"pattern" json:"pattern"`
+	Owner   string `bson:"owner,omitempty" json:"owner,omitempty"`
+	Reason  string `bson:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// nestedComponents are the diff components whose targets name something
+// inside a collection, as "<collection>.<name>".
+var nestedComponents = map[string]bool{"index": true, "search_index": true}
+
+// Matches reports whether the rule covers target of the given component. For
+// indexes and search indexes the glob is also tried against every dotted
+// prefix of target, so a collection pattern covers that collection's indexes.
+// Collection targets are matched whole: "orders" does not cover the
+// collection "orders.archive".
+func (r IgnoreRule) Matches(component, target string) bool {
+	if nestedComponents[component] {
+		return r.MatchesNested(component, target)
+	}
+	if r.Component != "" && r.Component != component {
+		return false
+	}
+	ok, _ := path.Match(r.Pattern, target)
+	return ok
+}
+
+// MatchesNested is Matches for a target that names something inside a
+// collection, such as "<collection>.<option>": the glob is tried against
+// target and every dotted prefix of it.
+func (r IgnoreRule) MatchesNested(component, target string) bool {
+	if r.Component != "" && r.Component != component {
+		return false
+	}
+	candidate := target
+	for {
+		if ok, _ := path.Match(r.Pattern, candidate); ok {
+			return true
+		}
+		i := strings.LastIndex(candidate, ".")
+		if i < 0 {
+			return false
+		}
+		candidate = candidate[:i]
+	}
+}
+
+// Validate checks that the pattern is a well-formed glob.
+func (r IgnoreRule) Validate() error {
+	if r.Pattern == "" {
+		return ErrIgnorePatternRequired
+	}
+	if _, err := path.Match(r.Pattern, ""); err != nil {
+		return fmt.Errorf("%w: %q: %w", ErrIgnorePatternInvalid, r.Pattern, err)
+	}
+	return nil
+}
+
+var (
+	ignoreRulesMu sync.RWMutex
+	// Server-owned collections are never drift.
+	ignoreRules = map[string]IgnoreRule{
+		"|system.*": {Pattern: "system.*", Owner: "mongodb"},
+	}
+)
+
+// RegisterIgnore adds a rule. Registering the same component and pattern again
+// replaces the earlier rule, so config and schema files can be reloaded.
+func RegisterIgnore(rule IgnoreRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	ignoreRulesMu.Lock()
+	defer ignoreRulesMu.Unlock()
+	ignoreRules[rule.Component+"|"+rule.Pattern] = rule
+	return nil
+}
+
+// UnregisterIgnore removes the rule registered for the same component and
+// pattern, if any.
+func UnregisterIgnore(rule IgnoreRule) {
+	ignoreRulesMu.Lock()
+	defer ignoreRulesMu.Unlock()
+	delete(ignoreRules, rule.Component+"|"+rule.Pattern)
+}
+
+func MustRegisterIgnore(rules ...IgnoreRule) {
+	for _, rule := range rules {
+		if err := RegisterIgnore(rule); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func IgnoreRules() []IgnoreRule {
+	ignoreRulesMu.RLock()
+	defer ignoreRulesMu.RUnlock()
+
+	out := make([]IgnoreRule, 0, len(ignoreRules))
+	for _, rule := range ignoreRules {
+		out = append(out, rule)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Pattern != out[j].Pattern {
+			return out[i].Pattern < out[j].Pattern
+		}
+		return out[i].Component < out[j].Component
+	})
+	return out
+}
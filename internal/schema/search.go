@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	SearchIndexTypeSearch       = "search"
+	SearchIndexTypeVectorSearch = "vectorSearch"
+)
+
+var (
+	ErrSearchIndexNameRequired       = errors.New("search index name is required")
+	ErrSearchIndexDefinitionRequired = errors.New("search index definition is required")
+	ErrSearchIndexTypeInvalid        = errors.New("search index type must be search or vectorSearch")
+	ErrSearchIndexAlreadyRegistered  = errors.New("search index already registered")
+)
+
+// SearchIndexSpec declares an Atlas Search or Vector Search index. Definition
+// is the document passed to createSearchIndexes, e.g. {mappings: {dynamic: true}}
+// or {fields: [{type: "vector", path: "embedding", numDimensions: 1536, similarity: "cosine"}]}.
+type SearchIndexSpec struct {
+	Collection string `bson:"collection"`
+	Name       string `bson:"name"`
+	Type       string `bson:"type,omitempty"`
+	Definition bson.M `bson:"definition"`
+}
+
+// IndexType returns Type, defaulting to "search" like the server does.
+func (s SearchIndexSpec) IndexType() string {
+	if s.Type == "" {
+		return SearchIndexTypeSearch
+	}
+	return s.Type
+}
+
+var (
+	searchIndexesMu sync.RWMutex
+	searchIndexes   = make(map[string]SearchIndexSpec)
+)
+
+func RegisterSearchIndex(spec SearchIndexSpec) error {
+	if spec.Collection == "" {
+		return ErrCollectionNameRequired
+	}
+	if spec.Name == "" {
+		return fmt.Errorf("%w: %s", ErrSearchIndexNameRequired, spec.Collection)
+	}
+	if len(spec.Definition) == 0 {
+		return fmt.Errorf("%w: %s.%s", ErrSearchIndexDefinitionRequired, spec.Collection, spec.Name)
+	}
+	if t := spec.IndexType(); t != SearchIndexTypeSearch && t != SearchIndexTypeVectorSearch {
+		return fmt.Errorf("%w: %s", ErrSearchIndexTypeInvalid, t)
+	}
+
+	key := spec.Collection + "." + spec.Name
+	searchIndexesMu.Lock()
+	defer searchIndexesMu.Unlock()
+	if _, exists := searchIndexes[key]; exists {
+		return fmt.Errorf("%w: %s", ErrSearchIndexAlreadyRegistered, key)
+	}
+	searchIndexes[key] = spec
+	return nil
+}
+
+func MustRegisterSearchIndex(specs ...SearchIndexSpec) {
+	for _, spec := range specs {
+		if err := RegisterSearchIndex(spec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func SearchIndexes() []SearchIndexSpec {
+	searchIndexesMu.RLock()
+	defer searchIndexesMu.RUnlock()
+
+	out := make([]SearchIndexSpec, 0, len(searchIndexes))
+	for _, spec := range searchIndexes {
+		out = append(out, spec)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Collection != out[j].Collection {
+			return out[i].Collection < out[j].Collection
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrShardKeyRequired          = errors.New("shard key must define at least one field")
+	ErrShardKeyAlreadyRegistered = errors.New("shard key already registered")
+	ErrZoneRangeInvalid          = errors.New("zone requires a name, min and max")
+)
+
+// ShardKeySpec declares how a collection is sharded. Zones pin key ranges to
+// shards tagged with the zone name; PresplitPoints are split before data
+// arrives so writes spread across shards from the start.
+type ShardKeySpec struct {
+	Collection     string     `bson:"collection"`
+	Key            bson.D     `bson:"key"`
+	Unique         bool       `bson:"unique,omitempty"`
+	Zones          []ZoneSpec `bson:"zones,omitempty"`
+	PresplitPoints []bson.D   `bson:"presplitPoints,omitempty"`
+}
+
+// ZoneSpec is a key range [Min, Max) assigned to a zone.
+type ZoneSpec struct {
+	Name string `bson:"name"`
+	Min  bson.D `bson:"min"`
+	Max  bson.D `bson:"max"`
+}
+
+// Hashed reports whether the shard key uses a hashed field.
+func (s ShardKeySpec) Hashed() bool {
+	for _, k := range s.Key {
+		if v, ok := k.Value.(string); ok && v == "hashed" {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	shardKeysMu sync.RWMutex
+	shardKeys   = make(map[string]ShardKeySpec)
+)
+
+func RegisterShardKey(spec ShardKeySpec) error {
+	if spec.Collection == "" {
+		return ErrCollectionNameRequired
+	}
+	if len(spec.Key) == 0 {
+		return fmt.Errorf("%w: %s", ErrShardKeyRequired, spec.Collection)
+	}
+	for _, z := range spec.Zones {
+		if z.Name == "" || len(z.Min) == 0 || len(z.Max) == 0 {
+			return fmt.Errorf("%w: %s", ErrZoneRangeInvalid, spec.Collection)
+		}
+	}
+
+	shardKeysMu.Lock()
+	defer shardKeysMu.Unlock()
+	if _, exists := shardKeys[spec.Collection]; exists {
+		return fmt.Errorf("%w: %s", ErrShardKeyAlreadyRegistered, spec.Collection)
+	}
+	shardKeys[spec.Collection] = spec
+	return nil
+}
+
+func MustRegisterShardKey(specs ...ShardKeySpec) {
+	for _, spec := range specs {
+		if err := RegisterShardKey(spec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func ShardKeys() []ShardKeySpec {
+	shardKeysMu.RLock()
+	defer shardKeysMu.RUnlock()
+
+	out := make([]ShardKeySpec, 0, len(shardKeys))
+	for _, spec := range shardKeys {
+		out = append(out, spec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Collection < out[j].Collection })
+	return out
+}
+
+func ShardKeyFor(collection string) (ShardKeySpec, bool) {
+	shardKeysMu.RLock()
+	defer shardKeysMu.RUnlock()
+	spec, ok := shardKeys[collection]
+	return spec, ok
+}
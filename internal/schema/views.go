@@ -0,0 +1,174 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var (
+	ErrViewNameRequired       = errors.New("view name is required")
+	ErrViewSourceRequired     = errors.New("view source collection is required")
+	ErrViewAlreadyRegistered  = errors.New("view already registered")
+	ErrMaterializedViewNotReg = errors.New("materialized view not registered")
+)
+
+// ViewSpec declares a standard (read-only, computed on read) view.
+type ViewSpec struct {
+	Name      string
+	Source    string
+	Pipeline  mongo.Pipeline
+	Collation bson.M
+}
+
+// MaterializedViewSpec declares an on-demand materialized view: a regular
+// collection refreshed by running Pipeline on Source and $merge-ing the result.
+type MaterializedViewSpec struct {
+	Name           string
+	Source         string
+	Pipeline       mongo.Pipeline
+	MergeOn        []string // defaults to _id
+	WhenMatched    string   // defaults to replace
+	WhenNotMatched string   // defaults to insert
+}
+
+var (
+	viewsMu           sync.RWMutex
+	views             = make(map[string]ViewSpec)
+	materializedViews = make(map[string]MaterializedViewSpec)
+)
+
+func RegisterView(spec ViewSpec) error {
+	if err := validateViewNames(spec.Name, spec.Source); err != nil {
+		return err
+	}
+
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+	if err := ensureViewNameFree(spec.Name); err != nil {
+		return err
+	}
+	views[spec.Name] = spec
+	return nil
+}
+
+func MustRegisterView(specs ...ViewSpec) {
+	for _, spec := range specs {
+		if err := RegisterView(spec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func RegisterMaterializedView(spec MaterializedViewSpec) error {
+	if err := validateViewNames(spec.Name, spec.Source); err != nil {
+		return err
+	}
+
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+	if err := ensureViewNameFree(spec.Name); err != nil {
+		return err
+	}
+	materializedViews[spec.Name] = spec
+	return nil
+}
+
+func MustRegisterMaterializedView(specs ...MaterializedViewSpec) {
+	for _, spec := range specs {
+		if err := RegisterMaterializedView(spec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func Views() []ViewSpec {
+	viewsMu.RLock()
+	defer viewsMu.RUnlock()
+
+	out := make([]ViewSpec, 0, len(views))
+	for _, spec := range views {
+		out = append(out, spec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func MaterializedViews() []MaterializedViewSpec {
+	viewsMu.RLock()
+	defer viewsMu.RUnlock()
+
+	out := make([]MaterializedViewSpec, 0, len(materializedViews))
+	for _, spec := range materializedViews {
+		out = append(out, spec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// MaterializedView looks up a registered materialized view by name.
+func MaterializedView(name string) (MaterializedViewSpec, bool) {
+	viewsMu.RLock()
+	defer viewsMu.RUnlock()
+
+	spec, ok := materializedViews[name]
+	return spec, ok
+}
+
+// RefreshPipeline appends the $merge stage that writes into the view collection.
+func (m MaterializedViewSpec) RefreshPipeline() mongo.Pipeline {
+	on := m.MergeOn
+	if len(on) == 0 {
+		on = []string{"_id"}
+	}
+	whenMatched := m.WhenMatched
+	if whenMatched == "" {
+		whenMatched = "replace"
+	}
+	whenNotMatched := m.WhenNotMatched
+	if whenNotMatched == "" {
+		whenNotMatched = "insert"
+	}
+
+	pipeline := make(mongo.Pipeline, 0, len(m.Pipeline)+1)
+	pipeline = append(pipeline, m.Pipeline...)
+	return append(pipeline, bson.D{{Key: "$merge", Value: bson.D{
+		{Key: "into", Value: m.Name},
+		{Key: "on", Value: on},
+		{Key: "whenMatched", Value: whenMatched},
+		{Key: "whenNotMatched", Value: whenNotMatched},
+	}}})
+}
+
+// Refresh recomputes the materialized view from its source collection.
+func (m MaterializedViewSpec) Refresh(ctx context.Context, db *mongo.Database) error {
+	cur, err := db.Collection(m.Source).Aggregate(ctx, m.RefreshPipeline())
+	if err != nil {
+		return fmt.Errorf("refresh materialized view %s: %w", m.Name, err)
+	}
+	return cur.Close(ctx)
+}
+
+func validateViewNames(name, source string) error {
+	if name == "" {
+		return ErrViewNameRequired
+	}
+	if source == "" {
+		return fmt.Errorf("%w: %s", ErrViewSourceRequired, name)
+	}
+	return nil
+}
+
+func ensureViewNameFree(name string) error {
+	_, isView := views[name]
+	_, isMaterialized := materializedViews[name]
+	if isView || isMaterialized {
+		return fmt.Errorf("%w: %s", ErrViewAlreadyRegistered, name)
+	}
+	return nil
+}
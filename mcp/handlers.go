@@ -9,8 +9,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/drewjocham/mongork/internal/cdc"
 	"github.com/drewjocham/mongork/internal/migration"
 	"github.com/drewjocham/mongork/internal/observability"
+	"github.com/drewjocham/mongork/internal/schema"
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -20,6 +22,7 @@ var (
 	ErrMigrationDownFailed = errors.New("migration down failed")
 	ErrFailedToListColl    = errors.New("failed to list collections")
 	ErrFailedToGetStatus   = errors.New("failed to get status")
+	ErrInvalidHistoryFrom  = errors.New("invalid from (expected RFC3339)")
 )
 
 const (
@@ -68,6 +71,14 @@ func (s *McpServer) registerTools() {
 		Description: "View collections and indexes.",
 		InputSchema: noArgsSchema(),
 	}, s.handleSchema)
+	s.server.AddTool(&mcpsdk.Tool{
+		Name:        "schema_infer",
+		Description: "Infer document shape (field paths, types, nullability) from sampled documents.",
+		InputSchema: objectSchema(map[string]any{
+			"collection": stringProperty("Collection to sample"),
+			"sample":     map[string]any{"type": "integer", "description": "Documents to sample (default 500, max 5000)"},
+		}, "collection"),
+	}, s.handleSchemaInfer)
 	s.server.AddTool(&mcpsdk.Tool{
 		Name:        "db_health",
 		Description: "Show database health report for operators.",
@@ -85,6 +96,13 @@ func (s *McpServer) registerTools() {
 			"collection": stringProperty("Optional collection filter"),
 		}),
 	}, s.handleDBIndexes)
+	s.server.AddTool(&mcpsdk.Tool{
+		Name:        "db_index_usage",
+		Description: "Report unused and prefix-redundant indexes with reclaimable space.",
+		InputSchema: objectSchema(map[string]any{
+			"collection": stringProperty("Optional collection filter"),
+		}),
+	}, s.handleDBIndexUsage)
 	s.server.AddTool(&mcpsdk.Tool{
 		Name:        "db_collection_stats",
 		Description: "Show collection storage and index size statistics.",
@@ -109,6 +127,16 @@ func (s *McpServer) registerTools() {
 		Description: "Show resource usage summary from serverStatus.",
 		InputSchema: noArgsSchema(),
 	}, s.handleDBResourceSummary)
+	s.server.AddTool(&mcpsdk.Tool{
+		Name:        "oplog_history",
+		Description: "Reconstruct every version of one document from the oplog with field-level diffs.",
+		InputSchema: objectSchema(map[string]any{
+			"namespace": stringProperty("Collection in the active database, or db.collection"),
+			"id":        stringProperty("Document _id: 24-hex ObjectID, integer, or string"),
+			"from":      stringProperty("Optional RFC3339 start time"),
+			"limit":     map[string]any{"type": "integer", "description": "Max versions to return (default all)"},
+		}, "namespace", "id"),
+	}, s.handleOplogHistory)
 }
 func (s *McpServer) handleStatus(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
 	return s.withConnection(ctx, func() (*mcpsdk.CallToolResult, error) {
@@ -158,6 +186,24 @@ func (s *McpServer) handleDBIndexes(ctx context.Context, req *mcpsdk.CallToolReq
 	})
 }
 
+func (s *McpServer) handleDBIndexUsage(
+	ctx context.Context,
+	req *mcpsdk.CallToolRequest,
+) (*mcpsdk.CallToolResult, error) {
+	return s.withConnection(ctx, func() (*mcpsdk.CallToolResult, error) {
+		var args struct {
+			Collection string `json:"collection"`
+		}
+		_ = unmarshalArgs(req, &args)
+		report, err := observability.AnalyzeIndexUsage(ctx, s.db, strings.TrimSpace(args.Collection))
+		recordToolResult("db_index_usage", args.Collection, err)
+		if err != nil {
+			return nil, err
+		}
+		return jsonResult(report)
+	})
+}
+
 func (s *McpServer) handleDBCollectionStats(
 	ctx context.Context,
 	req *mcpsdk.CallToolRequest,
@@ -225,6 +271,40 @@ func (s *McpServer) handleDBResourceSummary(
 	})
 }
 
+func (s *McpServer) handleOplogHistory(
+	ctx context.Context,
+	req *mcpsdk.CallToolRequest,
+) (*mcpsdk.CallToolResult, error) {
+	return s.withConnection(ctx, func() (*mcpsdk.CallToolResult, error) {
+		var args struct {
+			Namespace string `json:"namespace"`
+			ID        string `json:"id"`
+			From      string `json:"from"`
+			Limit     int    `json:"limit"`
+		}
+		_ = unmarshalArgs(req, &args)
+		ns := strings.TrimSpace(args.Namespace)
+		if ns != "" && !strings.Contains(ns, ".") {
+			ns = s.db.Name() + "." + ns
+		}
+		opts := cdc.HistoryOptions{Limit: args.Limit}
+		if args.From != "" {
+			from, err := time.Parse(time.RFC3339, args.From)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidHistoryFrom, err)
+			}
+			opts.From = bson.Timestamp{T: uint32(from.Unix())}
+		}
+		id := cdc.ParseDocumentID(strings.TrimSpace(args.ID))
+		versions, err := cdc.DocumentHistory(ctx, s.client, ns, id, opts)
+		recordToolResult("oplog_history", ns+" "+args.ID, err)
+		if err != nil {
+			return nil, err
+		}
+		return jsonResult(versions)
+	})
+}
+
 func (s *McpServer) handlePlan(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
 	return s.withConnection(ctx, func() (*mcpsdk.CallToolResult, error) {
 		result, err := s.statusTableResult(ctx)
@@ -274,6 +354,26 @@ func (s *McpServer) handleSchema(ctx context.Context, req *mcpsdk.CallToolReques
 	})
 }
 
+func (s *McpServer) handleSchemaInfer(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+	return s.withConnection(ctx, func() (*mcpsdk.CallToolResult, error) {
+		var args struct {
+			Collection string `json:"collection"`
+			Sample     int    `json:"sample"`
+		}
+		_ = unmarshalArgs(req, &args)
+		args.Collection = strings.TrimSpace(args.Collection)
+		if args.Sample > 5000 {
+			args.Sample = 5000
+		}
+		inferred, err := schema.InferSchema(ctx, s.db, args.Collection, args.Sample)
+		recordToolResult("schema_infer", args.Collection, err)
+		if err != nil {
+			return nil, err
+		}
+		return jsonResult(inferred)
+	})
+}
+
 func (s *McpServer) appendCollectionSchema(b *strings.Builder, ctx context.Context, name string) {
 	fmt.Fprintf(b, "#### Collection: `%s`\n\n| Index Name | Keys | Unique |\n| :--- | :--- | :--- |\n", name)
 	cursor, err := s.db.Collection(name).Indexes().List(ctx)